@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/archive"
+	"tspl-simulator/models"
+)
+
+// maxExportJobs 一次匯出的工作數上限
+const maxExportJobs = 1000
+
+// ExportJobsHandler 將列印工作匯出為封存檔 (含 TSPL、中繼資料與繪製的 PNG)
+// 查詢參數: format=zip|tar (tar 為 .tar.gz, 預設 zip), ids (以逗號分隔的工作 ID), 或與 /api/jobs 相同的
+// source、status、from、to、hash 篩選, limit (預設與上限 1000, 取最新的工作)
+func ExportJobsHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", archive.FormatZip)
+	if format != archive.FormatZip && format != archive.FormatTar {
+		jobListError(c, "format 必須為 zip 或 tar")
+		return
+	}
+	limit := maxExportJobs
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxExportJobs {
+			jobListError(c, fmt.Sprintf("limit 必須介於 1 與 %d 之間", maxExportJobs))
+			return
+		}
+		limit = n
+	}
+
+	var jobs []models.Job
+	total := 0
+	if ids := c.Query("ids"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			job, _, err := storageService.GetJob(id)
+			if err != nil {
+				c.JSON(jobErrorStatus(err), models.JobListResponse{
+					Success: false,
+					Error:   id + ": " + err.Error(),
+				})
+				return
+			}
+			jobs = append(jobs, *job)
+		}
+		if len(jobs) > limit {
+			jobListError(c, fmt.Sprintf("一次最多匯出 %d 筆工作", limit))
+			return
+		}
+		total = len(jobs)
+	} else {
+		query, err := parseJobQuery(c)
+		if err != nil {
+			jobListError(c, err.Error())
+			return
+		}
+		query.Limit = limit
+		if jobs, total, err = storageService.ListJobs(query); err != nil {
+			c.JSON(http.StatusInternalServerError, models.JobListResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	contentType := "application/zip"
+	if format == archive.FormatTar {
+		contentType = "application/gzip"
+	}
+	name := "tspl-jobs-" + time.Now().Format("20060102-150405") + archive.Extension(format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Status(http.StatusOK)
+
+	// 封存檔直接寫入回應, 開始後無法再回應錯誤, 只能記錄
+	if n, err := archive.Export(c.Writer, format, storageService, jobs); err != nil {
+		log.Printf("匯出工作失敗 (已寫入 %d 筆): %v", n, err)
+	}
+}
+
+// ImportJobsHandler 匯入工作封存檔 (zip、tar 或 tar.gz), TSPL 內容已存在的工作略過
+// 封存檔可為 multipart 表單欄位 file, 或直接作為請求內容
+func ImportJobsHandler(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, archive.MaxArchiveSize)
+
+	data, err := readArchive(c)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		importError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("封存檔超過 %d MB", archive.MaxArchiveSize>>20))
+		return
+	}
+	if err != nil {
+		importError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := archive.Import(data, storageService)
+	if err != nil {
+		importError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := models.JobImportResponse{Success: true, Results: results}
+	for _, r := range results {
+		switch r.Status {
+		case "imported":
+			response.Imported++
+		case "duplicate":
+			response.Duplicates++
+		default:
+			response.Failed++
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// readArchive 讀取 multipart 表單欄位 file 或請求內容
+func readArchive(c *gin.Context) ([]byte, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, fmt.Errorf("讀取封存檔失敗: %w", err)
+		}
+		return data, nil
+	}
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("缺少上傳檔案: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("讀取上傳檔案失敗: %w", err)
+	}
+	return data, nil
+}
+
+// importError 回應匯入錯誤
+func importError(c *gin.Context, status int, message string) {
+	c.JSON(status, models.JobImportResponse{
+		Success: false,
+		Error:   message,
+	})
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/convert"
+	"tspl-simulator/models"
+)
+
+// ConvertHandler 在標籤語言之間轉換 (查詢參數 from、to, 例如 from=zpl&to=tspl)
+func ConvertHandler(c *gin.Context) {
+	var req models.ConvertRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ConvertResponse{
+			Success: false,
+			Error:   "請求格式錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	output, issues, err := convert.Convert(req.Code, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ConvertResponse{
+			Success: false,
+			From:    from,
+			To:      to,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ConvertResponse{
+		Success:     true,
+		From:        from,
+		To:          to,
+		Output:      output,
+		Unsupported: issues,
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/diff"
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
+	"tspl-simulator/validator"
+)
+
+// DiffHandler 比較兩份標籤: 每一側為 TSPL 原始碼 (tspl_code) 或儲存的工作 ID (job_id)
+// 回應元素層級的差異 (新增、移除、移動、屬性變更)、標籤設定的變更與標示差異點的 PNG;
+// Accept 為 image/png 時直接輸出差異影像
+func DiffHandler(c *gin.Context) {
+	var req models.DiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.DiffResponse{
+			Success: false,
+			Error:   "請求格式錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	before, ok := loadDiffSource(c, "before", req.Before)
+	if !ok {
+		return
+	}
+	after, ok := loadDiffSource(c, "after", req.After)
+	if !ok {
+		return
+	}
+
+	elements, summary := diff.Elements(before.Elements, after.Elements)
+	settings := diff.Settings(before, after)
+	beforeCanvas, _ := renderer.Render(before)
+	afterCanvas, _ := renderer.Render(after)
+	pixels, img := diff.Pixels(beforeCanvas, afterCanvas)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		c.JSON(http.StatusInternalServerError, models.DiffResponse{
+			Success: false,
+			Error:   "影像編碼失敗: " + err.Error(),
+		})
+		return
+	}
+	if c.NegotiateFormat(gin.MIMEJSON, "image/png") == "image/png" {
+		c.Data(http.StatusOK, "image/png", buf.Bytes())
+		return
+	}
+	pixels.Image = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	c.JSON(http.StatusOK, models.DiffResponse{
+		Success:   true,
+		Identical: len(elements) == 0 && len(settings) == 0 && pixels.Added == 0 && pixels.Removed == 0,
+		Summary:   &summary,
+		Elements:  elements,
+		Settings:  settings,
+		Pixels:    &pixels,
+	})
+}
+
+// loadDiffSource 取得比較一側的 TSPL 並驗證與解析; 失敗時已回應錯誤
+func loadDiffSource(c *gin.Context, side string, src models.DiffSource) (*models.RenderData, bool) {
+	fail := func(status int, resp models.DiffResponse) (*models.RenderData, bool) {
+		resp.Side = side
+		c.JSON(status, resp)
+		return nil, false
+	}
+
+	code := src.TSPLCode
+	switch {
+	case (src.TSPLCode == "") == (src.JobID == ""):
+		return fail(http.StatusBadRequest, models.DiffResponse{Error: side + " 必須指定 tspl_code 或 job_id 其中之一"})
+	case src.JobID != "":
+		if storageService == nil {
+			return fail(http.StatusServiceUnavailable, models.DiffResponse{Error: "儲存服務未啟用"})
+		}
+		var err error
+		if _, code, err = storageService.GetJob(src.JobID); err != nil {
+			return fail(jobErrorStatus(err), models.DiffResponse{Error: src.JobID + ": " + err.Error()})
+		}
+	}
+
+	validationResult := validator.ValidateTSPL(code)
+	if !validationResult.Valid {
+		return fail(http.StatusBadRequest, models.DiffResponse{
+			Error:            side + " TSPL 語法驗證失敗",
+			ValidationErrors: convertValidationErrors(validationResult.Errors),
+		})
+	}
+	renderData, err := parser.ParseTSPLWithStore(code, parser.NewScratchStore())
+	if err != nil {
+		return fail(http.StatusBadRequest, models.DiffResponse{Error: side + " TSPL 解析錯誤: " + err.Error()})
+	}
+	return renderData, true
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/formatter"
+	"tspl-simulator/models"
+)
+
+// FormatHandler 將 TSPL 程式碼整理為一致的格式, 並確認整理前後解析結果相同
+func FormatHandler(c *gin.Context) {
+	var req models.RenderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.FormatResponse{
+			Success: false,
+			Error:   "請求格式錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	formatted, err := formatter.FormatVerified(req.TSPLCode)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, models.FormatResponse{
+			Success: false,
+			Error:   "格式化失敗: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.FormatResponse{
+		Success:   true,
+		Formatted: formatted,
+		Changed:   formatted != req.TSPLCode,
+	})
+}
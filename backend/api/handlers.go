@@ -1,27 +1,44 @@
 package api
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"image/png"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"tspl-simulator/models"
 	"tspl-simulator/mqtt"
 	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
 	"tspl-simulator/storage"
 	"tspl-simulator/validator"
+	"tspl-simulator/verifier"
 )
 
 var storageService *storage.StorageService
 
-// InitStorage 初始化儲存服務
-func InitStorage(basePath string) {
-	storageService = storage.NewStorageService(basePath)
+// InitStorage 設定儲存服務 (與 MQTT 共用同一個後端)
+func InitStorage(s *storage.StorageService) {
+	storageService = s
 }
 
+// 向量輸出的 MIME 類型
+const (
+	mimeSVG = "image/svg+xml"
+	mimePDF = "application/pdf"
+)
+
 // RenderHandler 處理 TSPL 渲染請求
+// 依 Accept 標頭回應: 預設為 JSON 元素模型, image/svg+xml、application/pdf 與 image/png 則直接輸出影像
+// 查詢參數 grid=N 於 SVG/PDF 疊加每 N 點的格線; PDF 每張列印的標籤一頁
 func RenderHandler(c *gin.Context) {
 	var req models.RenderRequest
 
@@ -36,35 +53,47 @@ func RenderHandler(c *gin.Context) {
 	// 驗證 TSPL 語法
 	validationResult := validator.ValidateTSPL(req.TSPLCode)
 	if !validationResult.Valid {
-		// 轉換驗證錯誤格式
-		var modelErrors []models.ValidationError
-		for _, err := range validationResult.Errors {
-			modelErrors = append(modelErrors, models.ValidationError{
-				Line:    err.Line,
-				Command: err.Command,
-				Message: err.Message,
-			})
+		// 驗證失敗的資料另存, 以便重現問題
+		if storageService != nil {
+			meta := storage.NewJobMetadata("api", validationResult, nil, 0, nil)
+			meta.RemoteAddr = c.ClientIP()
+			meta.RequestID = requestID(c)
+			if filePath, err := storageService.SaveRejectedAPIData(req.TSPLCode, meta); err != nil {
+				log.Printf("儲存驗證失敗的 API 資料失敗: %v", err)
+			} else {
+				log.Printf("驗證失敗的 API 資料已儲存至: %s", filePath)
+			}
 		}
-
 		c.JSON(http.StatusBadRequest, models.RenderResponse{
-			Success:          false,
-			Error:            "TSPL 語法驗證失敗",
-			ValidationErrors: modelErrors,
+			Success:            false,
+			Error:              "TSPL 語法驗證失敗",
+			ValidationErrors:   convertValidationErrors(validationResult.Errors),
+			ValidationWarnings: convertValidationErrors(validationResult.Warnings),
 		})
 		return
 	}
 
-	// 儲存 API 接收的資料
+	// 解析 TSPL
+	start := time.Now()
+	renderData, err := parser.ParseTSPL(req.TSPLCode)
+	parseDuration := time.Since(start)
+
+	// 儲存 API 接收的資料與中繼資料; 解析失敗的資料與驗證失敗的一樣另存
 	if storageService != nil {
-		if filePath, err := storageService.SaveAPIData(req.TSPLCode); err != nil {
+		meta := storage.NewJobMetadata("api", validationResult, renderData, parseDuration, err)
+		meta.RemoteAddr = c.ClientIP()
+		meta.RequestID = requestID(c)
+		save := storageService.SaveAPIData
+		if err != nil {
+			save = storageService.SaveRejectedAPIData
+		}
+		if filePath, err := save(req.TSPLCode, meta); err != nil {
 			log.Printf("儲存 API 資料失敗: %v", err)
 		} else {
 			log.Printf("API 資料已儲存至: %s", filePath)
 		}
 	}
 
-	// 解析 TSPL
-	renderData, err := parser.ParseTSPL(req.TSPLCode)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.RenderResponse{
 			Success: false,
@@ -81,12 +110,260 @@ func RenderHandler(c *gin.Context) {
 		}
 	}
 
+	switch format := c.NegotiateFormat(gin.MIMEJSON, mimeSVG, mimePDF, "image/png"); format {
+	case mimeSVG, mimePDF, "image/png":
+		renderImage(c, renderData, format)
+		return
+	}
+
 	c.JSON(http.StatusOK, models.RenderResponse{
-		Success: true,
-		Data:    renderData,
+		Success:            true,
+		Data:               renderData,
+		ValidationWarnings: convertValidationErrors(validationResult.Warnings),
+		QRCapacity:         validationResult.QRCapacity,
+		Verification:       verifyRendered(renderData, c.Query("mode") == "realistic"),
+	})
+}
+
+// requestID 取得請求的 X-Request-ID, 沒有時產生一個, 並回寫於回應標頭
+func requestID(c *gin.Context) string {
+	id := c.GetHeader("X-Request-ID")
+	if id == "" {
+		b := make([]byte, 8)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	c.Header("X-Request-ID", id)
+	return id
+}
+
+// renderImage 依協商的格式輸出 SVG、PDF 或 PNG (/api/render 與 /api/render/image 共用)
+func renderImage(c *gin.Context, renderData *models.RenderData, format string) {
+	opts := renderer.VectorOptions{}
+	if grid := c.Query("grid"); grid != "" {
+		n, err := strconv.Atoi(grid)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, models.RenderResponse{
+				Success: false,
+				Error:   "grid 必須為非負整數",
+			})
+			return
+		}
+		opts.Grid = n
+	}
+
+	var (
+		buf      bytes.Buffer
+		warnings []string
+		err      error
+	)
+	switch format {
+	case mimeSVG:
+		warnings, err = renderer.RenderSVG(&buf, renderData, opts)
+	case mimePDF:
+		warnings, err = renderer.RenderPDF(&buf, renderData, opts)
+	default:
+		var canvas *renderer.Canvas
+		canvas, warnings = renderer.Render(renderData)
+		if c.Query("mode") == "realistic" {
+			err = png.Encode(&buf, renderer.SimulateThermal(canvas, renderData.Print).Image())
+		} else {
+			err = canvas.EncodePNG(&buf)
+		}
+	}
+	for _, w := range warnings {
+		log.Printf("渲染警告: %s", w)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.RenderResponse{
+			Success: false,
+			Error:   "影像編碼失敗: " + err.Error(),
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, format, buf.Bytes())
+}
+
+// verifyRendered 將標籤點陣化後掃描驗證所有條碼與 QR Code
+// realistic 為 true 時以熱感列印模擬後的結果掃描
+func verifyRendered(renderData *models.RenderData, realistic bool) []models.ScanResult {
+	canvas, _ := renderer.Render(renderData)
+	if realistic {
+		canvas = renderer.SimulateThermal(canvas, renderData.Print).Canvas()
+	}
+	return verifier.Verify(renderData, canvas)
+}
+
+// RenderImageHandler 將 TSPL 繪製為 1 位元 PNG 預覽 (依命令順序合成 REVERSE/ERASE)
+// 查詢參數 mode=realistic 時改輸出依 DENSITY/SPEED 模擬熱感列印效果的灰階 PNG
+func RenderImageHandler(c *gin.Context) {
+	var req models.RenderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.RenderResponse{
+			Success: false,
+			Error:   "請求格式錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	// 驗證 TSPL 語法
+	validationResult := validator.ValidateTSPL(req.TSPLCode)
+	if !validationResult.Valid {
+		c.JSON(http.StatusBadRequest, models.RenderResponse{
+			Success:            false,
+			Error:              "TSPL 語法驗證失敗",
+			ValidationErrors:   convertValidationErrors(validationResult.Errors),
+			ValidationWarnings: convertValidationErrors(validationResult.Warnings),
+		})
+		return
+	}
+
+	renderData, err := parser.ParseTSPLWithStore(req.TSPLCode, parser.NewScratchStore())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.RenderResponse{
+			Success: false,
+			Error:   "TSPL 解析錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	renderImage(c, renderData, "image/png")
+}
+
+// RenderQualityHandler 依 DENSITY/SPEED/SET RIBBON 模擬熱感列印品質, 回報條碼窄條是否暈開或消失
+func RenderQualityHandler(c *gin.Context) {
+	var req models.RenderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.QualityResponse{
+			Success: false,
+			Error:   "請求格式錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	// 驗證 TSPL 語法
+	validationResult := validator.ValidateTSPL(req.TSPLCode)
+	if !validationResult.Valid {
+		c.JSON(http.StatusBadRequest, models.QualityResponse{
+			Success:          false,
+			Error:            "TSPL 語法驗證失敗",
+			ValidationErrors: convertValidationErrors(validationResult.Errors),
+		})
+		return
+	}
+
+	renderData, err := parser.ParseTSPLWithStore(req.TSPLCode, parser.NewScratchStore())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.QualityResponse{
+			Success: false,
+			Error:   "TSPL 解析錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	quality := renderer.SimulateQuality(renderData)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, quality.Thermal.Image()); err != nil {
+		c.JSON(http.StatusInternalServerError, models.QualityResponse{
+			Success: false,
+			Error:   "影像編碼失敗: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.QualityResponse{
+		Success:  true,
+		Image:    "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Print:    &renderData.Print,
+		Energy:   quality.Thermal.Energy,
+		DotGain:  quality.DotGain,
+		Dropouts: quality.Dropouts,
+		Barcodes: quality.Barcodes,
+		Warnings: quality.Warnings,
+	})
+}
+
+// RenderStripHandler 模擬整個列印工作在紙捲上的排列 (GAP/BLINE/連續紙與進退紙命令)
+func RenderStripHandler(c *gin.Context) {
+	var req models.RenderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.StripResponse{
+			Success: false,
+			Error:   "請求格式錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	// 驗證 TSPL 語法
+	validationResult := validator.ValidateTSPL(req.TSPLCode)
+	if !validationResult.Valid {
+		c.JSON(http.StatusBadRequest, models.StripResponse{
+			Success:          false,
+			Error:            "TSPL 語法驗證失敗",
+			ValidationErrors: convertValidationErrors(validationResult.Errors),
+		})
+		return
+	}
+
+	renderData, err := parser.ParseTSPLWithStore(req.TSPLCode, parser.NewScratchStore())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.StripResponse{
+			Success: false,
+			Error:   "TSPL 解析錯誤: " + err.Error(),
+		})
+		return
+	}
+
+	strip, err := renderer.RenderStrip(renderData)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.StripResponse{
+			Success: false,
+			Error:   "紙捲模擬失敗: " + err.Error(),
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, strip.Image); err != nil {
+		c.JSON(http.StatusInternalServerError, models.StripResponse{
+			Success: false,
+			Error:   "影像編碼失敗: " + err.Error(),
+		})
+		return
+	}
+
+	bounds := strip.Image.Bounds()
+	c.JSON(http.StatusOK, models.StripResponse{
+		Success:    true,
+		Image:      "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+		Pitch:      strip.Pitch,
+		Placements: strip.Placements,
+		Warnings:   strip.Warnings,
 	})
 }
 
+// convertValidationErrors 轉換驗證錯誤格式
+func convertValidationErrors(errors []validator.ValidationError) []models.ValidationError {
+	var modelErrors []models.ValidationError
+	for _, err := range errors {
+		modelErrors = append(modelErrors, models.ValidationError{
+			Line:    err.Line,
+			Column:  err.Column,
+			Command: err.Command,
+			AI:      err.AI,
+			Message: err.Message,
+		})
+	}
+	return modelErrors
+}
+
 // GetExamplesHandler 取得範例列表
 func GetExamplesHandler(c *gin.Context) {
 	examples := []models.ExampleInfo{
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/imagestore"
+)
+
+var imageStore *imagestore.Store
+
+// InitImageStore 設定模擬打印機記憶體
+func InitImageStore(s *imagestore.Store) {
+	imageStore = s
+}
+
+// ListImagesHandler 列出打印機記憶體中的檔案
+func ListImagesHandler(c *gin.Context) {
+	used, count := imageStore.Usage()
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"files":      imageStore.List(),
+		"used_bytes": used,
+		"file_count": count,
+	})
+}
+
+// UploadImageHandler 上傳 BMP/PCX/PNG 到打印機記憶體
+// 支援 multipart 表單 (file, 選用 name) 或 JSON {"name": "...", "data": "base64"}
+func UploadImageHandler(c *gin.Context) {
+	var name string
+	var data []byte
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "缺少上傳檔案: " + err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(file); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "讀取上傳檔案失敗: " + err.Error(),
+			})
+			return
+		}
+		name = c.PostForm("name")
+		if name == "" {
+			name = filepath.Base(header.Filename)
+		}
+	} else {
+		var req struct {
+			Name string `json:"name" binding:"required"`
+			Data string `json:"data" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "請求格式錯誤: " + err.Error(),
+			})
+			return
+		}
+		decoded, err := base64.StdEncoding.DecodeString(req.Data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "data 必須是 base64 編碼: " + err.Error(),
+			})
+			return
+		}
+		name, data = req.Name, decoded
+	}
+
+	if err := imageStore.Put(name, data); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, imagestore.ErrStoreFull) {
+			status = http.StatusInsufficientStorage
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   "儲存檔案失敗: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"name":    strings.ToUpper(name),
+		"size":    len(data),
+	})
+}
+
+// GetImageHandler 下載打印機記憶體中的檔案
+func GetImageHandler(c *gin.Context) {
+	data, ok := imageStore.Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "檔案不存在",
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+// DeleteImageHandler 刪除打印機記憶體中的檔案
+func DeleteImageHandler(c *gin.Context) {
+	if !imageStore.Delete(c.Param("name")) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "檔案不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "檔案已刪除",
+	})
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/storage"
+	"tspl-simulator/validator"
+)
+
+// 列印工作列表的分頁大小與可查詢的最大位移 (避免 page × page_size 溢位)
+const (
+	defaultJobPageSize = 50
+	maxJobPageSize     = 500
+	maxJobOffset       = 1_000_000
+)
+
+// sha256Pattern 內容雜湊查詢參數的格式
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ListJobsHandler 列出儲存的列印工作 (最新的在前)
+// 查詢參數: source=api|mqtt, status=accepted|rejected (驗證通過/失敗), from/to (YYYY-MM-DD 或 RFC 3339, to 為日期時包含當天),
+// hash (TSPL 內容的 SHA-256), page (從 1 開始), page_size
+func ListJobsHandler(c *gin.Context) {
+	query, err := parseJobQuery(c)
+	if err != nil {
+		jobListError(c, err.Error())
+		return
+	}
+
+	page, pageSize, err := parseJobPage(c)
+	if err != nil {
+		jobListError(c, err.Error())
+		return
+	}
+	query.Offset, query.Limit = jobPageOffset(page, pageSize), pageSize
+
+	jobs, total, err := storageService.ListJobs(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.JobListResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if jobs == nil {
+		jobs = []models.Job{}
+	}
+
+	c.JSON(http.StatusOK, models.JobListResponse{
+		Success:  true,
+		Jobs:     jobs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// GetJobHandler 取得列印工作的 TSPL 原始碼, 並重新驗證與解析
+func GetJobHandler(c *gin.Context) {
+	job, code, err := storageService.GetJob(c.Param("id"))
+	if err != nil {
+		c.JSON(jobErrorStatus(err), models.JobDetailResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	response := models.JobDetailResponse{
+		Success:  true,
+		Job:      job,
+		TSPLCode: code,
+	}
+	validationResult := validator.ValidateTSPL(code)
+	response.ValidationErrors = convertValidationErrors(validationResult.Errors)
+	response.ValidationWarnings = convertValidationErrors(validationResult.Warnings)
+	if validationResult.Valid {
+		if response.Data, err = parser.ParseTSPLWithStore(code, parser.NewScratchStore()); err != nil {
+			response.Error = "TSPL 解析錯誤: " + err.Error()
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteJobHandler 刪除列印工作
+func DeleteJobHandler(c *gin.Context) {
+	if err := storageService.DeleteJob(c.Param("id")); err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// jobListError 回應列表查詢參數錯誤
+func jobListError(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, models.JobListResponse{
+		Success: false,
+		Error:   message,
+	})
+}
+
+// jobErrorStatus 找不到工作時回應 404, 其餘為 500
+func jobErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrJobNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// parseJobQuery 解析工作篩選參數 source、status、hash、from、to
+func parseJobQuery(c *gin.Context) (storage.JobQuery, error) {
+	query := storage.JobQuery{Source: c.Query("source"), Status: c.Query("status"), Hash: strings.ToLower(c.Query("hash"))}
+	if query.Source != "" && query.Source != "api" && query.Source != "mqtt" {
+		return query, errors.New("source 必須為 api 或 mqtt")
+	}
+	if query.Status != "" && query.Status != "accepted" && query.Status != "rejected" {
+		return query, errors.New("status 必須為 accepted 或 rejected")
+	}
+	if query.Hash != "" && !sha256Pattern.MatchString(query.Hash) {
+		return query, errors.New("hash 必須為 64 位十六進位的 SHA-256")
+	}
+
+	var err error
+	if query.From, err = storage.ParseJobTime(c.Query("from"), false); err != nil {
+		return query, errors.New("from 格式錯誤: " + err.Error())
+	}
+	if query.To, err = storage.ParseJobTime(c.Query("to"), true); err != nil {
+		return query, errors.New("to 格式錯誤: " + err.Error())
+	}
+	return query, nil
+}
+
+// parseJobPage 解析分頁參數 page (從 1 開始) 與 page_size; 該頁的位移不可超過 maxJobOffset
+func parseJobPage(c *gin.Context) (int, int, error) {
+	page, pageSize := 1, defaultJobPageSize
+	var err error
+	if v := c.Query("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			return 0, 0, errors.New("page 必須為正整數")
+		}
+	}
+	if v := c.Query("page_size"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil || pageSize < 1 || pageSize > maxJobPageSize {
+			return 0, 0, errors.New("page_size 必須介於 1 到 " + strconv.Itoa(maxJobPageSize))
+		}
+	}
+	if page-1 > maxJobOffset/pageSize {
+		return 0, 0, errors.New("page 超過上限, 位移 (page-1) × page_size 不可超過 " + strconv.Itoa(maxJobOffset))
+	}
+	return page, pageSize, nil
+}
+
+// jobPageOffset 該頁第一筆的位移, 限制在 0 到 maxJobOffset 之間
+func jobPageOffset(page, pageSize int) int {
+	if page < 1 || pageSize < 1 {
+		return 0
+	}
+	if page-1 > maxJobOffset/pageSize {
+		return maxJobOffset
+	}
+	return (page - 1) * pageSize
+}
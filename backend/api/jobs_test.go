@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseJobPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query          string
+		page, pageSize int
+		offset         int
+		wantErr        bool
+	}{
+		{"", 1, defaultJobPageSize, 0, false},
+		{"page=3&page_size=20", 3, 20, 40, false},
+		{"page=2001&page_size=500", 2001, 500, maxJobOffset, false},
+		{"page=2002&page_size=500", 0, 0, 0, true},
+		{"page=" + strconv.Itoa(maxJobOffset+1) + "&page_size=1", maxJobOffset + 1, 1, maxJobOffset, false},
+		{"page=4611686018427387904&page_size=500", 0, 0, 0, true},
+		{"page=99999999999999999999", 0, 0, 0, true},
+		{"page=0", 0, 0, 0, true},
+		{"page=-1", 0, 0, 0, true},
+		{"page_size=0", 0, 0, 0, true},
+		{"page_size=501", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/jobs?"+tt.query, nil)
+			page, pageSize, err := parseJobPage(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if page != tt.page || pageSize != tt.pageSize {
+				t.Errorf("page, page_size = %d, %d, want %d, %d", page, pageSize, tt.page, tt.pageSize)
+			}
+			if offset := jobPageOffset(page, pageSize); offset != tt.offset {
+				t.Errorf("offset = %d, want %d", offset, tt.offset)
+			}
+		})
+	}
+}
+
+func TestJobPageOffsetClamps(t *testing.T) {
+	for _, tt := range []struct{ page, pageSize, want int }{
+		{1 << 62, 500, maxJobOffset},
+		{0, 50, 0},
+		{5, 0, 0},
+	} {
+		if got := jobPageOffset(tt.page, tt.pageSize); got != tt.want {
+			t.Errorf("jobPageOffset(%d, %d) = %d, want %d", tt.page, tt.pageSize, got, tt.want)
+		}
+	}
+}
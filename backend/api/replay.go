@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/models"
+	"tspl-simulator/mqtt"
+	"tspl-simulator/replay"
+	"tspl-simulator/storage"
+)
+
+// ReplayJobHandler 重送單一列印工作: 以目前的流程重新渲染 (target=render, 預設)、
+// 發布到 MQTT (target=mqtt) 或將原始位元組送到打印機 (target=printer)
+func ReplayJobHandler(c *gin.Context) {
+	req, replayer, ok := replayRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := replayer.Replay(c.Param("id"), replayTarget(req))
+	if err != nil {
+		c.JSON(jobErrorStatus(err), models.ReplayResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if !result.Success {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, replayResponse([]models.ReplayResult{result}, 1, false))
+}
+
+// ReplayJobsHandler 依來源、狀態與日期範圍批次重送列印工作 (由舊到新); 預設不含驗證失敗的工作
+func ReplayJobsHandler(c *gin.Context) {
+	req, replayer, ok := replayRequest(c)
+	if !ok {
+		return
+	}
+
+	query := storage.JobQuery{Source: req.Source}
+	if query.Source != "" && query.Source != "api" && query.Source != "mqtt" {
+		replayError(c, http.StatusBadRequest, "source 必須為 api 或 mqtt")
+		return
+	}
+	var err error
+	if query.Status, err = replay.RangeStatus(req.Status, req.IncludeRejected); err != nil {
+		if errors.Is(err, replay.ErrRejectedNotIncluded) {
+			replayError(c, http.StatusBadRequest, "status=rejected 須搭配 include_rejected: true")
+		} else {
+			replayError(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	if query.From, err = storage.ParseJobTime(req.From, false); err != nil {
+		replayError(c, http.StatusBadRequest, "from 格式錯誤, 應為 YYYY-MM-DD 或 RFC 3339")
+		return
+	}
+	if query.To, err = storage.ParseJobTime(req.To, true); err != nil {
+		replayError(c, http.StatusBadRequest, "to 格式錯誤, 應為 YYYY-MM-DD 或 RFC 3339")
+		return
+	}
+	if query.From.IsZero() && query.To.IsZero() {
+		replayError(c, http.StatusBadRequest, "批次重送須指定 from 或 to")
+		return
+	}
+	if req.Limit < 0 {
+		replayError(c, http.StatusBadRequest, "limit 不可為負數")
+		return
+	}
+
+	results, total, truncated, err := replayer.ReplayRange(query, req.Limit, replayTarget(req))
+	if err != nil {
+		replayError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, replayResponse(results, total, truncated))
+}
+
+// replayPrinters 允許重送的打印機位址
+var replayPrinters []string
+
+// InitReplay 設定允許重送的打印機位址 (host[:port]), 空清單時不允許 target=printer
+func InitReplay(printers []string) {
+	replayPrinters = printers
+}
+
+// replayRequest 解析請求並建立重送器; 失敗時已回應錯誤
+func replayRequest(c *gin.Context) (models.ReplayRequest, *replay.Replayer, bool) {
+	var req models.ReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		replayError(c, http.StatusBadRequest, "請求格式錯誤: "+err.Error())
+		return req, nil, false
+	}
+	if req.Target == "" {
+		req.Target = replay.TargetRender
+	}
+	if storageService == nil {
+		replayError(c, http.StatusServiceUnavailable, "儲存服務未啟用")
+		return req, nil, false
+	}
+
+	replayer := &replay.Replayer{Storage: storageService, Printers: replayPrinters}
+	if err := replayer.Check(replayTarget(req)); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, replay.ErrPrinterNotAllowed) {
+			status = http.StatusForbidden
+		}
+		replayError(c, status, err.Error())
+		return req, nil, false
+	}
+	if req.Target == replay.TargetMQTT {
+		mqttClient := mqtt.GetClient()
+		if mqttClient == nil || !mqttClient.IsConnected() {
+			replayError(c, http.StatusServiceUnavailable, "MQTT 未連接")
+			return req, nil, false
+		}
+		replayer.MQTT, replayer.Topic = mqttClient, mqttClient.Topic()
+	}
+	return req, replayer, true
+}
+
+// replayTarget 請求中的重送目標
+func replayTarget(req models.ReplayRequest) replay.Target {
+	return replay.Target{Kind: req.Target, Topic: req.Topic, Printer: req.Printer}
+}
+
+// replayResponse 統計重送結果
+func replayResponse(results []models.ReplayResult, total int, truncated bool) models.ReplayResponse {
+	resp := models.ReplayResponse{Success: true, Results: results, Total: total, Truncated: truncated}
+	for _, r := range results {
+		if r.Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+		if r.Render != nil && len(r.Render.Changes) > 0 {
+			resp.Changed++
+		}
+	}
+	return resp
+}
+
+// replayError 回應重送錯誤
+func replayError(c *gin.Context, status int, message string) {
+	c.JSON(status, models.ReplayResponse{
+		Success: false,
+		Error:   message,
+	})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/models"
+	"tspl-simulator/storage"
+)
+
+var janitor *storage.Janitor
+
+// InitJanitor 設定保留規則的背景清理
+func InitJanitor(j *storage.Janitor) {
+	janitor = j
+}
+
+// RetentionReportHandler 試算目前的保留規則: 列出會刪除的工作與會壓縮的資料夾, 不做任何變更
+// 並附上背景清理最近一次的執行結果
+func RetentionReportHandler(c *gin.Context) {
+	if janitor == nil {
+		c.JSON(http.StatusServiceUnavailable, models.RetentionResponse{
+			Success: false,
+			Error:   "儲存服務未啟用",
+		})
+		return
+	}
+
+	report, err := janitor.Run(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.RetentionResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RetentionResponse{
+		Success: true,
+		Report:  report,
+		LastRun: janitor.LastReport(),
+	})
+}
@@ -24,11 +24,46 @@ func SetupRouter() *gin.Engine {
 
 		// TSPL 渲染
 		api.POST("/render", RenderHandler)
+		api.POST("/render/image", RenderImageHandler)
+		api.POST("/render/strip", RenderStripHandler)
+		api.POST("/render/quality", RenderQualityHandler)
+
+		// TSPL 格式化
+		api.POST("/format", FormatHandler)
+
+		// 標籤比較 (元素差異與點陣差異)
+		api.POST("/diff", DiffHandler)
+
+		// 標籤語言轉換 (ZPL → TSPL、TSPL → ZPL/EPL2)
+		api.POST("/convert", ConvertHandler)
 
 		// 範例管理
 		api.GET("/examples", GetExamplesHandler)
 		api.GET("/examples/:id", GetExampleDetailHandler)
 
+		// 列印工作歷史 (API/MQTT 儲存的 TSPL)
+		jobs := api.Group("/jobs")
+		{
+			jobs.GET("", ListJobsHandler)
+			jobs.GET("/search", SearchJobsHandler)
+			jobs.GET("/retention", RetentionReportHandler)
+			jobs.POST("/replay", ReplayJobsHandler)
+			jobs.GET("/export", ExportJobsHandler)
+			jobs.POST("/import", ImportJobsHandler)
+			jobs.GET("/:id", GetJobHandler)
+			jobs.DELETE("/:id", DeleteJobHandler)
+			jobs.POST("/:id/replay", ReplayJobHandler)
+		}
+
+		// 模擬打印機記憶體 (PUTBMP/PUTPCX 使用的影像)
+		images := api.Group("/images")
+		{
+			images.GET("", ListImagesHandler)
+			images.POST("", UploadImageHandler)
+			images.GET("/:name", GetImageHandler)
+			images.DELETE("/:name", DeleteImageHandler)
+		}
+
 		// MQTT 相關
 		mqtt := api.Group("/mqtt")
 		{
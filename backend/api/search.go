@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tspl-simulator/models"
+	"tspl-simulator/search"
+	"tspl-simulator/storage"
+)
+
+// maxSearchQueryLength 搜尋字串的長度上限
+const maxSearchQueryLength = 256
+
+// commandPattern type 參數的格式 (TSPL 指令名稱)
+var commandPattern = regexp.MustCompile(`^[A-Za-z]+$`)
+
+var searchIndex *search.Index
+
+// InitSearchIndex 設定列印工作的搜尋索引
+func InitSearchIndex(x *search.Index) {
+	searchIndex = x
+}
+
+// SearchJobsHandler 全文搜尋儲存的列印工作 (最新的在前)
+// 查詢參數: q (以空白分隔的關鍵字, 不分大小寫比對子字串, 全部命中才列出), type (TSPL 指令, 例如 barcode),
+// source=api|mqtt, from/to (同 /api/jobs), page, page_size
+func SearchJobsHandler(c *gin.Context) {
+	if searchIndex == nil {
+		c.JSON(http.StatusServiceUnavailable, models.JobSearchResponse{
+			Success: false,
+			Error:   "搜尋索引未啟用",
+		})
+		return
+	}
+
+	query := search.Query{Text: strings.TrimSpace(c.Query("q")), Command: c.Query("type"), Source: c.Query("source")}
+	if query.Text == "" {
+		searchError(c, "缺少搜尋字串 q")
+		return
+	}
+	if len(query.Text) > maxSearchQueryLength {
+		searchError(c, "搜尋字串過長")
+		return
+	}
+	if query.Command != "" && !commandPattern.MatchString(query.Command) {
+		searchError(c, "type 必須為 TSPL 指令名稱")
+		return
+	}
+	if query.Source != "" && query.Source != "api" && query.Source != "mqtt" {
+		searchError(c, "source 必須為 api 或 mqtt")
+		return
+	}
+
+	var err error
+	if query.From, err = storage.ParseJobTime(c.Query("from"), false); err != nil {
+		searchError(c, "from 格式錯誤: "+err.Error())
+		return
+	}
+	if query.To, err = storage.ParseJobTime(c.Query("to"), true); err != nil {
+		searchError(c, "to 格式錯誤: "+err.Error())
+		return
+	}
+	page, pageSize, err := parseJobPage(c)
+	if err != nil {
+		searchError(c, err.Error())
+		return
+	}
+	query.Offset, query.Limit = jobPageOffset(page, pageSize), pageSize
+
+	results, total := searchIndex.Search(query)
+	response := models.JobSearchResponse{
+		Success:    true,
+		Query:      query.Text,
+		Results:    []models.JobSearchResult{},
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		Rebuilding: searchIndex.Rebuilding(),
+	}
+	for _, r := range results {
+		result := models.JobSearchResult{Job: r.Job, Matches: []models.JobSearchMatch{}}
+		for _, m := range r.Matches {
+			result.Matches = append(result.Matches, models.JobSearchMatch{Line: m.Line, Command: m.Command, Field: m.Name, Value: m.Value})
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// searchError 回應搜尋參數錯誤
+func searchError(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, models.JobSearchResponse{
+		Success: false,
+		Error:   message,
+	})
+}
@@ -0,0 +1,354 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
+	"tspl-simulator/storage"
+	"tspl-simulator/validator"
+)
+
+// 封存檔格式
+const (
+	FormatZip = "zip"
+	FormatTar = "tar" // gzip 壓縮的 tar (.tar.gz)
+)
+
+// 封存檔的內容與限制
+const (
+	ManifestName    = "manifest.json"
+	ManifestVersion = 1
+	jobsDir         = "jobs"
+
+	MaxArchiveSize   = 64 << 20  // 匯入封存檔的大小上限
+	maxExtractedSize = 256 << 20 // 解壓縮後 manifest 與 TSPL 的總大小上限, 避免壓縮炸彈
+)
+
+// ErrTooLarge 封存檔解壓縮後超過大小上限
+var ErrTooLarge = errors.New("封存檔解壓縮後超過大小上限")
+
+// Extension 封存檔的副檔名
+func Extension(format string) string {
+	if format == FormatTar {
+		return ".tar.gz"
+	}
+	return ".zip"
+}
+
+// entryWriter 依序寫入封存檔中的檔案
+type entryWriter interface {
+	add(name string, modTime time.Time, data []byte) error
+	Close() error
+}
+
+// Export 將工作匯出為封存檔: jobs/<id>.tspl、可解析工作的 jobs/<id>.png 與列出所有工作 (含中繼資料) 的 manifest.json
+// 匯出期間已刪除的工作略過; 回傳寫入的工作數
+func Export(w io.Writer, format string, s *storage.StorageService, jobs []models.Job) (int, error) {
+	var ew entryWriter
+	switch format {
+	case FormatZip:
+		ew = &zipWriter{zip.NewWriter(w)}
+	case FormatTar:
+		gz := gzip.NewWriter(w)
+		ew = &tarWriter{tar.NewWriter(gz), gz}
+	default:
+		return 0, fmt.Errorf("不支援的封存檔格式: %s", format)
+	}
+
+	now := time.Now()
+	manifest := models.JobArchiveManifest{Version: ManifestVersion, ExportedAt: now, Jobs: []models.JobArchiveEntry{}}
+	for _, listed := range jobs {
+		job, code, err := s.GetJob(listed.ID)
+		if errors.Is(err, storage.ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			return len(manifest.Jobs), err
+		}
+
+		entry := models.JobArchiveEntry{Job: *job, TSPL: path.Join(jobsDir, job.ID+".tspl")}
+		if err := ew.add(entry.TSPL, job.CreatedAt, []byte(code)); err != nil {
+			return len(manifest.Jobs), err
+		}
+		if png := renderPNG(job, code); png != nil {
+			entry.PNG = path.Join(jobsDir, job.ID+".png")
+			if err := ew.add(entry.PNG, job.CreatedAt, png); err != nil {
+				return len(manifest.Jobs), err
+			}
+		}
+		manifest.Jobs = append(manifest.Jobs, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return len(manifest.Jobs), err
+	}
+	if err := ew.add(ManifestName, now, data); err != nil {
+		return len(manifest.Jobs), err
+	}
+	return len(manifest.Jobs), ew.Close()
+}
+
+// renderPNG 繪製驗證通過且可解析的工作; 其他情況回傳 nil
+func renderPNG(job *models.Job, code string) []byte {
+	if job.Rejected || !validator.ValidateTSPL(code).Valid {
+		return nil
+	}
+	data, err := parser.ParseTSPLWithStore(code, parser.NewScratchStore())
+	if err != nil {
+		return nil
+	}
+	canvas, _ := renderer.Render(data)
+	var buf bytes.Buffer
+	if err := canvas.EncodePNG(&buf); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// Import 匯入封存檔 (zip、tar 或 tar.gz) 中的工作, 依 TSPL 內容的 SHA-256 略過已存在的工作
+// 有 manifest.json 時保留其中的來源、建立時間與用戶端資訊;
+// 沒有時 (例如只有 TSPL 檔案的封存檔) 每個 .tspl 檔視為 API 工作
+// 狀態與驗證、解析的中繼資料一律以重新驗證與解析的結果為準, 不信任封存檔中的記錄
+// 個別工作的失敗記錄在結果中, 封存檔無法讀取時回傳錯誤
+func Import(data []byte, s *storage.StorageService) ([]models.JobImportResult, error) {
+	files, err := readFiles(data)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestData, ok := files[ManifestName]
+	if !ok {
+		return importFiles(files, s), nil
+	}
+	var manifest models.JobArchiveManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("manifest.json 格式錯誤: %v", err)
+	}
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("不支援的封存檔版本: %d", manifest.Version)
+	}
+
+	results := make([]models.JobImportResult, 0, len(manifest.Jobs))
+	for _, entry := range manifest.Jobs {
+		name := entry.ID
+		if name == "" {
+			name = entry.TSPL
+		}
+		code, ok := files[cleanName(entry.TSPL)]
+		switch {
+		case !ok:
+			results = append(results, failed(name, fmt.Errorf("封存檔中沒有 %s", entry.TSPL)))
+		case entry.Hash != "" && entry.Hash != storage.ContentHash(code):
+			results = append(results, failed(name, errors.New("TSPL 內容與雜湊不符")))
+		default:
+			results = append(results, importJob(name, entry.Job, string(code), s))
+		}
+	}
+	return results, nil
+}
+
+// importFiles 匯入沒有 manifest 的封存檔中所有 .tspl 檔 (依路徑排序)
+func importFiles(files map[string][]byte, s *storage.StorageService) []models.JobImportResult {
+	var names []string
+	for name := range files {
+		if strings.EqualFold(path.Ext(name), ".tspl") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	results := make([]models.JobImportResult, 0, len(names))
+	for _, name := range names {
+		results = append(results, importJob(name, models.Job{Source: "api"}, string(files[name]), s))
+	}
+	return results
+}
+
+// importJob 重新驗證並匯入一筆工作, 記錄結果
+func importJob(name string, job models.Job, code string, s *storage.StorageService) models.JobImportResult {
+	imported, duplicate, err := s.ImportJob(revalidate(job, code), code)
+	switch {
+	case err != nil:
+		return failed(name, err)
+	case duplicate:
+		return models.JobImportResult{Entry: name, Status: "duplicate", JobID: imported.ID}
+	default:
+		return models.JobImportResult{Entry: name, Status: "imported", JobID: imported.ID}
+	}
+}
+
+// revalidate 以目前的驗證器與解析器重新產生工作的狀態與中繼資料, 保留原本的用戶端資訊
+// 驗證失敗或無法解析的工作視為 rejected, 與即時收到的工作相同
+func revalidate(job models.Job, code string) models.Job {
+	validationResult := validator.ValidateTSPL(code)
+	var meta *models.JobMetadata
+	var parseErr error
+	if validationResult.Valid {
+		start := time.Now()
+		var renderData *models.RenderData
+		renderData, parseErr = parser.ParseTSPLWithStore(code, parser.NewScratchStore())
+		meta = storage.NewJobMetadata(job.Source, validationResult, renderData, time.Since(start), parseErr)
+	} else {
+		meta = storage.NewJobMetadata(job.Source, validationResult, nil, 0, nil)
+	}
+	if job.Metadata != nil {
+		meta.RemoteAddr = job.Metadata.RemoteAddr
+		meta.MQTTClientID = job.Metadata.MQTTClientID
+		meta.MQTTTopic = job.Metadata.MQTTTopic
+		meta.RequestID = job.Metadata.RequestID
+	}
+	job.Rejected = !validationResult.Valid || parseErr != nil
+	job.Metadata = meta
+	return job
+}
+
+// failed 匯入失敗的結果
+func failed(name string, err error) models.JobImportResult {
+	return models.JobImportResult{Entry: name, Status: "failed", Error: err.Error()}
+}
+
+// readFiles 讀取封存檔中的 manifest.json 與 .tspl 檔 (其他檔案略過), 依開頭的位元組判斷格式
+func readFiles(data []byte) (map[string][]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return readZip(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("讀取 gzip 失敗: %v", err)
+		}
+		return readTar(gz)
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		return readTar(bytes.NewReader(data))
+	default:
+		return nil, errors.New("無法辨識的封存檔格式, 應為 zip、tar 或 tar.gz")
+	}
+}
+
+// wanted 是否需要讀取封存檔中的檔案
+func wanted(name string) bool {
+	return name == ManifestName || strings.EqualFold(path.Ext(name), ".tspl")
+}
+
+// cleanName 統一封存檔中的路徑 (去除開頭的 ./ 與 /)
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
+
+// readZip 讀取 zip 封存檔
+func readZip(data []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("讀取 zip 失敗: %v", err)
+	}
+	files := map[string][]byte{}
+	var total int64
+	for _, f := range zr.File {
+		name := cleanName(f.Name)
+		if f.FileInfo().IsDir() || !wanted(name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("讀取 %s 失敗: %v", f.Name, err)
+		}
+		content, err := readLimited(rc, &total)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// readTar 讀取 tar 封存檔
+func readTar(r io.Reader) (map[string][]byte, error) {
+	tr := tar.NewReader(r)
+	files := map[string][]byte{}
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("讀取 tar 失敗: %v", err)
+		}
+		name := cleanName(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !wanted(name) {
+			continue
+		}
+		content, err := readLimited(tr, &total)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+}
+
+// readLimited 讀取檔案內容並累計總大小
+func readLimited(r io.Reader, total *int64) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(r, maxExtractedSize-*total+1))
+	if err != nil {
+		return nil, fmt.Errorf("解壓縮失敗: %v", err)
+	}
+	*total += int64(len(content))
+	if *total > maxExtractedSize {
+		return nil, ErrTooLarge
+	}
+	return content, nil
+}
+
+// zipWriter 寫入 zip 封存檔
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipWriter) add(name string, modTime time.Time, data []byte) error {
+	f, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
+
+// tarWriter 寫入 gzip 壓縮的 tar 封存檔
+type tarWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+func (w *tarWriter) add(name string, modTime time.Time, data []byte) error {
+	if err := w.tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
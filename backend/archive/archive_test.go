@@ -0,0 +1,117 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tspl-simulator/models"
+	"tspl-simulator/storage"
+)
+
+// buildZip 建立含 manifest.json 與 TSPL 檔的 zip 封存檔
+func buildZip(t *testing.T, entries []models.JobArchiveEntry, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	manifest, err := json.Marshal(models.JobArchiveManifest{Version: ManifestVersion, ExportedAt: time.Now(), Jobs: entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[ManifestName] = string(manifest)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportCreatedAtRange(t *testing.T) {
+	s := storage.NewStorageServiceWithBackend(storage.NewMemoryBackend())
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		status    string
+		want      time.Time // 匯入後的建立時間, 零值代表不檢查
+	}{
+		{"pre-1970", time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC), "failed", time.Time{}},
+		{"just before epoch", time.UnixMilli(-1), "failed", time.Time{}},
+		{"epoch", time.UnixMilli(0), "imported", time.UnixMilli(0)},
+		{"kept", created, "imported", created},
+		{"future", time.Now().Add(24 * time.Hour), "imported", time.Time{}},
+	}
+
+	var entries []models.JobArchiveEntry
+	files := map[string]string{}
+	for i, tt := range tests {
+		name := "jobs/" + tt.name + ".tspl"
+		files[name] = "SIZE 50 mm, 30 mm\nCLS\nTEXT 10,10,\"3\",0,1,1,\"" + tt.name + "\"\nPRINT " + string(rune('1'+i)) + "\n"
+		entries = append(entries, models.JobArchiveEntry{Job: models.Job{ID: tt.name, Source: "api", CreatedAt: tt.createdAt}, TSPL: name})
+	}
+	data := buildZip(t, entries, files)
+
+	results, err := Import(data, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(tests) {
+		t.Fatalf("匯入結果 %d 筆, want %d", len(results), len(tests))
+	}
+	for i, tt := range tests {
+		got := results[i]
+		if got.Status != tt.status {
+			t.Errorf("%s: status = %q (%s), want %q", tt.name, got.Status, got.Error, tt.status)
+			continue
+		}
+		if got.Status != "imported" {
+			continue
+		}
+		// 匯入的工作必須可依 ID 讀取, 且建立時間與 ID 的 ULID 一致
+		job, _, err := s.GetJob(got.JobID)
+		if err != nil {
+			t.Errorf("%s: GetJob(%s): %v", tt.name, got.JobID, err)
+			continue
+		}
+		if !tt.want.IsZero() && !job.CreatedAt.Equal(tt.want) {
+			t.Errorf("%s: CreatedAt = %v, want %v", tt.name, job.CreatedAt, tt.want)
+		}
+		if tt.want.IsZero() && job.CreatedAt.After(time.Now()) {
+			t.Errorf("%s: 未來的建立時間應以現在的時間儲存, got %v", tt.name, job.CreatedAt)
+		}
+	}
+
+	// 拒絕的工作沒有儲存: 再次匯入仍為失敗, 而不是以雜湊判定為重複
+	again, err := Import(data, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		want := "duplicate"
+		if tt.status == "failed" {
+			want = "failed"
+		}
+		if again[i].Status != want {
+			t.Errorf("%s: 再次匯入 status = %q, want %q", tt.name, again[i].Status, want)
+		}
+	}
+
+	// 匯入的工作都可刪除
+	jobs, total, err := s.ListJobs(storage.JobQuery{})
+	if err != nil || total != 3 {
+		t.Fatalf("ListJobs: total %d, %v; want 3", total, err)
+	}
+	for _, job := range jobs {
+		if err := s.DeleteJob(job.ID); err != nil {
+			t.Errorf("DeleteJob(%s): %v", job.ID, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pattern 一維條碼的條/空序列
+// Widths 依序為條、空、條、空... 的寬度; 以第一個元素為深色條
+// 若 TwoWidth 為 true, 寬度 1 代表窄條、2 代表寬條 (依 narrow/wide 參數換算)
+// 否則寬度為模組數, 以 narrow 參數作為單一模組寬度
+type Pattern struct {
+	Symbology string
+	Widths    []int
+	TwoWidth  bool
+	Text      string // 人眼可讀文字 (含自動加入的檢查碼)
+}
+
+// Encode 依 TSPL 條碼類型編碼資料
+func Encode(codeType, data string) (*Pattern, error) {
+	switch strings.ToUpper(codeType) {
+	case "128", "128M":
+		return encodeCode128(data, false)
+	case "EAN128":
+		return encodeCode128(data, true)
+	case "39", "39S":
+		return encodeCode39(data, false)
+	case "39C":
+		return encodeCode39(data, true)
+	case "EAN13":
+		return encodeEAN13(data)
+	case "EAN8":
+		return encodeEAN8(data)
+	case "UPCA":
+		return encodeUPCA(data)
+	case "25":
+		return encodeITF(data, false)
+	case "25C":
+		return encodeITF(data, true)
+	case "ITF14":
+		return encodeITF14(data)
+	case "CODA":
+		return encodeCodabar(data)
+	default:
+		return nil, fmt.Errorf("不支援的條碼類型: %s", codeType)
+	}
+}
+
+// ModuleWidths 將條碼序列換算為點數寬度
+func (p *Pattern) ModuleWidths(narrow, wide int) []int {
+	if narrow < 1 {
+		narrow = 1
+	}
+	if wide < narrow {
+		wide = narrow
+	}
+	result := make([]int, len(p.Widths))
+	for i, w := range p.Widths {
+		switch {
+		case p.TwoWidth && w == 2:
+			result[i] = wide
+		case p.TwoWidth:
+			result[i] = narrow
+		default:
+			result[i] = w * narrow
+		}
+	}
+	return result
+}
+
+// TotalWidth 條碼總寬度 (點)
+func (p *Pattern) TotalWidth(narrow, wide int) int {
+	total := 0
+	for _, w := range p.ModuleWidths(narrow, wide) {
+		total += w
+	}
+	return total
+}
+
+// appendModules 將 "0110..." 形式的序列轉為條空寬度並附加 (從 dark 狀態開始)
+func appendModules(widths []int, bits string) []int {
+	for i := 0; i < len(bits); {
+		j := i
+		for j < len(bits) && bits[j] == bits[i] {
+			j++
+		}
+		dark := bits[i] == '1'
+		lastDark := len(widths)%2 == 1
+		if len(widths) > 0 && dark == lastDark {
+			widths[len(widths)-1] += j - i
+		} else if len(widths) == 0 && !dark {
+			widths = append(widths, 0, j-i)
+		} else {
+			widths = append(widths, j-i)
+		}
+		i = j
+	}
+	return widths
+}
+
+// isDigits 檢查字串是否全為數字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, ch := range s {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Mod10CheckDigit 計算 EAN/UPC/ITF-14 使用的 Mod 10 檢查碼 (由右往左奇數位權重 3)
+func Mod10CheckDigit(digits string) int {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
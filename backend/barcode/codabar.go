@@ -0,0 +1,46 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// codabarPatterns Codabar 字元編碼 (7 個元素條空交錯), 1 代表寬元素
+var codabarPatterns = map[byte]string{
+	'0': "0000011", '1': "0000110", '2': "0001001", '3': "1100000",
+	'4': "0010010", '5': "1000010", '6': "0100001", '7': "0100100",
+	'8': "0110000", '9': "1001000", '-': "0001100", '$': "0011000",
+	':': "1000101", '/': "1010001", '.': "1010100", '+': "0010101",
+	'A': "0011010", 'B': "0101001", 'C': "0001011", 'D': "0001110",
+}
+
+// CodabarPattern 取得 Codabar 字元的寬窄序列
+func CodabarPattern(ch byte) (string, bool) {
+	p, ok := codabarPatterns[ch]
+	return p, ok
+}
+
+// encodeCodabar 編碼 Codabar, 未指定起始/終止字元時預設使用 A
+func encodeCodabar(data string) (*Pattern, error) {
+	content := strings.ToUpper(data)
+	isGuard := func(ch byte) bool { return ch >= 'A' && ch <= 'D' }
+	if len(content) < 2 || !isGuard(content[0]) || !isGuard(content[len(content)-1]) {
+		content = "A" + content + "A"
+	}
+	for i := 1; i < len(content)-1; i++ {
+		if _, ok := codabarPatterns[content[i]]; !ok || isGuard(content[i]) {
+			return nil, fmt.Errorf("Codabar 不支援的字元: %q", content[i])
+		}
+	}
+
+	pattern := &Pattern{Symbology: "CODA", TwoWidth: true, Text: data}
+	for i := 0; i < len(content); i++ {
+		if i > 0 {
+			pattern.Widths = append(pattern.Widths, 1)
+		}
+		for _, ch := range codabarPatterns[content[i]] {
+			pattern.Widths = append(pattern.Widths, int(ch-'0')+1)
+		}
+	}
+	return pattern, nil
+}
@@ -0,0 +1,132 @@
+package barcode
+
+import (
+	"fmt"
+
+	"tspl-simulator/gs1"
+)
+
+// code128Patterns Code 128 的 107 個符號 (條空寬度, 0-105 為字元, 106 為終止符)
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128FNC1   = 102
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// Code128Pattern 取得 Code 128 符號值的條空寬度
+func Code128Pattern(value int) string {
+	return code128Patterns[value]
+}
+
+// digitRun 計算從 i 開始的連續數字長度
+func digitRun(data string, i int) int {
+	n := 0
+	for i+n < len(data) && data[i+n] >= '0' && data[i+n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// Code128Values 將資料轉換為 Code 128 符號值 (不含檢查碼與終止符)
+// 自動在 Code B 與 Code C 之間切換以縮短長度; fnc1 為 true 時資料中的 GS (0x1D) 編碼為 FNC1 分隔符
+func Code128Values(data string, fnc1 bool) ([]int, error) {
+	for i := 0; i < len(data); i++ {
+		if fnc1 && data[i] == 0x1D {
+			continue
+		}
+		if data[i] < 32 || data[i] > 126 {
+			return nil, fmt.Errorf("Code 128 不支援的字元: %q", data[i])
+		}
+	}
+
+	var values []int
+	lead := digitRun(data, 0)
+	useC := lead >= 4 || (lead == len(data) && lead == 2)
+	if useC {
+		values = append(values, code128StartC)
+	} else {
+		values = append(values, code128StartB)
+	}
+	if fnc1 {
+		values = append(values, code128FNC1)
+	}
+
+	for i := 0; i < len(data); {
+		if fnc1 && data[i] == 0x1D {
+			values = append(values, code128FNC1)
+			i++
+			continue
+		}
+		run := digitRun(data, i)
+		if useC {
+			if run >= 2 {
+				values = append(values, int(data[i]-'0')*10+int(data[i+1]-'0'))
+				i += 2
+				continue
+			}
+			values = append(values, code128CodeB)
+			useC = false
+			continue
+		}
+		if run >= 6 || (run >= 4 && i+run == len(data)) {
+			if run%2 == 1 {
+				values = append(values, int(data[i])-32)
+				i++
+			}
+			values = append(values, code128CodeC)
+			useC = true
+			continue
+		}
+		values = append(values, int(data[i])-32)
+		i++
+	}
+	return values, nil
+}
+
+// Code128Checksum 計算 Code 128 的 Mod 103 檢查碼
+func Code128Checksum(values []int) int {
+	sum := values[0]
+	for i := 1; i < len(values); i++ {
+		sum += values[i] * i
+	}
+	return sum % 103
+}
+
+// encodeCode128 編碼 Code 128 (isGS1 為 true 時於起始後及可變長度 AI 之後加入 FNC1)
+func encodeCode128(data string, isGS1 bool) (*Pattern, error) {
+	content := data
+	if isGS1 {
+		content = gs1.Content(data)
+	}
+
+	values, err := Code128Values(content, isGS1)
+	if err != nil {
+		return nil, err
+	}
+	values = append(values, Code128Checksum(values), code128Stop)
+
+	pattern := &Pattern{Symbology: "128", Text: data}
+	for _, v := range values {
+		for _, ch := range code128Patterns[v] {
+			pattern.Widths = append(pattern.Widths, int(ch-'0'))
+		}
+	}
+	return pattern, nil
+}
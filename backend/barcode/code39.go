@@ -0,0 +1,65 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code39Charset Code 39 的字元集 (依檢查碼數值排序)
+const Code39Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%"
+
+// code39Patterns 每個字元 9 個元素 (條空交錯), 1 代表寬元素
+var code39Patterns = map[byte]string{
+	'0': "000110100", '1': "100100001", '2': "001100001", '3': "101100000",
+	'4': "000110001", '5': "100110000", '6': "001110000", '7': "000100101",
+	'8': "100100100", '9': "001100100", 'A': "100001001", 'B': "001001001",
+	'C': "101001000", 'D': "000011001", 'E': "100011000", 'F': "001011000",
+	'G': "000001101", 'H': "100001100", 'I': "001001100", 'J': "000011100",
+	'K': "100000011", 'L': "001000011", 'M': "101000010", 'N': "000010011",
+	'O': "100010010", 'P': "001010010", 'Q': "000000111", 'R': "100000110",
+	'S': "001000110", 'T': "000010110", 'U': "110000001", 'V': "011000001",
+	'W': "111000000", 'X': "010010001", 'Y': "110010000", 'Z': "011010000",
+	'-': "010000101", '.': "110000100", ' ': "011000100", '$': "010101000",
+	'/': "010100010", '+': "010001010", '%': "000101010", '*': "010010100",
+}
+
+// Code39Pattern 取得 Code 39 字元的寬窄序列
+func Code39Pattern(ch byte) (string, bool) {
+	p, ok := code39Patterns[ch]
+	return p, ok
+}
+
+// Code39CheckChar 計算 Code 39 的 Mod 43 檢查字元
+func Code39CheckChar(data string) byte {
+	sum := 0
+	for i := 0; i < len(data); i++ {
+		sum += strings.IndexByte(Code39Charset, data[i])
+	}
+	return Code39Charset[sum%43]
+}
+
+// encodeCode39 編碼 Code 39 (withCheck 為 true 時自動加入檢查字元)
+func encodeCode39(data string, withCheck bool) (*Pattern, error) {
+	for i := 0; i < len(data); i++ {
+		if data[i] == '*' || strings.IndexByte(Code39Charset, data[i]) < 0 {
+			return nil, fmt.Errorf("Code 39 不支援的字元: %q", data[i])
+		}
+	}
+
+	content := data
+	if withCheck {
+		content += string(Code39CheckChar(data))
+	}
+
+	pattern := &Pattern{Symbology: "39", TwoWidth: true, Text: content}
+	full := "*" + content + "*"
+	for i := 0; i < len(full); i++ {
+		if i > 0 {
+			pattern.Widths = append(pattern.Widths, 1)
+		}
+		for _, ch := range code39Patterns[full[i]] {
+			pattern.Widths = append(pattern.Widths, int(ch-'0')+1)
+		}
+	}
+	return pattern, nil
+}
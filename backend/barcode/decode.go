@@ -0,0 +1,497 @@
+package barcode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Decoded 由掃描線條空寬度解碼的結果
+type Decoded struct {
+	Symbology string
+	Text      string // 掃描器輸出的資料 (含檢查碼; Code 128 的 FNC1 以 GS (0x1D) 表示)
+	GS1       bool   // Code 128 起始字元後為 FNC1 (GS1-128)
+	TwoWidth  bool
+	// Modules 每個條/空的名義寬度: 模組數, 或兩種寬度條碼的 1 (窄) / 2 (寬)
+	Modules []int
+}
+
+var (
+	code128Lookup = map[string]int{}
+	code39Lookup  = map[string]byte{}
+	codabarLookup = map[string]byte{}
+	itfLookup     = map[string]int{}
+	eanLookup     = map[string][2]int{} // 7 模組編碼 -> {數字, 同位集}
+)
+
+func init() {
+	for v, p := range code128Patterns {
+		code128Lookup[p] = v
+	}
+	for ch, p := range code39Patterns {
+		code39Lookup[p] = ch
+	}
+	for ch, p := range codabarPatterns {
+		codabarLookup[p] = ch
+	}
+	for d, p := range itfPatterns {
+		itfLookup[p] = d
+	}
+	for d := 0; d < 10; d++ {
+		for _, set := range []byte{'L', 'G', 'R'} {
+			eanLookup[EANDigitCode(d, set)] = [2]int{d, int(set)}
+		}
+	}
+}
+
+// DecodeRuns 依 TSPL 條碼類型解碼單一掃描線的條空寬度 (點), runs 以深色條開始並以深色條結束
+func DecodeRuns(codeType string, runs []int) (*Decoded, error) {
+	if len(runs)%2 == 0 {
+		return nil, fmt.Errorf("條空數量必須為奇數 (以條開始與結束)")
+	}
+	switch strings.ToUpper(codeType) {
+	case "128", "128M", "EAN128":
+		return decodeCode128(runs)
+	case "39", "39S", "39C":
+		d, err := decodeCode39(runs)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ToUpper(codeType) == "39C" {
+			n := len(d.Text)
+			if n < 2 || Code39CheckChar(d.Text[:n-1]) != d.Text[n-1] {
+				return nil, fmt.Errorf("Code 39 檢查字元錯誤")
+			}
+		}
+		return d, nil
+	case "EAN13", "EAN8":
+		return decodeEAN(runs)
+	case "UPCA":
+		d, err := decodeEAN(runs)
+		if err != nil {
+			return nil, err
+		}
+		if d.Symbology != "EAN13" || d.Text[0] != '0' {
+			return nil, fmt.Errorf("不是 UPC-A 條碼")
+		}
+		d.Symbology = "UPCA"
+		d.Text = d.Text[1:]
+		return d, nil
+	case "25", "25C", "ITF14":
+		return decodeITF(runs)
+	case "CODA":
+		return decodeCodabar(runs)
+	default:
+		return nil, fmt.Errorf("不支援的條碼類型: %s", codeType)
+	}
+}
+
+// normalize 將一組寬度依總模組數換算為整數模組 (每個元素 1..max), 誤差最大者優先調整
+func normalize(runs []int, total, max int) ([]int, bool) {
+	sum := 0
+	for _, r := range runs {
+		sum += r
+	}
+	if sum == 0 {
+		return nil, false
+	}
+	exact := make([]float64, len(runs))
+	mods := make([]int, len(runs))
+	count := 0
+	for i, r := range runs {
+		exact[i] = float64(r) * float64(total) / float64(sum)
+		mods[i] = int(exact[i] + 0.5)
+		if mods[i] < 1 {
+			mods[i] = 1
+		}
+		count += mods[i]
+	}
+	for count != total {
+		step := 1
+		if count > total {
+			step = -1
+		}
+		best, bestErr := -1, 0.0
+		for i := range mods {
+			next := mods[i] + step
+			if next < 1 || next > max {
+				continue
+			}
+			err := (exact[i] - float64(mods[i])) * float64(step)
+			if best < 0 || err > bestErr {
+				best, bestErr = i, err
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+		mods[best] += step
+		count += step
+	}
+	for _, m := range mods {
+		if m > max {
+			return nil, false
+		}
+	}
+	return mods, true
+}
+
+// modulesKey 將模組數組成查表字串
+func modulesKey(mods []int) string {
+	var b strings.Builder
+	for _, m := range mods {
+		b.WriteByte(byte('0' + m))
+	}
+	return b.String()
+}
+
+// decodeCode128 解碼 Code 128 (每個符號 6 個元素共 11 模組, 終止符 7 個元素共 13 模組)
+func decodeCode128(runs []int) (*Decoded, error) {
+	if len(runs) < 6*2+7 || (len(runs)-7)%6 != 0 {
+		return nil, fmt.Errorf("Code 128 條空數量不符 (%d)", len(runs))
+	}
+	n := (len(runs) - 7) / 6
+	d := &Decoded{Symbology: "128"}
+	values := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		mods, ok := normalize(runs[i*6:i*6+6], 11, 4)
+		if !ok {
+			return nil, fmt.Errorf("Code 128 第 %d 個符號寬度無法辨識", i+1)
+		}
+		v, ok := code128Lookup[modulesKey(mods)]
+		if !ok || v == code128Stop {
+			return nil, fmt.Errorf("Code 128 第 %d 個符號無法辨識", i+1)
+		}
+		values = append(values, v)
+		d.Modules = append(d.Modules, mods...)
+	}
+	stop, ok := normalize(runs[n*6:], 13, 4)
+	if !ok || modulesKey(stop) != code128Patterns[code128Stop] {
+		return nil, fmt.Errorf("Code 128 終止符無法辨識")
+	}
+	d.Modules = append(d.Modules, stop...)
+
+	if values[0] < 103 {
+		return nil, fmt.Errorf("Code 128 缺少起始字元")
+	}
+	if Code128Checksum(values[:n-1]) != values[n-1] {
+		return nil, fmt.Errorf("Code 128 檢查碼錯誤")
+	}
+
+	const (
+		setA = iota
+		setB
+		setC
+	)
+	set := values[0] - 103
+	shift := false
+	var text strings.Builder
+	for i, v := range values[1 : n-1] {
+		cur := set
+		if shift {
+			cur = setA + setB - set
+			shift = false
+		}
+		if v == code128FNC1 {
+			if i == 0 {
+				d.GS1 = true
+			} else {
+				text.WriteByte(0x1D)
+			}
+			continue
+		}
+		switch cur {
+		case setC:
+			switch {
+			case v < 100:
+				fmt.Fprintf(&text, "%02d", v)
+			case v == 100:
+				set = setB
+			case v == 101:
+				set = setA
+			}
+		default:
+			switch {
+			case v < 64 || (cur == setB && v < 96):
+				text.WriteByte(byte(v + 32))
+			case v < 96:
+				text.WriteByte(byte(v - 64))
+			case v == 98:
+				shift = set != setC
+			case v == 99:
+				set = setC
+			case v == 100 && cur == setA:
+				set = setB
+			case v == 101 && cur == setB:
+				set = setA
+			}
+		}
+	}
+	d.Text = text.String()
+	return d, nil
+}
+
+// classify 以門檻將寬度分為窄 (1) 與寬 (2)
+func classify(runs []int, threshold float64) []int {
+	mods := make([]int, len(runs))
+	for i, r := range runs {
+		mods[i] = 1
+		if float64(r) > threshold {
+			mods[i] = 2
+		}
+	}
+	return mods
+}
+
+// wideKey 將窄寬分類轉為 "0"/"1" 序列
+func wideKey(mods []int) string {
+	var b strings.Builder
+	for _, m := range mods {
+		b.WriteByte(byte('0' + m - 1))
+	}
+	return b.String()
+}
+
+// decodeCode39 解碼 Code 39 (每個字元 9 個元素, 其中 3 個為寬, 字元之間以窄空隔開)
+func decodeCode39(runs []int) (*Decoded, error) {
+	if len(runs) < 29 || (len(runs)+1)%10 != 0 {
+		return nil, fmt.Errorf("Code 39 條空數量不符 (%d)", len(runs))
+	}
+	n := (len(runs) + 1) / 10
+	d := &Decoded{Symbology: "39", TwoWidth: true}
+	var text []byte
+	for i := 0; i < n; i++ {
+		el := runs[i*10 : i*10+9]
+		sorted := append([]int(nil), el...)
+		sort.Ints(sorted)
+		if sorted[6] <= sorted[5] {
+			return nil, fmt.Errorf("Code 39 第 %d 個字元無法區分寬窄元素", i+1)
+		}
+		mods := classify(el, float64(sorted[5]+sorted[6])/2)
+		ch, ok := code39Lookup[wideKey(mods)]
+		if !ok {
+			return nil, fmt.Errorf("Code 39 第 %d 個字元無法辨識", i+1)
+		}
+		text = append(text, ch)
+		d.Modules = append(d.Modules, mods...)
+		if i < n-1 {
+			d.Modules = append(d.Modules, 1)
+		}
+	}
+	if text[0] != '*' || text[n-1] != '*' {
+		return nil, fmt.Errorf("Code 39 缺少起始/終止字元")
+	}
+	d.Text = string(text[1 : n-1])
+	if strings.IndexByte(d.Text, '*') >= 0 {
+		return nil, fmt.Errorf("Code 39 資料中出現起始/終止字元")
+	}
+	return d, nil
+}
+
+// splitThreshold 取得寬窄分界 (最小與最大寬度的中點), 無法區分時回傳 false
+func splitThreshold(widths []int) (float64, bool) {
+	if len(widths) == 0 {
+		return 0, false
+	}
+	min, max := widths[0], widths[0]
+	for _, w := range widths {
+		if w < min {
+			min = w
+		}
+		if w > max {
+			max = w
+		}
+	}
+	if float64(max) < float64(min)*1.5 {
+		return 0, false
+	}
+	return float64(min+max) / 2, true
+}
+
+// decodeCodabar 解碼 Codabar (每個字元 7 個元素, 字元之間以窄空隔開)
+func decodeCodabar(runs []int) (*Decoded, error) {
+	if len(runs) < 23 || (len(runs)+1)%8 != 0 {
+		return nil, fmt.Errorf("Codabar 條空數量不符 (%d)", len(runs))
+	}
+	n := (len(runs) + 1) / 8
+	var elements []int
+	for i := 0; i < n; i++ {
+		elements = append(elements, runs[i*8:i*8+7]...)
+	}
+	threshold, ok := splitThreshold(elements)
+	if !ok {
+		return nil, fmt.Errorf("Codabar 無法區分寬窄元素")
+	}
+
+	d := &Decoded{Symbology: "CODA", TwoWidth: true}
+	var text []byte
+	for i := 0; i < n; i++ {
+		mods := classify(runs[i*8:i*8+7], threshold)
+		ch, ok := codabarLookup[wideKey(mods)]
+		if !ok {
+			return nil, fmt.Errorf("Codabar 第 %d 個字元無法辨識", i+1)
+		}
+		text = append(text, ch)
+		d.Modules = append(d.Modules, mods...)
+		if i < n-1 {
+			d.Modules = append(d.Modules, 1)
+		}
+	}
+	isGuard := func(ch byte) bool { return ch >= 'A' && ch <= 'D' }
+	if !isGuard(text[0]) || !isGuard(text[n-1]) {
+		return nil, fmt.Errorf("Codabar 缺少起始/終止字元")
+	}
+	d.Text = string(text)
+	return d, nil
+}
+
+// decodeITF 解碼 Interleaved 2 of 5 (起始 4 窄元素, 每對數字 10 個元素交錯, 終止為寬條窄空窄條)
+func decodeITF(runs []int) (*Decoded, error) {
+	if len(runs) < 17 || (len(runs)-7)%10 != 0 {
+		return nil, fmt.Errorf("Interleaved 2 of 5 條空數量不符 (%d)", len(runs))
+	}
+	var bars, spaces []int
+	for i, r := range runs {
+		if i%2 == 0 {
+			bars = append(bars, r)
+		} else {
+			spaces = append(spaces, r)
+		}
+	}
+	barThreshold, ok1 := splitThreshold(bars)
+	spaceThreshold, ok2 := splitThreshold(spaces)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("Interleaved 2 of 5 無法區分寬窄元素")
+	}
+
+	d := &Decoded{Symbology: "25", TwoWidth: true, Modules: make([]int, len(runs))}
+	for i, r := range runs {
+		threshold := barThreshold
+		if i%2 == 1 {
+			threshold = spaceThreshold
+		}
+		d.Modules[i] = 1
+		if float64(r) > threshold {
+			d.Modules[i] = 2
+		}
+	}
+	if wideKey(d.Modules[:4]) != "0000" {
+		return nil, fmt.Errorf("Interleaved 2 of 5 起始符無法辨識")
+	}
+	if wideKey(d.Modules[len(runs)-3:]) != "100" {
+		return nil, fmt.Errorf("Interleaved 2 of 5 終止符無法辨識")
+	}
+
+	var text []byte
+	for i := 4; i+10 <= len(runs)-3; i += 10 {
+		var barKey, spaceKey []int
+		for j := 0; j < 10; j += 2 {
+			barKey = append(barKey, d.Modules[i+j])
+			spaceKey = append(spaceKey, d.Modules[i+j+1])
+		}
+		first, ok1 := itfLookup[wideKey(barKey)]
+		second, ok2 := itfLookup[wideKey(spaceKey)]
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("Interleaved 2 of 5 第 %d 組數字無法辨識", (i-4)/10+1)
+		}
+		text = append(text, byte('0'+first), byte('0'+second))
+	}
+	d.Text = string(text)
+	return d, nil
+}
+
+// decodeEAN 解碼 EAN-13/UPC-A (59 個元素, 95 模組) 或 EAN-8 (43 個元素, 67 模組)
+func decodeEAN(runs []int) (*Decoded, error) {
+	var symbology string
+	switch len(runs) {
+	case 59:
+		symbology = "EAN13"
+	case 43:
+		symbology = "EAN8"
+	default:
+		return nil, fmt.Errorf("EAN/UPC 條空數量不符 (%d)", len(runs))
+	}
+	half := 6
+	if symbology == "EAN8" {
+		half = 4
+	}
+
+	d := &Decoded{Symbology: symbology}
+	guard := func(seg []int, total int) bool {
+		mods, ok := normalize(seg, total, 1)
+		d.Modules = append(d.Modules, mods...)
+		return ok
+	}
+	digit := func(seg []int, darkFirst bool) ([2]int, bool) {
+		mods, ok := normalize(seg, 7, 4)
+		if !ok {
+			return [2]int{}, false
+		}
+		d.Modules = append(d.Modules, mods...)
+		var key strings.Builder
+		dark := darkFirst
+		for _, m := range mods {
+			ch := "0"
+			if dark {
+				ch = "1"
+			}
+			key.WriteString(strings.Repeat(ch, m))
+			dark = !dark
+		}
+		v, ok := eanLookup[key.String()]
+		return v, ok
+	}
+
+	if !guard(runs[0:3], 3) {
+		return nil, fmt.Errorf("%s 起始護線無法辨識", symbology)
+	}
+	var result []byte
+	var parity []byte
+	pos := 3
+	for i := 0; i < half; i++ {
+		v, ok := digit(runs[pos:pos+4], false)
+		if !ok || v[1] == 'R' {
+			return nil, fmt.Errorf("%s 左側第 %d 位數字無法辨識", symbology, i+1)
+		}
+		result = append(result, byte('0'+v[0]))
+		parity = append(parity, byte(v[1]))
+		pos += 4
+	}
+	if !guard(runs[pos:pos+5], 5) {
+		return nil, fmt.Errorf("%s 中間護線無法辨識", symbology)
+	}
+	pos += 5
+	for i := 0; i < half; i++ {
+		v, ok := digit(runs[pos:pos+4], true)
+		if !ok || v[1] != 'R' {
+			return nil, fmt.Errorf("%s 右側第 %d 位數字無法辨識", symbology, i+1)
+		}
+		result = append(result, byte('0'+v[0]))
+		pos += 4
+	}
+	if !guard(runs[pos:pos+3], 3) {
+		return nil, fmt.Errorf("%s 結束護線無法辨識", symbology)
+	}
+
+	if symbology == "EAN13" {
+		first := -1
+		for i, p := range ean13Parity {
+			if p == string(parity) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return nil, fmt.Errorf("EAN13 同位組合無法辨識")
+		}
+		result = append([]byte{byte('0' + first)}, result...)
+	} else if strings.Contains(string(parity), "G") {
+		return nil, fmt.Errorf("EAN8 左側只能使用 L 編碼")
+	}
+
+	n := len(result)
+	if Mod10CheckDigit(string(result[:n-1])) != int(result[n-1]-'0') {
+		return nil, fmt.Errorf("%s 檢查碼錯誤", symbology)
+	}
+	d.Text = string(result)
+	return d, nil
+}
@@ -0,0 +1,121 @@
+package barcode
+
+import "fmt"
+
+// eanLCodes EAN/UPC 左側奇同位 (L) 編碼
+var eanLCodes = [10]string{
+	"0001101", "0011001", "0010011", "0111101", "0100011",
+	"0110001", "0101111", "0111011", "0110111", "0001011",
+}
+
+// ean13Parity EAN-13 第一位數字決定左側六位的同位 (L/G)
+var ean13Parity = [10]string{
+	"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG",
+	"LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL",
+}
+
+// EANDigitCode 取得 EAN 數字在指定同位集 (L, G, R) 的 7 模組編碼
+func EANDigitCode(digit int, set byte) string {
+	l := eanLCodes[digit]
+	r := make([]byte, 7)
+	for i := 0; i < 7; i++ {
+		if l[i] == '0' {
+			r[i] = '1'
+		} else {
+			r[i] = '0'
+		}
+	}
+	switch set {
+	case 'R':
+		return string(r)
+	case 'G':
+		g := make([]byte, 7)
+		for i := 0; i < 7; i++ {
+			g[i] = r[6-i]
+		}
+		return string(g)
+	default:
+		return l
+	}
+}
+
+// EAN13ParityPattern 取得 EAN-13 第一位數字對應的同位組合
+func EAN13ParityPattern(first int) string {
+	return ean13Parity[first]
+}
+
+// completeCheckDigit 補上或驗證 Mod 10 檢查碼
+func completeCheckDigit(name, data string, length int) (string, error) {
+	if !isDigits(data) {
+		return "", fmt.Errorf("%s 只能包含數字", name)
+	}
+	switch len(data) {
+	case length - 1:
+		return data + fmt.Sprint(Mod10CheckDigit(data)), nil
+	case length:
+		expected := Mod10CheckDigit(data[:length-1])
+		if int(data[length-1]-'0') != expected {
+			return "", fmt.Errorf("%s 檢查碼錯誤: 應為 %d", name, expected)
+		}
+		return data, nil
+	default:
+		return "", fmt.Errorf("%s 需要 %d 或 %d 位數字", name, length-1, length)
+	}
+}
+
+// encodeEAN13 編碼 EAN-13
+func encodeEAN13(data string) (*Pattern, error) {
+	digits, err := completeCheckDigit("EAN13", data, 13)
+	if err != nil {
+		return nil, err
+	}
+
+	bits := "101"
+	parity := ean13Parity[digits[0]-'0']
+	for i := 1; i <= 6; i++ {
+		bits += EANDigitCode(int(digits[i]-'0'), parity[i-1])
+	}
+	bits += "01010"
+	for i := 7; i <= 12; i++ {
+		bits += EANDigitCode(int(digits[i]-'0'), 'R')
+	}
+	bits += "101"
+
+	return &Pattern{Symbology: "EAN13", Widths: appendModules(nil, bits), Text: digits}, nil
+}
+
+// encodeEAN8 編碼 EAN-8
+func encodeEAN8(data string) (*Pattern, error) {
+	digits, err := completeCheckDigit("EAN8", data, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	bits := "101"
+	for i := 0; i < 4; i++ {
+		bits += EANDigitCode(int(digits[i]-'0'), 'L')
+	}
+	bits += "01010"
+	for i := 4; i < 8; i++ {
+		bits += EANDigitCode(int(digits[i]-'0'), 'R')
+	}
+	bits += "101"
+
+	return &Pattern{Symbology: "EAN8", Widths: appendModules(nil, bits), Text: digits}, nil
+}
+
+// encodeUPCA 編碼 UPC-A (等同首位為 0 的 EAN-13)
+func encodeUPCA(data string) (*Pattern, error) {
+	digits, err := completeCheckDigit("UPCA", data, 12)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := encodeEAN13("0" + digits)
+	if err != nil {
+		return nil, err
+	}
+	pattern.Symbology = "UPCA"
+	pattern.Text = digits
+	return pattern, nil
+}
@@ -0,0 +1,53 @@
+package barcode
+
+import "fmt"
+
+// itfPatterns Interleaved 2 of 5 的數字編碼, 1 代表寬元素
+var itfPatterns = [10]string{
+	"00110", "10001", "01001", "11000", "00101",
+	"10100", "01100", "00011", "10010", "01010",
+}
+
+// ITFPattern 取得 Interleaved 2 of 5 數字的寬窄序列
+func ITFPattern(digit int) string {
+	return itfPatterns[digit]
+}
+
+// encodeITF 編碼 Interleaved 2 of 5 (withCheck 為 true 時加入 Mod 10 檢查碼)
+func encodeITF(data string, withCheck bool) (*Pattern, error) {
+	if !isDigits(data) {
+		return nil, fmt.Errorf("Interleaved 2 of 5 只能包含數字")
+	}
+	digits := data
+	if withCheck {
+		digits += fmt.Sprint(Mod10CheckDigit(digits))
+	}
+	if len(digits)%2 == 1 {
+		digits = "0" + digits
+	}
+	return interleave(digits, "25"), nil
+}
+
+// encodeITF14 編碼 ITF-14 (13 位時自動補上檢查碼)
+func encodeITF14(data string) (*Pattern, error) {
+	digits, err := completeCheckDigit("ITF14", data, 14)
+	if err != nil {
+		return nil, err
+	}
+	return interleave(digits, "ITF14"), nil
+}
+
+// interleave 將偶數位數字兩兩交錯編碼
+func interleave(digits, symbology string) *Pattern {
+	pattern := &Pattern{Symbology: symbology, TwoWidth: true, Text: digits}
+	pattern.Widths = append(pattern.Widths, 1, 1, 1, 1)
+	for i := 0; i+1 < len(digits); i += 2 {
+		bars := itfPatterns[digits[i]-'0']
+		spaces := itfPatterns[digits[i+1]-'0']
+		for j := 0; j < 5; j++ {
+			pattern.Widths = append(pattern.Widths, int(bars[j]-'0')+1, int(spaces[j]-'0')+1)
+		}
+	}
+	pattern.Widths = append(pattern.Widths, 2, 1, 1)
+	return pattern
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"tspl-simulator/lsp"
+)
+
+// tspl-lsp 以 stdio 提供 TSPL 的 Language Server Protocol 服務, 日誌輸出至 stderr
+func main() {
+	log.SetPrefix("tspl-lsp: ")
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		log.Fatal(err)
+	}
+}
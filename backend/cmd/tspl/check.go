@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"tspl-simulator/lint"
+)
+
+// fileFindings 單一檔案的檢查結果
+type fileFindings struct {
+	File     string         `json:"file"`
+	Valid    bool           `json:"valid"`
+	Findings []lint.Finding `json:"findings"`
+}
+
+// runCheck 執行 validate 或 lint; 有錯誤 (或 -strict 時有警告) 時回傳 exitFailed
+func runCheck(name string, args []string) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := fs.String("format", "text", "輸出格式: text、json 或 sarif")
+	strict := fs.Bool("strict", false, "警告也視為失敗")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		fmt.Fprintf(os.Stderr, "不支援的輸出格式: %s\n", *format)
+		return exitUsage
+	}
+
+	check := lint.Validate
+	if name == "lint" {
+		check = lint.Lint
+	}
+
+	var results []fileFindings
+	code := exitOK
+	for _, file := range inputs(fs.Args()) {
+		src, err := readInput(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "讀取 %s 失敗: %v\n", file, err)
+			return exitIOError
+		}
+		findings := check(src)
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		failed := lint.HasErrors(findings) || (*strict && len(findings) > 0)
+		if failed {
+			code = exitFailed
+		}
+		results = append(results, fileFindings{File: file, Valid: !failed, Findings: findings})
+	}
+
+	var err error
+	switch *format {
+	case "json":
+		err = writeJSON(os.Stdout, results)
+	case "sarif":
+		err = writeSARIF(os.Stdout, results)
+	default:
+		writeText(os.Stdout, results)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "輸出失敗: %v\n", err)
+		return exitIOError
+	}
+	return code
+}
+
+// writeText 以 "檔案:行:欄: 嚴重程度: 訊息 [規則]" 格式輸出
+func writeText(w io.Writer, results []fileFindings) {
+	for _, r := range results {
+		for _, f := range r.Findings {
+			column := f.Column
+			if column < 1 {
+				column = 1
+			}
+			fmt.Fprintf(w, "%s:%d:%d: %s: %s [%s]\n", r.File, f.Line, column, f.Severity, f.Message, f.Rule)
+		}
+	}
+}
+
+// writeJSON 輸出 JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeSARIF 輸出 SARIF 2.1.0, 供 GitHub code scanning 等工具讀取
+func writeSARIF(w io.Writer, results []fileFindings) error {
+	type region struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region region `json:"region"`
+		} `json:"physicalLocation"`
+	}
+	type text struct {
+		Text string `json:"text"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     string     `json:"level"`
+		Message   text       `json:"message"`
+		Locations []location `json:"locations"`
+	}
+	type rule struct {
+		ID               string `json:"id"`
+		ShortDescription text   `json:"shortDescription"`
+	}
+
+	ids := make([]string, 0, len(lint.Rules))
+	for id := range lint.Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	rules := make([]rule, 0, len(ids))
+	for _, id := range ids {
+		rules = append(rules, rule{ID: id, ShortDescription: text{lint.Rules[id]}})
+	}
+
+	out := []result{}
+	for _, r := range results {
+		for _, f := range r.Findings {
+			var loc location
+			loc.PhysicalLocation.ArtifactLocation.URI = r.File
+			// SARIF 的行號從 1 開始, 整份檔案的錯誤 (行號 0) 標示在第 1 行
+			loc.PhysicalLocation.Region = region{StartLine: max(f.Line, 1), StartColumn: f.Column}
+			out = append(out, result{RuleID: f.Rule, Level: f.Severity, Message: text{f.Message}, Locations: []location{loc}})
+		}
+	}
+
+	sarif := map[string]interface{}{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": []interface{}{map[string]interface{}{
+			"tool": map[string]interface{}{
+				"driver": map[string]interface{}{
+					"name":  "tspl",
+					"rules": rules,
+				},
+			},
+			"results": out,
+		}},
+	}
+	return writeJSON(w, sarif)
+}
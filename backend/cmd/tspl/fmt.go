@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"tspl-simulator/formatter"
+)
+
+// runFmt 整理 TSPL 程式碼格式; -check 時有未整理的檔案, 或整理後解析結果改變時回傳 exitFailed
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := fs.Bool("w", false, "將結果寫回原檔案")
+	list := fs.Bool("l", false, "只列出格式不一致的檔案")
+	check := fs.Bool("check", false, "只檢查, 格式不一致時以代碼 1 結束")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, file := range inputs(fs.Args()) {
+		src, err := readInput(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "讀取 %s 失敗: %v\n", file, err)
+			return exitIOError
+		}
+		formatted, err := formatter.FormatVerified(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			code = exitFailed
+			continue
+		}
+		changed := formatted != src
+
+		switch {
+		case *list || *check:
+			if changed {
+				fmt.Println(file)
+				if *check {
+					code = exitFailed
+				}
+			}
+		case *write && file != "-":
+			if changed {
+				if err := os.WriteFile(file, []byte(formatted), 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "寫入 %s 失敗: %v\n", file, err)
+					return exitIOError
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+	return code
+}
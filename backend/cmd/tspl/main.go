@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"tspl-simulator/imagestore"
+	"tspl-simulator/parser"
+)
+
+// 結束代碼
+const (
+	exitOK      = 0 // 成功 / 沒有錯誤
+	exitFailed  = 1 // 驗證失敗、有檢查錯誤或格式不一致
+	exitUsage   = 2 // 參數錯誤
+	exitIOError = 3 // 檔案讀寫或繪製失敗
+)
+
+const usage = `tspl - TSPL 標籤工具 (不需啟動伺服器)
+
+用法:
+  tspl validate [-format text|json|sarif] [-strict] [檔案...]
+  tspl lint     [-format text|json|sarif] [-strict] [檔案...]
+  tspl render   [-o 輸出檔] [-format png|svg|pdf] [-mode realistic] [檔案]
+  tspl fmt      [-w] [-l] [-check] [檔案...]
+  tspl replay   [-target render|mqtt|printer] [-printer host[:port]] [-topic 主題] 工作ID...
+  tspl replay   [-target ...] -from 日期 [-to 日期] [-source api|mqtt] [-status accepted|rejected]
+
+未指定檔案或檔案為 "-" 時從標準輸入讀取
+replay 讀取伺服器儲存的工作, 儲存位置與 MQTT 連線預設使用相同的環境變數
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	// DOWNLOAD/PUTBMP/PUTPCX 使用的模擬打印機記憶體
+	parser.SetFileStore(imagestore.NewStore())
+
+	var code int
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "validate":
+		code = runCheck("validate", args)
+	case "lint":
+		code = runCheck("lint", args)
+	case "render":
+		code = runRender(args)
+	case "fmt":
+		code = runFmt(args)
+	case "replay":
+		code = runReplay(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "未知的子命令: %s\n\n%s", os.Args[1], usage)
+		code = exitUsage
+	}
+	os.Exit(code)
+}
+
+// readInput 讀取檔案內容, "-" 代表標準輸入
+func readInput(name string) (string, error) {
+	if name == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(name)
+	return string(data), err
+}
+
+// inputs 未指定檔案時使用標準輸入
+func inputs(files []string) []string {
+	if len(files) == 0 {
+		return []string{"-"}
+	}
+	return files
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tspl-simulator/lint"
+	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
+)
+
+// runRender 驗證並繪製標籤, 依 -format 或輸出檔副檔名輸出 PNG/SVG/PDF (PDF 每張列印的標籤一頁)
+func runRender(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	output := fs.String("o", "", "輸出檔 (預設為輸入檔名加上格式副檔名, 標準輸入時輸出至標準輸出)")
+	format := fs.String("format", "", "輸出格式: png、svg 或 pdf (預設依輸出檔副檔名, 否則為 png)")
+	mode := fs.String("mode", "", "realistic: 依 DENSITY/SPEED 模擬熱感列印效果 (僅 PNG)")
+	grid := fs.Int("grid", 0, "點格線間距 (點), 0 為不顯示 (僅 SVG/PDF)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "render 一次只能處理一個檔案")
+		return exitUsage
+	}
+	input := "-"
+	if fs.NArg() == 1 {
+		input = fs.Arg(0)
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+		if *format == "" {
+			*format = "png"
+		}
+	}
+	if *format != "png" && *format != "svg" && *format != "pdf" {
+		fmt.Fprintf(os.Stderr, "不支援的輸出格式: %s\n", *format)
+		return exitUsage
+	}
+	if *mode != "" && (*mode != "realistic" || *format != "png") {
+		fmt.Fprintf(os.Stderr, "-mode 僅支援 realistic 且只能用於 PNG\n")
+		return exitUsage
+	}
+	if *grid < 0 || (*grid > 0 && *format == "png") {
+		fmt.Fprintf(os.Stderr, "-grid 必須為非負整數且只能用於 SVG/PDF\n")
+		return exitUsage
+	}
+	if *output == "" && input != "-" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + "." + *format
+	}
+
+	src, err := readInput(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "讀取 %s 失敗: %v\n", input, err)
+		return exitIOError
+	}
+
+	findings := lint.Validate(src)
+	if lint.HasErrors(findings) {
+		writeText(os.Stderr, []fileFindings{{File: input, Findings: findings}})
+		fmt.Fprintln(os.Stderr, "TSPL 語法驗證失敗")
+		return exitFailed
+	}
+
+	data, err := parser.ParseTSPL(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "TSPL 解析錯誤: %v\n", err)
+		return exitFailed
+	}
+	var (
+		buf      bytes.Buffer
+		warnings []string
+	)
+	opts := renderer.VectorOptions{Grid: *grid}
+	switch *format {
+	case "svg":
+		warnings, err = renderer.RenderSVG(&buf, data, opts)
+	case "pdf":
+		warnings, err = renderer.RenderPDF(&buf, data, opts)
+	default:
+		var canvas *renderer.Canvas
+		canvas, warnings = renderer.Render(data)
+		if *mode == "realistic" {
+			err = png.Encode(&buf, renderer.SimulateThermal(canvas, data.Print).Image())
+		} else {
+			err = canvas.EncodePNG(&buf)
+		}
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "渲染警告: %s\n", w)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "影像編碼失敗: %v\n", err)
+		return exitIOError
+	}
+
+	if *output == "" || *output == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*output, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "寫入輸出失敗: %v\n", err)
+		return exitIOError
+	}
+	return exitOK
+}
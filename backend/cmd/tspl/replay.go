@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"tspl-simulator/config"
+	"tspl-simulator/models"
+	"tspl-simulator/mqtt"
+	"tspl-simulator/replay"
+	"tspl-simulator/storage"
+)
+
+// runReplay 重送儲存的列印工作: 指定工作 ID, 或以 -from/-to 選取日期範圍 (由舊到新)
+// 儲存位置與 MQTT 連線預設使用伺服器相同的環境變數
+func runReplay(args []string) int {
+	cfg := config.LoadConfig()
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	data := fs.String("data", cfg.StoragePath, "儲存資料夾 (STORAGE_PATH)")
+	backend := fs.String("backend", cfg.StorageBackend, "儲存後端: fs、kv 或 s3 (STORAGE_BACKEND)")
+	target := fs.String("target", replay.TargetRender, "重送目標: render、mqtt 或 printer")
+	printer := fs.String("printer", "", "printer: 打印機位址 host[:port], 預設埠 "+replay.DefaultPrinterPort)
+	broker := fs.String("broker", "", "mqtt: MQTT 代理 host[:port] (預設為 MQTT_BROKER/MQTT_PORT)")
+	topic := fs.String("topic", "", "mqtt: 發布的主題 (預設為 MQTT_TOPIC)")
+	from := fs.String("from", "", "日期範圍起點 YYYY-MM-DD 或 RFC 3339")
+	to := fs.String("to", "", "日期範圍終點 (含當天) YYYY-MM-DD 或 RFC 3339")
+	source := fs.String("source", "", "日期範圍的來源: api 或 mqtt")
+	status := fs.String("status", "", "日期範圍的狀態: accepted 或 rejected (rejected 須搭配 -include-rejected)")
+	includeRejected := fs.Bool("include-rejected", false, "日期範圍包含驗證失敗的工作 (預設只重送驗證通過的工作)")
+	limit := fs.Int("limit", replay.MaxRangeJobs, "日期範圍最多重送的工作數")
+	timeout := fs.Duration("timeout", replay.DefaultTimeout, "printer: 連線與傳送逾時")
+	format := fs.String("format", "text", "輸出格式: text 或 json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	t := replay.Target{Kind: *target, Topic: *topic, Printer: *printer}
+	if err := t.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "不支援的輸出格式: %s\n", *format)
+		return exitUsage
+	}
+	if *backend == "memory" {
+		fmt.Fprintln(os.Stderr, "memory 後端只存在於伺服器程序中, 無法重送")
+		return exitUsage
+	}
+	ranged := *from != "" || *to != ""
+	if ranged == (fs.NArg() > 0) {
+		fmt.Fprintln(os.Stderr, "請指定工作 ID 或 -from/-to 其中之一")
+		return exitUsage
+	}
+	if !ranged && (*source != "" || *status != "" || *includeRejected) {
+		fmt.Fprintln(os.Stderr, "-source/-status/-include-rejected 只能與 -from/-to 一起使用")
+		return exitUsage
+	}
+	if *limit < 1 || *limit > replay.MaxRangeJobs {
+		fmt.Fprintf(os.Stderr, "-limit 必須介於 1 與 %d 之間\n", replay.MaxRangeJobs)
+		return exitUsage
+	}
+
+	query := storage.JobQuery{Source: *source}
+	var err error
+	if query.Status, err = replay.RangeStatus(*status, *includeRejected); err != nil {
+		if errors.Is(err, replay.ErrRejectedNotIncluded) {
+			err = errors.New("-status rejected 須搭配 -include-rejected")
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if query.From, err = storage.ParseJobTime(*from, false); err != nil {
+		fmt.Fprintln(os.Stderr, "-from 格式錯誤, 應為 YYYY-MM-DD 或 RFC 3339")
+		return exitUsage
+	}
+	if query.To, err = storage.ParseJobTime(*to, true); err != nil {
+		fmt.Fprintln(os.Stderr, "-to 格式錯誤, 應為 YYYY-MM-DD 或 RFC 3339")
+		return exitUsage
+	}
+
+	// 伺服器可能正在寫入同一份儲存, 以唯讀方式開啟
+	s, err := storage.OpenReadOnly(*backend, *data, storage.S3Config{
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		Prefix:    cfg.S3Prefix,
+		AccessKey: cfg.S3AccessKey,
+		SecretKey: cfg.S3SecretKey,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "開啟儲存服務失敗: %v\n", err)
+		return exitIOError
+	}
+	defer s.Close()
+
+	// 命令列由操作者直接指定打印機, 只允許該位址
+	replayer := &replay.Replayer{Storage: s, Timeout: *timeout, Printers: []string{*printer}}
+	if t.Kind == replay.TargetMQTT {
+		if *broker != "" {
+			cfg.MQTTBroker = *broker
+			if host, port, err := net.SplitHostPort(*broker); err == nil {
+				cfg.MQTTBroker, cfg.MQTTPort = host, port
+			}
+		}
+		client, err := mqtt.NewPublisher(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitIOError
+		}
+		defer client.Close()
+		replayer.MQTT, replayer.Topic = client, client.Topic()
+	}
+
+	var results []models.ReplayResult
+	code := exitOK
+	if ranged {
+		var total int
+		var truncated bool
+		results, total, truncated, err = replayer.ReplayRange(query, *limit, t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "列出工作失敗: %v\n", err)
+			return exitIOError
+		}
+		if truncated {
+			fmt.Fprintf(os.Stderr, "符合的工作有 %d 筆, 只重送最早的 %d 筆\n", total, *limit)
+		}
+	} else {
+		for _, id := range fs.Args() {
+			result, err := replayer.Replay(id, t)
+			if err != nil {
+				if errors.Is(err, storage.ErrJobNotFound) {
+					fmt.Fprintf(os.Stderr, "%s: 找不到工作\n", id)
+				} else {
+					fmt.Fprintf(os.Stderr, "%s: 讀取工作失敗: %v\n", id, err)
+				}
+				code = exitFailed
+				continue
+			}
+			results = append(results, result)
+		}
+	}
+
+	if *format == "json" {
+		if err := writeReplayJSON(os.Stdout, results); err != nil {
+			return exitIOError
+		}
+	} else {
+		writeReplayText(os.Stdout, results)
+	}
+	for _, r := range results {
+		if !r.Success || (r.Render != nil && len(r.Render.Changes) > 0) {
+			code = exitFailed
+		}
+	}
+	return code
+}
+
+// writeReplayText 每個工作一行結果, render 的差異逐行縮排列出
+func writeReplayText(w io.Writer, results []models.ReplayResult) {
+	for _, r := range results {
+		switch {
+		case !r.Success:
+			fmt.Fprintf(w, "%s: 失敗: %s\n", r.JobID, r.Error)
+		case r.Render != nil:
+			state := "相同"
+			if len(r.Render.Changes) > 0 {
+				state = "不同"
+			}
+			extra := ""
+			if r.Render.ScanFailures > 0 {
+				extra = fmt.Sprintf(", %d 個條碼掃描不符", r.Render.ScanFailures)
+			}
+			fmt.Fprintf(w, "%s: 重新渲染, 結果%s%s\n", r.JobID, state, extra)
+			for _, change := range r.Render.Changes {
+				fmt.Fprintf(w, "  %s\n", change)
+			}
+		case r.Topic != "":
+			fmt.Fprintf(w, "%s: 已發布到 %s\n", r.JobID, r.Topic)
+		default:
+			fmt.Fprintf(w, "%s: 已送出 %d 位元組到 %s\n", r.JobID, r.BytesSent, r.Printer)
+		}
+	}
+	if len(results) > 1 {
+		failed := 0
+		for _, r := range results {
+			if !r.Success {
+				failed++
+			}
+		}
+		fmt.Fprintf(w, "共 %d 筆, 失敗 %d 筆\n", len(results), failed)
+	}
+}
+
+// writeReplayJSON 輸出重送結果陣列
+func writeReplayJSON(w io.Writer, results []models.ReplayResult) error {
+	if results == nil {
+		results = []models.ReplayResult{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
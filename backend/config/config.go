@@ -1,7 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +17,33 @@ type Config struct {
 	MQTTUsername string
 	MQTTPassword string
 	MQTTTopic    string
+
+	// 儲存後端: fs (預設) / memory / kv / s3
+	StoragePath    string
+	StorageBackend string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3Prefix       string
+	S3AccessKey    string
+	S3SecretKey    string
+
+	// 保留規則: 各來源 (含驗證失敗的工作) 的最長保留時間、最多工作數與最大容量, 0 代表不限
+	RetentionAPIMaxAge     time.Duration
+	RetentionAPIMaxJobs    int
+	RetentionAPIMaxBytes   int64
+	RetentionMQTTMaxAge    time.Duration
+	RetentionMQTTMaxJobs   int
+	RetentionMQTTMaxBytes  int64
+	RetentionCompressAfter time.Duration // 日期資料夾超過此時間後以 gzip 壓縮, 0 代表不壓縮
+	RetentionInterval      time.Duration
+
+	// 模擬打印機記憶體 (DOWNLOAD 與上傳的影像) 的總大小與檔案數上限, 0 代表預設值
+	ImageStoreMaxBytes int64
+	ImageStoreMaxFiles int
+
+	// 重送 API 允許的打印機位址 host[:port] (以逗號分隔), 未設定時不允許重送到打印機
+	ReplayPrinters []string
 }
 
 func LoadConfig() *Config {
@@ -23,6 +55,30 @@ func LoadConfig() *Config {
 		MQTTUsername: getEnv("MQTT_USERNAME", ""),
 		MQTTPassword: getEnv("MQTT_PASSWORD", ""),
 		MQTTTopic:    getEnv("MQTT_TOPIC", "tspl/commands"),
+
+		StoragePath:    getEnv("STORAGE_PATH", "./data"),
+		StorageBackend: getEnv("STORAGE_BACKEND", "fs"),
+		S3Endpoint:     getEnv("S3_ENDPOINT", ""),
+		S3Region:       getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:       getEnv("S3_BUCKET", ""),
+		S3Prefix:       getEnv("S3_PREFIX", ""),
+		S3AccessKey:    getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:    getEnv("S3_SECRET_KEY", ""),
+
+		// RETENTION_API_* / RETENTION_MQTT_* 未設定時使用 RETENTION_* 的值
+		RetentionAPIMaxAge:     getEnvDuration("RETENTION_API_MAX_AGE", getEnvDuration("RETENTION_MAX_AGE", 0)),
+		RetentionAPIMaxJobs:    getEnvInt("RETENTION_API_MAX_JOBS", getEnvInt("RETENTION_MAX_JOBS", 0)),
+		RetentionAPIMaxBytes:   getEnvBytes("RETENTION_API_MAX_BYTES", getEnvBytes("RETENTION_MAX_BYTES", 0)),
+		RetentionMQTTMaxAge:    getEnvDuration("RETENTION_MQTT_MAX_AGE", getEnvDuration("RETENTION_MAX_AGE", 0)),
+		RetentionMQTTMaxJobs:   getEnvInt("RETENTION_MQTT_MAX_JOBS", getEnvInt("RETENTION_MAX_JOBS", 0)),
+		RetentionMQTTMaxBytes:  getEnvBytes("RETENTION_MQTT_MAX_BYTES", getEnvBytes("RETENTION_MAX_BYTES", 0)),
+		RetentionCompressAfter: getEnvDuration("RETENTION_COMPRESS_AFTER", 0),
+		RetentionInterval:      getEnvDuration("RETENTION_INTERVAL", time.Hour),
+
+		ImageStoreMaxBytes: getEnvBytes("IMAGE_STORE_MAX_BYTES", 0),
+		ImageStoreMaxFiles: getEnvInt("IMAGE_STORE_MAX_FILES", 0),
+
+		ReplayPrinters: getEnvList("REPLAY_PRINTERS"),
 	}
 }
 
@@ -33,3 +89,91 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvList 讀取以逗號分隔的清單, 忽略空白項目
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvDuration 讀取時間長度, 除 Go 的格式 (90m, 12h) 外接受天數 (30d); 格式錯誤時使用預設值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := parseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("警告: %s 格式錯誤 (%s), 使用預設值", key, value)
+		return defaultValue
+	}
+	return d
+}
+
+// parseDuration 解析時間長度, 支援 d (天) 單位
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(value)
+}
+
+// getEnvInt 讀取非負整數; 格式錯誤時使用預設值
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("警告: %s 格式錯誤 (%s), 使用預設值", key, value)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvBytes 讀取容量, 接受位元組數或 KB/MB/GB 單位 (1024 進位); 格式錯誤時使用預設值
+func getEnvBytes(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := parseBytes(value)
+	if err != nil {
+		log.Printf("警告: %s 格式錯誤 (%s), 使用預設值", key, value)
+		return defaultValue
+	}
+	return n
+}
+
+// parseBytes 解析容量 (例如 500MB、2GB)
+func parseBytes(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}}
+	upper := strings.ToUpper(strings.TrimSpace(value))
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("容量格式錯誤: %s", value)
+			}
+			return int64(n * float64(unit.size)), nil
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("容量格式錯誤: %s", value)
+	}
+	return n, nil
+}
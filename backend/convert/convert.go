@@ -0,0 +1,68 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/validator"
+)
+
+// Convert 在標籤語言之間轉換, 回傳轉換結果與無法轉換或有落差的內容
+// 支援 zpl → tspl 以及 tspl → zpl、epl; TSPL 來源須先通過驗證
+func Convert(source, from, to string) (string, []models.ConversionIssue, error) {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	switch {
+	case from == "zpl" && to == "tspl":
+		data, issues := ParseZPL(source)
+		output, emitIssues := EmitTSPL(data)
+		return output, append(issues, emitIssues...), nil
+	case from == "tspl" && (to == "zpl" || to == "epl"):
+		data, err := parseValidTSPL(source)
+		if err != nil {
+			return "", nil, err
+		}
+		if to == "zpl" {
+			output, issues := EmitZPL(data)
+			return output, issues, nil
+		}
+		output, issues := EmitEPL(data)
+		return output, issues, nil
+	}
+	return "", nil, fmt.Errorf("不支援從 %s 轉換為 %s", from, to)
+}
+
+// parseValidTSPL 驗證並解析 TSPL
+func parseValidTSPL(source string) (*models.RenderData, error) {
+	result := validator.ValidateTSPL(source)
+	if !result.Valid && len(result.Errors) > 0 {
+		first := result.Errors[0]
+		return nil, fmt.Errorf("TSPL 語法驗證失敗 (第 %d 行 %s: %s)", first.Line, first.Command, first.Message)
+	}
+	data, err := parser.ParseTSPLWithStore(source, parser.NewScratchStore())
+	if err != nil {
+		return nil, fmt.Errorf("TSPL 解析錯誤: %v", err)
+	}
+	return data, nil
+}
+
+// issueList 收集轉換問題, 相同的命令與訊息只記錄一次
+type issueList struct {
+	items []models.ConversionIssue
+	seen  map[string]bool
+}
+
+// add 記錄轉換問題
+func (l *issueList) add(command, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	key := command + "\x00" + message
+	if l.seen[key] {
+		return
+	}
+	if l.seen == nil {
+		l.seen = map[string]bool{}
+	}
+	l.seen[key] = true
+	l.items = append(l.items, models.ConversionIssue{Command: command, Message: message})
+}
@@ -0,0 +1,201 @@
+package convert
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+)
+
+var update = flag.Bool("update", false, "更新 testdata 中的 golden 檔案")
+
+// golden 比對輸出與 testdata/name; -update 時改寫 golden 檔案
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("輸出與 %s 不同:\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// convertFile 轉換 testdata 中的檔案
+func convertFile(t *testing.T, name, from, to string) (string, []models.ConversionIssue) {
+	t.Helper()
+	src, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, issues, err := Convert(string(src), from, to)
+	if err != nil {
+		t.Fatalf("Convert(%s, %s → %s): %v", name, from, to, err)
+	}
+	return output, issues
+}
+
+// placed 元素的類型與位置
+type placed struct {
+	Type string
+	X, Y int
+}
+
+func placements(elements []models.Element) []placed {
+	out := make([]placed, len(elements))
+	for i, el := range elements {
+		out[i] = placed{el.Type, el.X, el.Y}
+	}
+	return out
+}
+
+// comparePlacements 比對兩份元素的類型與位置
+func comparePlacements(t *testing.T, got, want []models.Element) {
+	t.Helper()
+	g, w := placements(got), placements(want)
+	if len(g) != len(w) {
+		t.Fatalf("元素數 %d, want %d:\n got  %v\n want %v", len(g), len(w), g, w)
+	}
+	for i := range g {
+		if g[i] != w[i] {
+			t.Errorf("元素 %d = %+v, want %+v", i+1, g[i], w[i])
+		}
+	}
+}
+
+func TestConvertZPLToTSPL(t *testing.T) {
+	output, issues := convertFile(t, "shipping.zpl", "zpl", "tspl")
+	golden(t, "shipping.tspl.golden", output)
+	if len(issues) != 0 {
+		t.Errorf("不應有轉換問題: %v", issues)
+	}
+
+	// 產生的 TSPL 重新解析後, 元素與 ZPL 的解析結果相同
+	src, _ := os.ReadFile(filepath.Join("testdata", "shipping.zpl"))
+	zpl, _ := ParseZPL(string(src))
+	data, err := parser.ParseTSPLWithStore(output, parser.NewScratchStore())
+	if err != nil {
+		t.Fatalf("產生的 TSPL 無法解析: %v", err)
+	}
+	comparePlacements(t, data.Elements, zpl.Elements)
+
+	if data.Width != 812 || data.Height != 406 {
+		t.Errorf("標籤尺寸 %dx%d 點, want 812x406 (^PW/^LL)", data.Width, data.Height)
+	}
+	if len(data.Steps) != 1 || data.Steps[0].Sets != 2 {
+		t.Errorf("Steps = %+v, want 一次 PRINT 2 (^PQ2)", data.Steps)
+	}
+	checks := []struct {
+		index      int
+		properties map[string]interface{}
+	}{
+		{0, map[string]interface{}{"text": "ACME Logistics"}},
+		{3, map[string]interface{}{"type": "128", "code": "PKG-000123", "height": 80}},
+		{4, map[string]interface{}{"data": "https://example.com/t/123", "eccLevel": "Q"}},
+		{6, map[string]interface{}{"text": "FRAGILE", "rotation": 90}},
+	}
+	for _, c := range checks {
+		el := data.Elements[c.index]
+		for name, want := range c.properties {
+			if got := el.Properties[name]; got != want {
+				t.Errorf("元素 %d (%s) %s = %v, want %v", c.index+1, el.Type, name, got, want)
+			}
+		}
+	}
+}
+
+func TestConvertTSPLToZPL(t *testing.T) {
+	output, issues := convertFile(t, "price.tspl", "tspl", "zpl")
+	golden(t, "price.zpl.golden", output)
+	if !hasIssue(issues, "TEXT", "以 ZPL 可縮放字型 0 近似") {
+		t.Errorf("點陣字型的近似應列在轉換問題中: %v", issues)
+	}
+
+	// 產生的 ZPL 重新解析後, 元素的類型與位置與原本的 TSPL 相同
+	src, _ := os.ReadFile(filepath.Join("testdata", "price.tspl"))
+	original, err := parser.ParseTSPLWithStore(string(src), parser.NewScratchStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ParseZPL 只支援版面的子集, 濃度、速度與媒體設定 (~SD、^PR、^MN) 會略過
+	data, zplIssues := ParseZPL(output)
+	for _, issue := range zplIssues {
+		if issue.Command != "~SD" && issue.Command != "^PR" && issue.Command != "^MN" {
+			t.Errorf("產生的 ZPL 有無法解析的命令: %+v", issue)
+		}
+	}
+	comparePlacements(t, data.Elements, original.Elements)
+	if data.Width != original.Width || data.Height != original.Height {
+		t.Errorf("標籤尺寸 %dx%d, want %dx%d", data.Width, data.Height, original.Width, original.Height)
+	}
+	if len(data.Steps) != 1 || data.Steps[0].Sets != 3 {
+		t.Errorf("Steps = %+v, want 一次 PRINT 的 3 份 (^PQ3)", data.Steps)
+	}
+}
+
+func TestConvertTSPLToEPL(t *testing.T) {
+	output, issues := convertFile(t, "price.tspl", "tspl", "epl")
+	golden(t, "price.epl.golden", output)
+	if !hasIssue(issues, "TEXT", "EPL2 字型 2") {
+		t.Errorf("字型 4 的近似應列在轉換問題中: %v", issues)
+	}
+
+	// 沒有對應命令的圖形以線段近似並回報
+	circle, issues, err := Convert("SIZE 30 mm, 30 mm\nCLS\nCIRCLE 10,10,40,2\nPRINT 1\n", "tspl", "epl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(circle, "\nLO") || !hasIssue(issues, "CIRCLE", "逐列的 LO 線段") {
+		t.Errorf("CIRCLE 應以 LO 線段輸出並回報: %v\n%s", issues, circle)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name, source, from, to, want string
+	}{
+		{"unsupported pair", "^XA^XZ", "zpl", "epl", "不支援從 zpl 轉換為 epl"},
+		{"invalid TSPL", "SIZE 50 mm, 30 mm\nFOO 1\nPRINT 1\n", "tspl", "zpl", "TSPL 語法驗證失敗 (第 2 行"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Convert(tt.source, tt.from, tt.to)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Convert: got %v, want 包含 %q 的錯誤", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseZPLIssues(t *testing.T) {
+	_, issues := ParseZPL("^FO10,10^FDoutside^FS\n^XA\n^FO10,10^XYZ^FDx^FS\n")
+	for _, want := range []string{"位於 ^XA...^XZ 之外", "沒有以 ^XZ 結束", "未指定 ^PW/^LL"} {
+		found := false
+		for _, issue := range issues {
+			found = found || strings.Contains(issue.Message, want)
+		}
+		if !found {
+			t.Errorf("問題清單 %v 缺少 %q", issues, want)
+		}
+	}
+}
+
+// hasIssue 是否有指定命令且訊息包含 text 的轉換問題
+func hasIssue(issues []models.ConversionIssue, command, text string) bool {
+	for _, issue := range issues {
+		if issue.Command == command && strings.Contains(issue.Message, text) {
+			return true
+		}
+	}
+	return false
+}
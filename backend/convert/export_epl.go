@@ -0,0 +1,193 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
+)
+
+// eplFonts EPL2 內建字型 1-5 的字元寬高 (點, 203 DPI)
+var eplFonts = [][2]int{{8, 12}, {10, 16}, {12, 20}, {14, 24}, {32, 48}}
+
+// eplBarcodes TSPL 條碼類型與 EPL2 B 命令條碼類型的對應
+var eplBarcodes = map[string]string{
+	"128": "1", "128M": "1", "EAN128": "1E",
+	"39": "3", "39S": "3", "39C": "3C",
+	"EAN13": "E30", "EAN8": "E80", "UPCA": "UA0",
+	"25": "2", "25C": "2C", "ITF14": "2", "CODA": "K",
+}
+
+// EmitEPL 將元素模型輸出為 EPL2: 設定命令 (q、Q、R、Z、D、S) 之後, 每次 PRINT 輸出 N、元素與 P
+// EPL2 與 TSPL 的座標與旋轉原點相同; 字型取最接近的內建字型與倍率,
+// 沒有對應命令的元素 (CIRCLE、ELLIPSE、TRIANGLE、PUTBMP/PUTPCX) 以逐列的 LO 線段輸出
+func EmitEPL(data *models.RenderData) (string, []models.ConversionIssue) {
+	var b strings.Builder
+	issues := &issueList{}
+
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "q%d\n", data.Width)
+	switch data.Media.Type {
+	case "gap":
+		fmt.Fprintf(&b, "Q%d,%d\n", data.Height, parser.ToDots(data.Media.Distance, data.Media.Unit))
+	case "bline":
+		fmt.Fprintf(&b, "Q%d,B%d\n", data.Height, parser.ToDots(data.Media.Distance, data.Media.Unit))
+	default:
+		fmt.Fprintf(&b, "Q%d,0\n", data.Height)
+	}
+	if data.Reference != (models.Reference{}) {
+		fmt.Fprintf(&b, "R%d,%d\n", data.Reference.X, data.Reference.Y)
+	}
+	if data.Direction == 1 {
+		b.WriteString("ZB\n")
+	} else {
+		b.WriteString("ZT\n")
+	}
+	if data.Print.Density != parser.DefaultDensity {
+		fmt.Fprintf(&b, "D%d\n", data.Print.Density)
+	}
+	if data.Print.Speed != parser.DefaultSpeed {
+		speed := int(math.Round(data.Print.Speed))
+		if float64(speed) != data.Print.Speed {
+			issues.add("SPEED", "EPL2 只接受整數速度, %s 以 %d 輸出", formatFloat(data.Print.Speed), speed)
+		}
+		fmt.Fprintf(&b, "S%d\n", speed)
+	}
+	if data.Print.Ribbon {
+		issues.add("SET", "EPL2 由打印機設定決定是否使用碳帶, SET RIBBON 未輸出")
+	}
+
+	emit := func(elements []models.Element) {
+		b.WriteString("N\n")
+		for _, el := range elements {
+			b.WriteString(eplElement(data, el, issues))
+		}
+	}
+
+	printed := false
+	for _, step := range data.Steps {
+		if step.Command != "PRINT" {
+			continue
+		}
+		printed = true
+		emit(data.Elements[step.ElementStart:step.ElementEnd])
+		fmt.Fprintf(&b, "P%d,%d\n", step.Sets, max(step.Copies, 1))
+	}
+	if !printed {
+		emit(data.Elements)
+	}
+	return b.String(), issues.items
+}
+
+// eplElement 將單一元素輸出為 EPL2 命令
+func eplElement(data *models.RenderData, el models.Element, issues *issueList) string {
+	p := el.Properties
+	i := func(key string) int { return renderer.IntProp(p, key) }
+	s := func(key string) string { return renderer.StringProp(p, key) }
+	command := strings.ToUpper(el.Type)
+	rotation := i("rotation") / 90 % 4
+
+	switch el.Type {
+	case "text":
+		font := s("font")
+		cellW, cellH := renderer.FontCell(font, i("xScale"), i("yScale"))
+		epl, h, v := nearestEPLFont(cellW, cellH)
+		if w, hh := eplFonts[epl-1][0]*h, eplFonts[epl-1][1]*v; w != cellW || hh != cellH {
+			issues.add(command, "TSPL 字型 %s (%d×%d 點) 以 EPL2 字型 %d 放大 %d×%d 倍 (%d×%d 點) 近似", font, cellW, cellH, epl, h, v, w, hh)
+		}
+		return fmt.Sprintf("A%d,%d,%d,%d,%d,%d,N,\"%s\"\n", el.X, el.Y, rotation, epl, h, v, eplQuote(s("text")))
+
+	case "barcode":
+		codeType := strings.ToUpper(s("type"))
+		kind, ok := eplBarcodes[codeType]
+		if !ok {
+			issues.add(command, "EPL2 不支援條碼類型 %s, 已略過", codeType)
+			return ""
+		}
+		switch codeType {
+		case "128M":
+			issues.add(command, "128M 的手動子集切換碼無法轉換, 改以 EPL2 自動模式編碼")
+		case "ITF14":
+			issues.add(command, "ITF14 以 Interleaved 2 of 5 輸出, 不含外框 (bearer bar)")
+		}
+		human := "B"
+		switch i("readable") {
+		case 0:
+			human = "N"
+		case 1, 3:
+			issues.add(command, "EPL2 的人眼可讀文字只能置中")
+		}
+		narrow := max(i("narrow"), 1)
+		return fmt.Sprintf("B%d,%d,%d,%s,%d,%d,%d,%s,\"%s\"\n", el.X, el.Y, rotation, kind, narrow, max(i("wide"), narrow), i("height"), human, eplQuote(s("code")))
+
+	case "qrcode":
+		if rotation != 0 {
+			issues.add(command, "EPL2 的 QR Code 不支援旋轉, 以 0 度輸出")
+		}
+		w, h := qrcodeBox(el)
+		x, y := origin(el.X, el.Y, w, h, i("rotation"))
+		return fmt.Sprintf("b%d,%d,Q,m2,s%d,e%s,i%s,\"%s\"\n", x, y, max(i("cellSize"), 1), s("eccLevel"), s("mode"), eplQuote(s("data")))
+
+	case "box":
+		if i("radius") > 0 {
+			issues.add(command, "EPL2 的 X 命令不支援圓角, 以直角輸出")
+		}
+		return fmt.Sprintf("X%d,%d,%d,%d,%d\n", el.X, el.Y, i("thickness"), i("endX"), i("endY"))
+
+	case "bar":
+		return fmt.Sprintf("LO%d,%d,%d,%d\n", el.X, el.Y, i("width"), i("height"))
+
+	case "reverse":
+		return fmt.Sprintf("LE%d,%d,%d,%d\n", el.X, el.Y, i("width"), i("height"))
+
+	case "erase":
+		return fmt.Sprintf("LW%d,%d,%d,%d\n", el.X, el.Y, i("width"), i("height"))
+
+	case "diagonal":
+		return fmt.Sprintf("LS%d,%d,%d,%d,%d\n", el.X, el.Y, i("thickness"), i("endX"), i("endY"))
+	}
+
+	// 沒有對應命令: 以逐列的 LO 線段輸出點陣
+	canvas, bounds := rasterize(data, el)
+	if bounds.Empty() {
+		issues.add(command, "(%d, %d) 無法繪製, 已略過", el.X, el.Y)
+		return ""
+	}
+	issues.add(command, "EPL2 沒有對應的命令, 以逐列的 LO 線段輸出")
+	var b strings.Builder
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; {
+			if !canvas.Get(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < bounds.Max.X && canvas.Get(x, y) {
+				x++
+			}
+			fmt.Fprintf(&b, "LO%d,%d,%d,1\n", start, y, x-start)
+		}
+	}
+	return b.String()
+}
+
+// nearestEPLFont 選擇字高最接近的 EPL2 字型 1-5 與水平、垂直倍率 (1-6, 1-9)
+func nearestEPLFont(width, height int) (int, int, int) {
+	best, bestH, bestV, bestDiff := 1, 1, 1, math.MaxInt
+	for n, cell := range eplFonts {
+		v := min(max(int(math.Round(float64(height)/float64(cell[1]))), 1), 9)
+		h := min(max(int(math.Round(float64(width)/float64(cell[0]))), 1), 6)
+		if diff := abs(cell[1]*v-height)*2 + abs(cell[0]*h-width); diff < bestDiff {
+			best, bestH, bestV, bestDiff = n+1, h, v, diff
+		}
+	}
+	return best, bestH, bestV
+}
+
+// eplQuote EPL2 字串中的反斜線與雙引號以反斜線跳脫
+func eplQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
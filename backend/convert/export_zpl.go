@@ -0,0 +1,245 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
+)
+
+// tsplOrientations TSPL 旋轉角度與 ZPL 方向的對應
+var tsplOrientations = map[int]string{0: "N", 90: "R", 180: "I", 270: "B"}
+
+// EmitZPL 將元素模型輸出為 ZPL II: 每次 PRINT 輸出一個 ^XA...^XZ 標籤格式
+// 旋轉欄位的原點由 TSPL 的旋轉原點換算為 ZPL 的左上角; 字型以可縮放字型 0 近似,
+// ZPL 沒有對應命令的元素 (TRIANGLE、PUTBMP/PUTPCX) 以 ^GFA 點陣圖輸出
+func EmitZPL(data *models.RenderData) (string, []models.ConversionIssue) {
+	var b strings.Builder
+	issues := &issueList{}
+
+	if data.Print.Density != parser.DefaultDensity {
+		fmt.Fprintf(&b, "~SD%02d\n", min(data.Print.Density*2, 30))
+	}
+
+	emit := func(elements []models.Element, quantity int) {
+		b.WriteString("^XA\n")
+		fmt.Fprintf(&b, "^PW%d\n^LL%d\n", data.Width, data.Height)
+		switch data.Media.Type {
+		case "gap":
+			b.WriteString("^MNY\n")
+		case "bline":
+			b.WriteString("^MNM\n")
+		default:
+			b.WriteString("^MNN\n")
+		}
+		if data.Print.Ribbon {
+			b.WriteString("^MTT\n")
+		}
+		if data.Print.Speed != parser.DefaultSpeed {
+			speed := int(math.Round(data.Print.Speed))
+			if float64(speed) != data.Print.Speed {
+				issues.add("SPEED", "^PR 只接受整數速度, %s 以 %d 輸出", formatFloat(data.Print.Speed), speed)
+			}
+			fmt.Fprintf(&b, "^PR%d\n", speed)
+		}
+		if data.Reference != (models.Reference{}) {
+			fmt.Fprintf(&b, "^LH%d,%d\n", data.Reference.X, data.Reference.Y)
+		}
+		if data.Direction == 1 {
+			b.WriteString("^POI\n")
+		}
+		for _, el := range elements {
+			b.WriteString(zplElement(data, el, issues))
+		}
+		if quantity > 1 {
+			fmt.Fprintf(&b, "^PQ%d\n", quantity)
+		}
+		b.WriteString("^XZ\n")
+	}
+
+	printed := false
+	for _, step := range data.Steps {
+		if step.Command != "PRINT" {
+			continue
+		}
+		printed = true
+		emit(data.Elements[step.ElementStart:step.ElementEnd], step.Sets*max(step.Copies, 1))
+	}
+	if !printed {
+		emit(data.Elements, 1)
+	}
+	return b.String(), issues.items
+}
+
+// zplElement 將單一元素輸出為 ^FO...^FS 欄位
+func zplElement(data *models.RenderData, el models.Element, issues *issueList) string {
+	p := el.Properties
+	i := func(key string) int { return renderer.IntProp(p, key) }
+	s := func(key string) string { return renderer.StringProp(p, key) }
+	command := strings.ToUpper(el.Type)
+	rotation := i("rotation")
+
+	switch el.Type {
+	case "text":
+		w, h := textBox(el)
+		x, y := origin(el.X, el.Y, w, h, rotation)
+		font := s("font")
+		cellW, cellH := renderer.FontCell(font, i("xScale"), i("yScale"))
+		if isBitmapFont(font) {
+			issues.add(command, "TSPL 字型 %s 以 ZPL 可縮放字型 0 近似", font)
+		}
+		return fmt.Sprintf("^FO%d,%d^A0%s,%d,%d%s^FS\n", x, y, tsplOrientations[rotation], cellH, cellW, zplData(s("text")))
+
+	case "barcode":
+		w, h := barcodeBox(el)
+		x, y := origin(el.X, el.Y, w, h, rotation)
+		field, code, err := zplBarcode(el, issues)
+		if err != nil {
+			issues.add(command, "%v, 已略過", err)
+			return ""
+		}
+		narrow := max(i("narrow"), 1)
+		ratio := math.Min(math.Max(float64(max(i("wide"), narrow))/float64(narrow), 2), 3)
+		return fmt.Sprintf("^BY%d,%.1f,%d\n^FO%d,%d%s%s^FS\n", narrow, ratio, i("height"), x, y, field, zplData(code))
+
+	case "qrcode":
+		if rotation != 0 {
+			issues.add(command, "ZPL 的 QR Code 不支援旋轉, 以 0 度輸出")
+		}
+		w, h := qrcodeBox(el)
+		x, y := origin(el.X, el.Y, w, h, rotation)
+		return fmt.Sprintf("^FO%d,%d^BQN,2,%d^FD%s%s,%s^FS\n", x, y, min(max(i("cellSize"), 1), 10), s("eccLevel"), s("mode"), s("data"))
+
+	case "box":
+		w, h := i("endX")-el.X, i("endY")-el.Y
+		rounding := 0
+		if r := i("radius"); r > 0 && min(w, h) > 0 {
+			rounding = min(max(int(math.Round(float64(r)*16/float64(min(w, h)))), 1), 8)
+		}
+		return fmt.Sprintf("^FO%d,%d^GB%d,%d,%d,B,%d^FS\n", el.X, el.Y, w, h, i("thickness"), rounding)
+
+	case "bar":
+		w, h := i("width"), i("height")
+		return fmt.Sprintf("^FO%d,%d^GB%d,%d,%d^FS\n", el.X, el.Y, w, h, min(w, h))
+
+	case "reverse":
+		w, h := i("width"), i("height")
+		return fmt.Sprintf("^FO%d,%d^FR^GB%d,%d,%d^FS\n", el.X, el.Y, w, h, min(w, h))
+
+	case "erase":
+		w, h := i("width"), i("height")
+		return fmt.Sprintf("^FO%d,%d^GB%d,%d,%d,W^FS\n", el.X, el.Y, w, h, min(w, h))
+
+	case "circle":
+		return fmt.Sprintf("^FO%d,%d^GC%d,%d,B^FS\n", el.X, el.Y, i("diameter"), i("thickness"))
+
+	case "ellipse":
+		return fmt.Sprintf("^FO%d,%d^GE%d,%d,%d,B^FS\n", el.X, el.Y, i("width"), i("height"), i("thickness"))
+
+	case "diagonal":
+		x1, y1, x2, y2 := el.X, el.Y, i("endX"), i("endY")
+		lean := "L"
+		if (x2-x1)*(y2-y1) < 0 {
+			lean = "R"
+		}
+		return fmt.Sprintf("^FO%d,%d^GD%d,%d,%d,B,%s^FS\n", min(x1, x2), min(y1, y2), abs(x2-x1), abs(y2-y1), i("thickness"), lean)
+	}
+
+	// 沒有對應命令: 以 ^GFA 點陣圖輸出
+	canvas, bounds := rasterize(data, el)
+	if bounds.Empty() {
+		issues.add(command, "(%d, %d) 無法繪製, 已略過", el.X, el.Y)
+		return ""
+	}
+	issues.add(command, "ZPL 沒有對應的命令, 以 ^GFA 點陣圖輸出")
+	rowBytes := (bounds.Dx() + 7) / 8
+	var hex strings.Builder
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for bx := 0; bx < rowBytes; bx++ {
+			var v byte
+			for bit := 0; bit < 8; bit++ {
+				if x := bounds.Min.X + bx*8 + bit; x < bounds.Max.X && canvas.Get(x, y) {
+					v |= 0x80 >> bit
+				}
+			}
+			fmt.Fprintf(&hex, "%02X", v)
+		}
+	}
+	total := rowBytes * bounds.Dy()
+	return fmt.Sprintf("^FO%d,%d^GFA,%d,%d,%d,%s^FS\n", bounds.Min.X, bounds.Min.Y, total, total, rowBytes, hex.String())
+}
+
+// zplBarcode 依 TSPL 條碼類型產生 ZPL 條碼命令 (不含 ^FO) 與欄位資料
+func zplBarcode(el models.Element, issues *issueList) (string, string, error) {
+	p := el.Properties
+	code := renderer.StringProp(p, "code")
+	codeType := strings.ToUpper(renderer.StringProp(p, "type"))
+	o := tsplOrientations[renderer.IntProp(p, "rotation")]
+	h := renderer.IntProp(p, "height")
+	readable := "Y"
+	switch renderer.IntProp(p, "readable") {
+	case 0:
+		readable = "N"
+	case 1, 3:
+		issues.add("BARCODE", "ZPL 的人眼可讀文字只能置中")
+	}
+
+	switch codeType {
+	case "128":
+		return fmt.Sprintf("^BC%s,%d,%s,N,N,A", o, h, readable), code, nil
+	case "128M":
+		issues.add("BARCODE", "128M 的手動子集切換碼無法轉換, 改以 ZPL 自動模式編碼")
+		return fmt.Sprintf("^BC%s,%d,%s,N,N,A", o, h, readable), code, nil
+	case "EAN128":
+		return fmt.Sprintf("^BC%s,%d,%s,N,N,D", o, h, readable), code, nil
+	case "39", "39S":
+		return fmt.Sprintf("^B3%s,N,%d,%s,N", o, h, readable), code, nil
+	case "39C":
+		return fmt.Sprintf("^B3%s,Y,%d,%s,N", o, h, readable), code, nil
+	case "EAN13":
+		return fmt.Sprintf("^BE%s,%d,%s,N", o, h, readable), code, nil
+	case "EAN8":
+		return fmt.Sprintf("^B8%s,%d,%s,N", o, h, readable), code, nil
+	case "UPCA":
+		return fmt.Sprintf("^BU%s,%d,%s,N,Y", o, h, readable), code, nil
+	case "25":
+		return fmt.Sprintf("^B2%s,%d,%s,N,N", o, h, readable), code, nil
+	case "25C":
+		return fmt.Sprintf("^B2%s,%d,%s,N,Y", o, h, readable), code, nil
+	case "ITF14":
+		issues.add("BARCODE", "ITF14 以 ^B2 輸出, 不含外框 (bearer bar)")
+		return fmt.Sprintf("^B2%s,%d,%s,N,N", o, h, readable), code, nil
+	case "CODA":
+		start, stop := "A", "A"
+		if len(code) >= 2 && strings.ContainsAny(code[:1], "ABCDabcd") && strings.ContainsAny(code[len(code)-1:], "ABCDabcd") {
+			start, stop, code = strings.ToUpper(code[:1]), strings.ToUpper(code[len(code)-1:]), code[1:len(code)-1]
+		}
+		return fmt.Sprintf("^BK%s,N,%d,%s,N,%s,%s", o, h, readable, start, stop), code, nil
+	}
+	return "", "", fmt.Errorf("ZPL 不支援條碼類型 %s", codeType)
+}
+
+// zplData 產生 ^FD 欄位資料; 含 ^ 或 ~ 時以 ^FH 十六進位跳脫
+func zplData(s string) string {
+	if !strings.ContainsAny(s, "^~") {
+		return "^FD" + s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '^', '~', '_':
+			fmt.Fprintf(&b, "_%02X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "^FH^FD" + b.String()
+}
+
+// isBitmapFont 判斷是否為 TSPL 內建點陣字型 "1"-"8"
+func isBitmapFont(font string) bool {
+	return len(font) == 1 && font[0] >= '1' && font[0] <= '8'
+}
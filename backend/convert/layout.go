@@ -0,0 +1,94 @@
+package convert
+
+import (
+	"image"
+
+	"tspl-simulator/barcode"
+	"tspl-simulator/models"
+	"tspl-simulator/qrcode"
+	"tspl-simulator/renderer"
+)
+
+// tsplFontCell TSPL 字型在倍率 1 時的字元寬高
+func tsplFontCell(font string) (int, int) {
+	return renderer.FontCell(font, 1, 1)
+}
+
+// textBox 文字元素未旋轉時的寬高 (點)
+func textBox(el models.Element) (int, int) {
+	p := el.Properties
+	font, text := renderer.StringProp(p, "font"), renderer.StringProp(p, "text")
+	xScale, yScale := renderer.IntProp(p, "xScale"), renderer.IntProp(p, "yScale")
+	_, h := renderer.FontCell(font, xScale, yScale)
+	return renderer.TextWidth(text, font, xScale, yScale), h
+}
+
+// barcodeBox 條碼元素未旋轉時的寬高 (點, 不含人眼可讀文字); 無法編碼時寬度為 0
+func barcodeBox(el models.Element) (int, int) {
+	p := el.Properties
+	height := renderer.IntProp(p, "height")
+	pattern, err := barcode.Encode(renderer.StringProp(p, "type"), renderer.StringProp(p, "code"))
+	if err != nil {
+		return 0, height
+	}
+	return pattern.TotalWidth(renderer.IntProp(p, "narrow"), renderer.IntProp(p, "wide")), height
+}
+
+// anchor 將旋轉後外框的左上角 (ZPL/EPL 的欄位原點) 換算為 TSPL 的旋轉原點
+// w, h 為元素未旋轉時的寬高, rotation 為順時針角度
+func anchor(x, y, w, h, rotation int) (int, int) {
+	w, h = max(w-1, 0), max(h-1, 0)
+	switch rotation {
+	case 90:
+		return x + h, y
+	case 180:
+		return x + w, y + h
+	case 270:
+		return x, y + w
+	}
+	return x, y
+}
+
+// origin 為 anchor 的反向換算: 由 TSPL 的旋轉原點求旋轉後外框的左上角
+func origin(x, y, w, h, rotation int) (int, int) {
+	w, h = max(w-1, 0), max(h-1, 0)
+	switch rotation {
+	case 90:
+		return x - h, y
+	case 180:
+		return x - w, y - h
+	case 270:
+		return x, y - w
+	}
+	return x, y
+}
+
+// qrcodeBox QR Code 元素的邊長 (點); 無法編碼時為 0
+func qrcodeBox(el models.Element) (int, int) {
+	p := el.Properties
+	code, err := qrcode.Encode(renderer.StringProp(p, "data"), qrcode.ParseECC(renderer.StringProp(p, "eccLevel")))
+	if err != nil {
+		return 0, 0
+	}
+	size := code.Size * max(renderer.IntProp(p, "cellSize"), 1)
+	return size, size
+}
+
+// rasterize 將單一元素繪製為點陣 (不含 REFERENCE 與 DIRECTION), 回傳有黑點的範圍
+// 供目標語言沒有對應命令的元素以點陣圖輸出; 沒有任何黑點時範圍為空
+func rasterize(data *models.RenderData, el models.Element) (*renderer.Canvas, image.Rectangle) {
+	plain := *data
+	plain.Reference = models.Reference{}
+	plain.Direction = 0
+	canvas, _ := renderer.RenderElements(&plain, []models.Element{el})
+
+	bounds := image.Rectangle{}
+	for y := 0; y < canvas.Height; y++ {
+		for x := 0; x < canvas.Width; x++ {
+			if canvas.Get(x, y) {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return canvas, bounds
+}
@@ -0,0 +1,124 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
+)
+
+// EmitTSPL 將元素模型輸出為 TSPL: SIZE 之後每次 PRINT 輸出 CLS、該次緩衝區的元素與 PRINT
+// 沒有 PRINT 步驟時輸出全部元素且不列印; 無法以 TSPL 表示的元素列於回傳的問題清單
+func EmitTSPL(data *models.RenderData) (string, []models.ConversionIssue) {
+	var b strings.Builder
+	var issues []models.ConversionIssue
+
+	size := data.LabelSize
+	unit := size.Unit
+	if unit == "" {
+		unit = "mm"
+	}
+	fmt.Fprintf(&b, "SIZE %s %s, %s %s\n", formatFloat(size.Width), unit, formatFloat(size.Height), unit)
+	switch data.Media.Type {
+	case "gap":
+		fmt.Fprintf(&b, "GAP %s %s, %s %s\n", formatFloat(data.Media.Distance), data.Media.Unit, formatFloat(data.Media.Offset), data.Media.Unit)
+	case "bline":
+		fmt.Fprintf(&b, "BLINE %s %s, %s %s\n", formatFloat(data.Media.Distance), data.Media.Unit, formatFloat(data.Media.Offset), data.Media.Unit)
+	}
+	if data.Direction != 0 {
+		fmt.Fprintf(&b, "DIRECTION %d\n", data.Direction)
+	}
+	if data.Reference != (models.Reference{}) {
+		fmt.Fprintf(&b, "REFERENCE %d,%d\n", data.Reference.X, data.Reference.Y)
+	}
+	if data.Print.Density != parser.DefaultDensity {
+		fmt.Fprintf(&b, "DENSITY %d\n", data.Print.Density)
+	}
+	if data.Print.Speed != parser.DefaultSpeed {
+		fmt.Fprintf(&b, "SPEED %s\n", formatFloat(data.Print.Speed))
+	}
+	if data.Print.Ribbon {
+		b.WriteString("SET RIBBON ON\n")
+	}
+
+	emit := func(elements []models.Element) {
+		b.WriteString("CLS\n")
+		for _, el := range elements {
+			line, err := tsplElement(el)
+			if err != nil {
+				issues = append(issues, models.ConversionIssue{Command: strings.ToUpper(el.Type), Message: err.Error()})
+				continue
+			}
+			if strings.Contains(line, `\["]`) {
+				issues = append(issues, models.ConversionIssue{Command: strings.ToUpper(el.Type), Message: `內容含雙引號, 以 TSPL 跳脫字元 \["] 輸出, 模擬器無法預覽`})
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	printed := false
+	for _, step := range data.Steps {
+		if step.Command != "PRINT" {
+			continue
+		}
+		printed = true
+		emit(data.Elements[step.ElementStart:step.ElementEnd])
+		if step.Copies > 1 {
+			fmt.Fprintf(&b, "PRINT %d,%d\n", step.Sets, step.Copies)
+		} else {
+			fmt.Fprintf(&b, "PRINT %d\n", step.Sets)
+		}
+	}
+	if !printed {
+		emit(data.Elements)
+	}
+	return b.String(), issues
+}
+
+// tsplElement 將單一元素輸出為 TSPL 命令
+func tsplElement(el models.Element) (string, error) {
+	p := el.Properties
+	i := func(key string) int { return renderer.IntProp(p, key) }
+	s := func(key string) string { return renderer.StringProp(p, key) }
+
+	switch el.Type {
+	case "text":
+		return fmt.Sprintf(`TEXT %d,%d,"%s",%d,%d,%d,"%s"`, el.X, el.Y, s("font"), i("rotation"), i("xScale"), i("yScale"), quote(s("text"))), nil
+	case "barcode":
+		return fmt.Sprintf(`BARCODE %d,%d,"%s",%d,%d,%d,%d,%d,"%s"`, el.X, el.Y, s("type"), i("height"), i("readable"), i("rotation"), i("narrow"), i("wide"), quote(s("code"))), nil
+	case "qrcode":
+		return fmt.Sprintf(`QRCODE %d,%d,%s,%d,%s,%d,"%s"`, el.X, el.Y, s("eccLevel"), i("cellSize"), s("mode"), i("rotation"), quote(s("data"))), nil
+	case "box":
+		line := fmt.Sprintf("BOX %d,%d,%d,%d,%d", el.X, el.Y, i("endX"), i("endY"), i("thickness"))
+		if _, ok := p["radius"]; ok {
+			line += "," + strconv.Itoa(i("radius"))
+		}
+		return line, nil
+	case "bar":
+		return fmt.Sprintf("BAR %d,%d,%d,%d", el.X, el.Y, i("width"), i("height")), nil
+	case "reverse", "erase":
+		return fmt.Sprintf("%s %d,%d,%d,%d", strings.ToUpper(el.Type), el.X, el.Y, i("width"), i("height")), nil
+	case "circle":
+		return fmt.Sprintf("CIRCLE %d,%d,%d,%d", el.X, el.Y, i("diameter"), i("thickness")), nil
+	case "ellipse":
+		return fmt.Sprintf("ELLIPSE %d,%d,%d,%d,%d", el.X, el.Y, i("width"), i("height"), i("thickness")), nil
+	case "diagonal":
+		return fmt.Sprintf("DIAGONAL %d,%d,%d,%d,%d", el.X, el.Y, i("endX"), i("endY"), i("thickness")), nil
+	case "triangle":
+		return fmt.Sprintf("TRIANGLE %d,%d,%d,%d,%d,%d,%d", el.X, el.Y, i("x2"), i("y2"), i("x3"), i("y3"), i("thickness")), nil
+	}
+	return "", fmt.Errorf("元素類型 %s 無法轉換為 TSPL, 已略過", el.Type)
+}
+
+// quote TSPL 字串中的雙引號以 \["] 表示
+func quote(s string) string {
+	return strings.ReplaceAll(s, `"`, `\["]`)
+}
+
+// formatFloat 輸出不含多餘小數位的數值
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,515 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+)
+
+// ZPL 預設的標籤尺寸 (未指定 ^PW/^LL 時使用 4×6 吋)
+const (
+	defaultZPLWidth  = 4 * parser.DPI
+	defaultZPLLength = 6 * parser.DPI
+)
+
+// zplBitmapFonts ZPL 內建點陣字型的基本高寬 (點, 203 DPI)
+var zplBitmapFonts = map[byte][2]int{
+	'A': {9, 5}, 'B': {11, 7}, 'C': {18, 10}, 'D': {18, 10},
+	'E': {28, 15}, 'F': {26, 13}, 'G': {60, 40}, 'H': {21, 13},
+}
+
+// zplRotations ZPL 方向與 TSPL 旋轉角度 (順時針) 的對應
+var zplRotations = map[byte]int{'N': 0, 'R': 90, 'I': 180, 'B': 270}
+
+// zplCommand ZPL 命令, Code 為 ^ 或 ~ 之後的兩個字元 (大寫)
+type zplCommand struct {
+	Prefix byte
+	Code   string
+	Params string
+	Line   int
+}
+
+// zplField ^FO 到 ^FS 之間的欄位狀態
+type zplField struct {
+	x, y     int
+	font     byte
+	rotation int
+	height   int // ^A 字高 (點), 0 代表未指定
+	width    int
+	kind     string // "", "barcode", "qrcode"
+	params   []string
+	data     string
+	hasData  bool
+}
+
+// zplState 解析中的標籤格式狀態
+type zplState struct {
+	data     *models.RenderData
+	issues   []models.ConversionIssue
+	width    int
+	length   int
+	sized    bool
+	quantity int
+	start    int
+	field    zplField
+	defFont  zplField // ^CF 設定的預設字型
+	moduleW  int      // ^BY 模組寬度
+	ratio    float64  // ^BY 寬窄比
+	barH     int      // ^BY 條碼高度
+}
+
+// ParseZPL 將 ZPL II 子集 (^XA/^XZ、^FO、^A、^CF、^FD/^FS、^BY、^BC、^BQ、^GB、^PW/^LL、^PQ、^FX)
+// 解析為與 TSPL 相同的元素模型; 每個 ^XA...^XZ 對應一次 PRINT
+// 不支援的命令略過並列於回傳的問題清單
+func ParseZPL(zpl string) (*models.RenderData, []models.ConversionIssue) {
+	s := &zplState{
+		data: &models.RenderData{
+			Elements: []models.Element{},
+			DPI:      parser.DPI,
+			Media:    models.Media{Type: "continuous", Unit: "mm"},
+			Print:    models.PrintSettings{Density: parser.DefaultDensity, Speed: parser.DefaultSpeed},
+		},
+		width:   defaultZPLWidth,
+		length:  defaultZPLLength,
+		moduleW: 2,
+		ratio:   3,
+		barH:    10,
+	}
+
+	inFormat := false
+	for _, cmd := range tokenizeZPL(zpl) {
+		switch {
+		case cmd.Prefix == '^' && cmd.Code == "XA":
+			inFormat = true
+			s.quantity = 1
+			s.start = len(s.data.Elements)
+			s.field = s.defFont
+		case cmd.Prefix == '^' && cmd.Code == "XZ":
+			if !inFormat {
+				s.issue(cmd, "^XZ 之前沒有對應的 ^XA")
+				continue
+			}
+			inFormat = false
+			s.data.Steps = append(s.data.Steps, models.JobStep{
+				Command:      "PRINT",
+				Line:         cmd.Line,
+				Sets:         s.quantity,
+				Copies:       1,
+				ElementStart: s.start,
+				ElementEnd:   len(s.data.Elements),
+			})
+		case !inFormat:
+			s.issue(cmd, "位於 ^XA...^XZ 之外, 已略過")
+		default:
+			s.apply(cmd)
+		}
+	}
+	if inFormat {
+		s.issues = append(s.issues, models.ConversionIssue{Command: "^XZ", Message: "標籤格式沒有以 ^XZ 結束, 不會列印"})
+	}
+	if !s.sized {
+		s.issues = append(s.issues, models.ConversionIssue{Command: "^PW", Message: fmt.Sprintf("未指定 ^PW/^LL, 以 4×6 吋 (%d×%d 點) 計算", defaultZPLWidth, defaultZPLLength)})
+	}
+
+	s.data.LabelSize = models.LabelSize{Width: dotsToMM(s.width), Height: dotsToMM(s.length), Unit: "mm"}
+	s.data.Width = parser.ToDots(s.data.LabelSize.Width, "mm")
+	s.data.Height = parser.ToDots(s.data.LabelSize.Height, "mm")
+	return s.data, s.issues
+}
+
+// apply 處理 ^XA...^XZ 之間的單一命令
+func (s *zplState) apply(cmd zplCommand) {
+	params := splitZPLParams(cmd.Params)
+	if cmd.Prefix == '^' && cmd.Code[0] == 'A' {
+		s.setFont(cmd, params)
+		return
+	}
+	if cmd.Prefix == '~' {
+		s.issue(cmd, "不支援的 ZPL 命令, 已略過")
+		return
+	}
+
+	switch cmd.Code {
+	case "FX":
+		// 註解
+	case "CF":
+		font := strings.ToUpper(zplString(params, 0, "A"))[0]
+		if font != '0' && zplBitmapFonts[font] == [2]int{} {
+			s.issue(cmd, fmt.Sprintf("不支援的字型 %c, 以字型 0 代替", font))
+			font = '0'
+		}
+		s.defFont = zplField{font: font, height: zplInt(params, 1, 0), width: zplInt(params, 2, 0)}
+		s.field.font, s.field.height, s.field.width = s.defFont.font, s.defFont.height, s.defFont.width
+	case "FO":
+		s.field.x = zplInt(params, 0, 0)
+		s.field.y = zplInt(params, 1, 0)
+	case "FD":
+		s.field.data = cmd.Params
+		s.field.hasData = true
+	case "FS":
+		s.finishField(cmd)
+	case "BY":
+		s.moduleW = zplInt(params, 0, s.moduleW)
+		if len(params) > 1 && params[1] != "" {
+			if r, err := strconv.ParseFloat(params[1], 64); err == nil {
+				s.ratio = r
+			}
+		}
+		s.barH = zplInt(params, 2, s.barH)
+	case "BC":
+		s.field.kind = "barcode"
+		s.field.params = params
+	case "BQ":
+		s.field.kind = "qrcode"
+		s.field.params = params
+	case "GB":
+		s.graphicBox(cmd, params)
+	case "PW":
+		s.width = zplInt(params, 0, s.width)
+		s.sized = true
+	case "LL":
+		s.length = zplInt(params, 0, s.length)
+		s.sized = true
+	case "PQ":
+		s.quantity = max(zplInt(params, 0, 1), 1)
+		if zplInt(params, 2, 0) > 0 {
+			s.issue(cmd, "^PQ 的複本數 (replicates) 不支援, 僅轉換列印張數")
+		}
+	default:
+		s.issue(cmd, "不支援的 ZPL 命令, 已略過")
+	}
+}
+
+// setFont 處理 ^Afo,h,w
+func (s *zplState) setFont(cmd zplCommand, params []string) {
+	font := cmd.Code[1]
+	if font != '0' && zplBitmapFonts[font] == [2]int{} {
+		s.issue(cmd, fmt.Sprintf("不支援的字型 %c, 以字型 0 代替", font))
+		font = '0'
+	}
+	s.field.font = font
+	orientation := "N"
+	if len(params) > 0 && params[0] != "" {
+		orientation = strings.ToUpper(params[0])
+	}
+	rotation, ok := zplRotations[orientation[0]]
+	if !ok {
+		s.issue(cmd, "不支援的方向 "+orientation+", 以 N 代替")
+	}
+	s.field.rotation = rotation
+	s.field.height = zplInt(params, 1, 0)
+	s.field.width = zplInt(params, 2, 0)
+}
+
+// finishField 在 ^FS 時依欄位種類加入文字、條碼或 QR Code 元素
+func (s *zplState) finishField(cmd zplCommand) {
+	f := s.field
+	s.field = s.defFont
+	switch f.kind {
+	case "barcode":
+		s.addBarcode(cmd, f)
+	case "qrcode":
+		s.addQRCode(cmd, f)
+	default:
+		if f.hasData {
+			s.addText(f)
+		}
+	}
+}
+
+// addText 將 ^A 字型換算為 TSPL 字型: 字型 0 以點數 (pt) 為倍率, 點陣字型取高度最接近的內建字型
+func (s *zplState) addText(f zplField) {
+	font, xScale, yScale := "0", 0, 0
+	if f.font == '0' {
+		height := f.height
+		if height == 0 {
+			height = zplBitmapFonts['A'][0]
+		}
+		width := f.width
+		if width == 0 {
+			width = height
+		}
+		yScale = max(int(math.Round(float64(height)*72/parser.DPI)), 1)
+		xScale = max(int(math.Round(float64(width)*72/parser.DPI)), 1)
+	} else {
+		// 未指定 ^A 時使用 ZPL 預設字型 A
+		base, ok := zplBitmapFonts[f.font]
+		if !ok {
+			base = zplBitmapFonts['A']
+		}
+		height, width := f.height, f.width
+		if height == 0 {
+			height = base[0]
+		}
+		if width == 0 {
+			width = base[1] * height / base[0]
+		}
+		font, xScale, yScale = nearestFont(width, height)
+	}
+
+	el := models.Element{
+		Type: "text",
+		Properties: map[string]interface{}{
+			"text":     f.data,
+			"font":     font,
+			"rotation": f.rotation,
+			"xScale":   xScale,
+			"yScale":   yScale,
+		},
+	}
+	w, h := textBox(el)
+	el.X, el.Y = anchor(f.x, f.y, w, h, f.rotation)
+	s.data.Elements = append(s.data.Elements, el)
+}
+
+// addBarcode 處理 ^BCo,h,f,g,e,m (Code 128)
+func (s *zplState) addBarcode(cmd zplCommand, f zplField) {
+	p := f.params
+	rotation := 0
+	if len(p) > 0 && p[0] != "" {
+		r, ok := zplRotations[strings.ToUpper(p[0])[0]]
+		if !ok {
+			s.issue(cmd, "不支援的條碼方向 "+p[0]+", 以 N 代替")
+		}
+		rotation = r
+	}
+	readable := 2
+	if !zplFlag(p, 2, true) {
+		readable = 0
+	} else if zplFlag(p, 3, false) {
+		s.issue(cmd, "條碼上方的人眼可讀文字不支援, 改為顯示在下方")
+	}
+	if zplFlag(p, 4, false) {
+		s.issue(cmd, "^BC 的 UCC 檢查碼不支援, 已略過")
+	}
+
+	codeType, code := "128", f.data
+	switch mode := strings.ToUpper(zplString(p, 5, "N")); mode {
+	case "D":
+		codeType = "EAN128"
+	case "N", "A":
+		for _, start := range []string{">9", ">:", ">;"} {
+			code = strings.TrimPrefix(code, start)
+		}
+		if strings.Contains(code, ">") {
+			s.issue(cmd, "Code 128 子集切換碼 (>) 不支援, 資料原樣輸出")
+		}
+	default:
+		s.issue(cmd, "^BC 模式 "+mode+" 不支援, 以自動模式代替")
+	}
+
+	narrow := max(s.moduleW, 1)
+	el := models.Element{
+		Type: "barcode",
+		Properties: map[string]interface{}{
+			"code":     code,
+			"type":     codeType,
+			"height":   zplInt(p, 1, s.barH),
+			"readable": readable,
+			"rotation": rotation,
+			"narrow":   narrow,
+			"wide":     max(int(math.Round(float64(narrow)*s.ratio)), narrow),
+		},
+	}
+	w, h := barcodeBox(el)
+	el.X, el.Y = anchor(f.x, f.y, w, h, rotation)
+	s.data.Elements = append(s.data.Elements, el)
+}
+
+// addQRCode 處理 ^BQa,b,c,d 與 ^FD 的 "ECC 模式,資料" 格式
+func (s *zplState) addQRCode(cmd zplCommand, f zplField) {
+	p := f.params
+	if o := zplString(p, 0, "N"); strings.ToUpper(o) != "N" {
+		s.issue(cmd, "^BQ 只支援方向 N")
+	}
+	if model := zplString(p, 1, "2"); model != "2" {
+		s.issue(cmd, "只支援 QR Code Model 2")
+	}
+
+	ecc, mode, data := strings.ToUpper(zplString(p, 3, "Q")), "A", f.data
+	if i := strings.Index(data, ","); i >= 0 && i <= 2 {
+		prefix := strings.ToUpper(data[:i])
+		data = data[i+1:]
+		if len(prefix) > 0 && strings.ContainsRune("HQML", rune(prefix[0])) {
+			ecc = prefix[:1]
+		}
+		if len(prefix) > 1 && prefix[1] == 'M' {
+			mode = "M"
+		}
+	} else {
+		s.issue(cmd, "^FD 缺少 QR Code 的 \"ECC 模式,\" 前置字串, 以整段內容作為資料")
+	}
+	if !strings.ContainsAny(ecc, "HQML") || len(ecc) != 1 {
+		ecc = "Q"
+	}
+
+	s.data.Elements = append(s.data.Elements, models.Element{
+		Type: "qrcode",
+		X:    f.x,
+		Y:    f.y,
+		Properties: map[string]interface{}{
+			"data":     data,
+			"eccLevel": ecc,
+			"cellSize": max(zplInt(p, 2, 2), 1),
+			"mode":     mode,
+			"rotation": 0,
+		},
+	})
+}
+
+// graphicBox 處理 ^GBw,h,t,c,r: 實心為 BAR (白色為 ERASE), 外框為 BOX
+func (s *zplState) graphicBox(cmd zplCommand, p []string) {
+	t := max(zplInt(p, 2, 1), 1)
+	w := max(zplInt(p, 0, t), t)
+	h := max(zplInt(p, 1, t), t)
+	white := strings.ToUpper(zplString(p, 3, "B")) == "W"
+	rounding := zplInt(p, 4, 0)
+	x, y := s.field.x, s.field.y
+
+	filled := t >= w || t >= h
+	switch {
+	case filled && white:
+		s.data.Elements = append(s.data.Elements, models.Element{
+			Type: "erase", X: x, Y: y,
+			Properties: map[string]interface{}{"width": w, "height": h},
+		})
+	case white:
+		s.issue(cmd, "白色外框不支援, 已略過")
+	case filled && rounding == 0:
+		s.data.Elements = append(s.data.Elements, models.Element{
+			Type: "bar", X: x, Y: y,
+			Properties: map[string]interface{}{"width": w, "height": h},
+		})
+	default:
+		el := models.Element{
+			Type: "box", X: x, Y: y,
+			Properties: map[string]interface{}{"endX": x + w, "endY": y + h, "thickness": t},
+		}
+		if rounding > 0 {
+			el.Properties["radius"] = min(w, h) * min(rounding, 8) / 16
+		}
+		s.data.Elements = append(s.data.Elements, el)
+	}
+}
+
+// issue 記錄無法轉換的內容
+func (s *zplState) issue(cmd zplCommand, message string) {
+	s.issues = append(s.issues, models.ConversionIssue{
+		Line:    cmd.Line,
+		Command: string(cmd.Prefix) + cmd.Code,
+		Message: message,
+	})
+}
+
+// tokenizeZPL 將 ZPL 分割為命令; ^FD 的資料到下一個 ^ 為止 (可包含 ~ 與逗號)
+func tokenizeZPL(zpl string) []zplCommand {
+	var commands []zplCommand
+	line := 1
+	for i := 0; i < len(zpl); {
+		ch := zpl[i]
+		if ch != '^' && ch != '~' {
+			if ch == '\n' {
+				line++
+			}
+			i++
+			continue
+		}
+		cmd := zplCommand{Prefix: ch, Line: line}
+		end := min(i+3, len(zpl))
+		cmd.Code = strings.ToUpper(zpl[i+1 : end])
+		j := end
+		for j < len(zpl) && zpl[j] != '^' && (zpl[j] != '~' || cmd.Code == "FD" || cmd.Code == "FX") {
+			j++
+		}
+		params := zpl[end:j]
+		line += strings.Count(params, "\n")
+		if cmd.Code != "FD" {
+			params = strings.TrimSpace(params)
+		} else {
+			params = strings.TrimRight(params, "\r\n")
+		}
+		cmd.Params = params
+		if cmd.Code != "" {
+			commands = append(commands, cmd)
+		}
+		i = j
+	}
+	return commands
+}
+
+// splitZPLParams 以逗號分割參數
+func splitZPLParams(params string) []string {
+	if params == "" {
+		return nil
+	}
+	parts := strings.Split(params, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// zplInt 取得第 i 個整數參數, 未指定或格式錯誤時回傳預設值
+func zplInt(p []string, i, def int) int {
+	if i >= len(p) || p[i] == "" {
+		return def
+	}
+	n, err := strconv.Atoi(p[i])
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// zplString 取得第 i 個參數, 未指定時回傳預設值
+func zplString(p []string, i int, def string) string {
+	if i >= len(p) || p[i] == "" {
+		return def
+	}
+	return p[i]
+}
+
+// zplFlag 取得 Y/N 參數
+func zplFlag(p []string, i int, def bool) bool {
+	switch strings.ToUpper(zplString(p, i, "")) {
+	case "Y":
+		return true
+	case "N":
+		return false
+	}
+	return def
+}
+
+// nearestFont 選擇字高最接近的 TSPL 點陣字型 "1"-"5" 與整數倍率
+func nearestFont(width, height int) (string, int, int) {
+	best, bestScale, bestDiff := "1", 1, math.MaxInt
+	for _, font := range []string{"1", "2", "3", "4", "5"} {
+		_, h := tsplFontCell(font)
+		scale := max(int(math.Round(float64(height)/float64(h))), 1)
+		if diff := abs(h*scale - height); diff < bestDiff {
+			best, bestScale, bestDiff = font, scale, diff
+		}
+	}
+	w, _ := tsplFontCell(best)
+	return best, max(int(math.Round(float64(width)/float64(w))), 1), bestScale
+}
+
+// dotsToMM 將點數換算為毫米 (取兩位小數), 並確保換算回點數時不小於原值
+func dotsToMM(dots int) float64 {
+	mm := math.Round(float64(dots)*25.4/parser.DPI*100) / 100
+	for parser.ToDots(mm, "mm") < dots {
+		mm = math.Round((mm+0.01)*100) / 100
+	}
+	return mm
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
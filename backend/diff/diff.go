@@ -0,0 +1,269 @@
+package diff
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+
+	"tspl-simulator/models"
+	"tspl-simulator/renderer"
+)
+
+// maxValueLength 差異中顯示的字串屬性最大長度 (例如 image 的 bitmap)
+const maxValueLength = 64
+
+// Elements 比較兩份標籤的元素, 依序配對:
+//  1. 類型、位置與屬性完全相同
+//  2. 類型與內容 (text/code/data/file) 相同, 取位置最近的: 只有位置不同為 moved, 其他屬性不同為 changed
+//  3. 類型與位置相同但屬性不同: changed
+//
+// 未配對的元素為 added / removed; 回傳只列出有差異的元素, 依 after 的順序, 移除的元素在最後
+func Elements(before, after []models.Element) ([]models.ElementDiff, models.DiffSummary) {
+	pairA, pairB := unpaired(len(before)), unpaired(len(after))
+	pair := func(i, j int) {
+		pairA[i], pairB[j] = j, i
+	}
+
+	for j, b := range after {
+		for i, a := range before {
+			if pairA[i] < 0 && a.Type == b.Type && a.X == b.X && a.Y == b.Y && reflect.DeepEqual(a.Properties, b.Properties) {
+				pair(i, j)
+				break
+			}
+		}
+	}
+	for j, b := range after {
+		key, ok := contentKey(b)
+		if pairB[j] >= 0 || !ok {
+			continue
+		}
+		best, bestDist := -1, 0
+		for i, a := range before {
+			if k, ok := contentKey(a); pairA[i] >= 0 || a.Type != b.Type || !ok || k != key {
+				continue
+			}
+			if d := abs(a.X-b.X) + abs(a.Y-b.Y); best < 0 || d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		if best >= 0 {
+			pair(best, j)
+		}
+	}
+	for j, b := range after {
+		if pairB[j] >= 0 {
+			continue
+		}
+		for i, a := range before {
+			if pairA[i] < 0 && a.Type == b.Type && a.X == b.X && a.Y == b.Y {
+				pair(i, j)
+				break
+			}
+		}
+	}
+
+	var diffs []models.ElementDiff
+	var summary models.DiffSummary
+	for j, b := range after {
+		i := pairB[j]
+		if i < 0 {
+			summary.Added++
+			diffs = append(diffs, models.ElementDiff{Status: "added", Type: b.Type, Key: displayKey(b), AfterIndex: j + 1, To: position(b)})
+			continue
+		}
+		a := before[i]
+		changes := propertyChanges(a.Properties, b.Properties)
+		moved := a.X != b.X || a.Y != b.Y
+		if !moved && len(changes) == 0 {
+			summary.Unchanged++
+			continue
+		}
+		d := models.ElementDiff{Status: "moved", Type: b.Type, Key: displayKey(b), BeforeIndex: i + 1, AfterIndex: j + 1, From: position(a), To: position(b), Changes: changes}
+		if len(changes) > 0 {
+			d.Status = "changed"
+			summary.Changed++
+		} else {
+			summary.Moved++
+		}
+		diffs = append(diffs, d)
+	}
+	for i, a := range before {
+		if pairA[i] < 0 {
+			summary.Removed++
+			diffs = append(diffs, models.ElementDiff{Status: "removed", Type: a.Type, Key: displayKey(a), BeforeIndex: i + 1, From: position(a)})
+		}
+	}
+	return diffs, summary
+}
+
+// Settings 比較標籤尺寸、間距、方向、參考點、解析度、紙張與列印設定
+func Settings(before, after *models.RenderData) []models.PropertyChange {
+	fields := []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"width", before.Width, after.Width},
+		{"height", before.Height, after.Height},
+		{"labelSize", before.LabelSize, after.LabelSize},
+		{"gap", before.Gap, after.Gap},
+		{"direction", before.Direction, after.Direction},
+		{"reference", before.Reference, after.Reference},
+		{"dpi", before.DPI, after.DPI},
+		{"media", before.Media, after.Media},
+		{"print", before.Print, after.Print},
+	}
+	var changes []models.PropertyChange
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.before, f.after) {
+			changes = append(changes, models.PropertyChange{Property: f.name, Before: f.before, After: f.after})
+		}
+	}
+	return changes
+}
+
+// 差異影像的調色盤索引
+const (
+	pixelBlank = iota
+	pixelSame
+	pixelRemoved
+	pixelAdded
+)
+
+// diffPalette 白紙、相同的點 (灰)、只有 before 的點 (紅)、只有 after 的點 (綠)
+var diffPalette = color.Palette{
+	color.White,
+	color.Gray{Y: 0xC0},
+	color.RGBA{R: 0xE0, G: 0x20, B: 0x20, A: 0xFF},
+	color.RGBA{R: 0x10, G: 0xA0, B: 0x30, A: 0xFF},
+}
+
+// Pixels 逐點比較兩張點陣, 尺寸不同時以較大的範圍比較 (超出的部分視為白點), 並回傳標示差異的影像
+func Pixels(before, after *renderer.Canvas) (models.PixelDiff, *image.Paletted) {
+	width, height := max(before.Width, after.Width), max(before.Height, after.Height)
+	result := models.PixelDiff{Width: width, Height: height}
+	img := image.NewPaletted(image.Rect(0, 0, width, height), diffPalette)
+	bounds := image.Rectangle{}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			a, b := before.Get(x, y), after.Get(x, y)
+			var idx uint8
+			switch {
+			case a && b:
+				idx = pixelSame
+				result.Unchanged++
+			case a:
+				idx = pixelRemoved
+				result.Removed++
+			case b:
+				idx = pixelAdded
+				result.Added++
+			default:
+				continue
+			}
+			img.Pix[y*img.Stride+x] = idx
+			if idx != pixelSame {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if !bounds.Empty() {
+		result.Bounds = &models.DiffBound{X: bounds.Min.X, Y: bounds.Min.Y, Width: bounds.Dx(), Height: bounds.Dy()}
+	}
+	return result, img
+}
+
+// propertyChanges 列出不同的屬性 (依名稱排序)
+func propertyChanges(before, after map[string]interface{}) []models.PropertyChange {
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []models.PropertyChange
+	for _, name := range sorted {
+		a, aok := before[name]
+		b, bok := after[name]
+		if aok == bok && reflect.DeepEqual(a, b) {
+			continue
+		}
+		changes = append(changes, models.PropertyChange{Property: name, Before: displayValue(a), After: displayValue(b)})
+	}
+	return changes
+}
+
+// contentKey 元素的內容屬性, 用於辨識移動或修改過的同一個元素; 圖形元素沒有
+func contentKey(el models.Element) (string, bool) {
+	var name string
+	switch el.Type {
+	case "text":
+		name = "text"
+	case "barcode":
+		name = "code"
+	case "qrcode":
+		name = "data"
+	case "image":
+		name = "file"
+	default:
+		return "", false
+	}
+	v, ok := el.Properties[name].(string)
+	return v, ok
+}
+
+// displayKey 顯示用的元素內容
+func displayKey(el models.Element) string {
+	key, _ := contentKey(el)
+	return shorten(key)
+}
+
+// displayValue 顯示用的屬性值, 過長的字串截斷
+func displayValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		return shorten(s)
+	}
+	return v
+}
+
+// shorten 截斷過長的字串 (不切斷 UTF-8 字元) 並註明原長度
+func shorten(s string) string {
+	if len(s) <= maxValueLength {
+		return s
+	}
+	n := maxValueLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return fmt.Sprintf("%s… (%d 位元組)", s[:n], len(s))
+}
+
+// position 元素的位置
+func position(el models.Element) *models.Reference {
+	return &models.Reference{X: el.X, Y: el.Y}
+}
+
+// unpaired 建立尚未配對的索引表
+func unpaired(n int) []int {
+	p := make([]int, n)
+	for i := range p {
+		p[i] = -1
+	}
+	return p
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,102 @@
+package formatter
+
+import (
+	"strings"
+
+	"tspl-simulator/parser"
+)
+
+// Kind 行的種類
+type Kind int
+
+const (
+	Blank   Kind = iota // 空行
+	Comment             // ; 註解
+	Label               // :標籤 (GOTO/GOSUB 目標)
+	Command             // 命令
+)
+
+// Node 原始碼中的一行 (DOWNLOAD 連同其資料視為一行)
+type Node struct {
+	Kind     Kind
+	Line     int              // 起始行號 (從 1 開始)
+	Raw      string           // 原始內容 (不含換行; DOWNLOAD 為資料之前的標頭, 含逗號)
+	Name     string           // 命令名稱 (原始大小寫)
+	Args     []string         // 命令參數 (原始內容, 含前後空白)
+	Text     string           // 註解或標籤內容 (不含 ; 或 :)
+	Download *parser.Download // DOWNLOAD 的檔案內容, 原樣保留
+	Trailer  string           // DOWNLOAD 資料之後到行尾的內容
+}
+
+// File TSPL 原始碼的語法樹, Source 可還原出與輸入完全相同的內容
+type File struct {
+	Nodes []*Node
+}
+
+// Parse 將 TSPL 原始碼解析為語法樹; 不驗證命令內容, 任何輸入都能解析
+func Parse(tsplCode string) *File {
+	f := &File{}
+	for _, src := range parser.SplitSource(tsplCode) {
+		node := &Node{Line: src.Line, Raw: src.Text, Download: src.Download, Trailer: src.Trailer}
+		text := strings.TrimSpace(node.Raw)
+		switch {
+		case text == "":
+			node.Kind = Blank
+		case strings.HasPrefix(text, ";"):
+			node.Kind = Comment
+			node.Text = text[1:]
+		case strings.HasPrefix(text, ":"):
+			node.Kind = Label
+			node.Text = text[1:]
+		default:
+			node.Kind = Command
+			if node.Download != nil {
+				text = strings.TrimSuffix(text, ",")
+			}
+			node.Name, node.Args = splitCommand(text)
+		}
+		f.Nodes = append(f.Nodes, node)
+	}
+	return f
+}
+
+// Source 還原原始碼
+func (f *File) Source() string {
+	var b strings.Builder
+	for i, node := range f.Nodes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(node.Raw)
+		if node.Download != nil {
+			b.Write(node.Download.Data)
+			b.WriteString(node.Trailer)
+		}
+	}
+	return b.String()
+}
+
+// splitCommand 分出命令名稱與以引號外逗號分隔的參數
+func splitCommand(text string) (string, []string) {
+	i := strings.IndexAny(text, " \t")
+	if i < 0 {
+		return text, nil
+	}
+	rest := strings.TrimSpace(text[i:])
+	if rest == "" {
+		return text[:i], nil
+	}
+
+	var args []string
+	start, inQuote := 0, false
+	for j := 0; j < len(rest); j++ {
+		switch {
+		case rest[j] == '"':
+			inQuote = !inQuote
+		case rest[j] == ',' && !inQuote:
+			args = append(args, rest[start:j])
+			start = j + 1
+		}
+	}
+	return text[:i], append(args, rest[start:])
+}
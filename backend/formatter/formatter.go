@@ -0,0 +1,155 @@
+package formatter
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"tspl-simulator/parser"
+)
+
+// unitCommands 參數帶有 mm/inch 單位的命令
+var unitCommands = map[string]bool{
+	"SIZE": true, "GAP": true, "BLINE": true, "LIMITFEED": true,
+}
+
+// listCommands 參數以 ", " 分隔的命令 (與範例檔一致)
+var listCommands = map[string]bool{
+	"SIZE": true, "GAP": true, "BLINE": true,
+}
+
+var unitPattern = regexp.MustCompile(`(?i)^([\d.]+)\s*(mm|inch)?$`)
+
+// Format 將 TSPL 程式碼整理為一致的格式:
+//   - 命令名稱轉為大寫, 命令與參數之間以一個空白分隔
+//   - 參數之間的逗號前後不留空白 (引號內的內容不變); SIZE/GAP/BLINE 以 ", " 分隔
+//   - 長度寫為 "數值 單位", 例如 "100.50MM" 寫為 "100.5 mm"; SET 的關鍵字轉為大寫
+//   - 註解寫為 "; 內容", 標籤寫為 ":名稱"
+//   - 移除行首縮排與行尾空白, 換行統一為 LF, 檔尾的空行移除並保留一個換行
+//
+// 空行保留以維持行號不變; DOWNLOAD 的檔案內容原樣保留
+func Format(tsplCode string) string {
+	return Parse(tsplCode).Format()
+}
+
+// FormatVerified 整理格式並以 Verify 確認結果的語意不變
+func FormatVerified(tsplCode string) (string, error) {
+	formatted := Format(tsplCode)
+	if err := Verify(tsplCode, formatted); err != nil {
+		return "", err
+	}
+	return formatted, nil
+}
+
+// Verify 重新解析整理前後的程式碼, 確認 RenderData 完全相同; 原始碼本身無法解析時不比較
+func Verify(original, formatted string) error {
+	before, err := parser.ParseTSPLWithStore(original, parser.NewScratchStore())
+	if err != nil {
+		return nil
+	}
+	after, err := parser.ParseTSPLWithStore(formatted, parser.NewScratchStore())
+	if err != nil {
+		return fmt.Errorf("整理後的程式碼無法解析: %v", err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		return fmt.Errorf("整理後的程式碼解析結果與原始碼不同")
+	}
+	return nil
+}
+
+// Format 輸出整理後的原始碼
+func (f *File) Format() string {
+	lines := make([]string, 0, len(f.Nodes))
+	for _, node := range f.Nodes {
+		lines = append(lines, node.Format())
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Format 輸出整理後的單行內容 (DOWNLOAD 含檔案內容)
+func (n *Node) Format() string {
+	switch n.Kind {
+	case Comment:
+		return formatComment(n.Text)
+	case Label:
+		return ":" + strings.TrimSpace(n.Text)
+	case Command:
+		line := formatCommand(n.Name, n.Args)
+		if n.Download != nil {
+			line += "," + string(n.Download.Data) + strings.TrimRight(n.Trailer, " \t\r")
+		}
+		return line
+	}
+	return ""
+}
+
+// formatCommand 整理命令名稱與參數
+func formatCommand(name string, args []string) string {
+	command := strings.ToUpper(name)
+	if len(args) == 0 {
+		return command
+	}
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = formatArg(command, arg)
+	}
+	sep := ","
+	if listCommands[command] {
+		sep = ", "
+	}
+	return command + " " + strings.Join(formatted, sep)
+}
+
+// formatArg 去除參數前後空白, 引號外的連續空白合併為一個, 並整理單位與關鍵字
+func formatArg(command, arg string) string {
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(arg, `"`) {
+		return arg
+	}
+	arg = strings.Join(strings.Fields(arg), " ")
+	switch {
+	case unitCommands[command]:
+		return formatLength(arg)
+	case command == "SET":
+		return strings.ToUpper(arg)
+	}
+	return arg
+}
+
+// formatLength 將 "100.50MM" 整理為 "100.5 mm"; 沒有單位時只整理數值
+func formatLength(arg string) string {
+	m := unitPattern.FindStringSubmatch(arg)
+	if m == nil {
+		return arg
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return arg
+	}
+	number := strconv.FormatFloat(value, 'f', -1, 64)
+	if m[2] == "" {
+		return number
+	}
+	return number + " " + strings.ToLower(m[2])
+}
+
+// formatComment 註解以 "; " 開頭; 以符號開頭的分隔線 (如 ";-----") 不加空白
+func formatComment(text string) string {
+	text = strings.TrimRight(text, " \t\r")
+	trimmed := strings.TrimLeft(text, " \t")
+	if trimmed == "" {
+		return ";"
+	}
+	if trimmed == text && strings.ContainsRune(";-=*#", rune(text[0])) {
+		return ";" + text
+	}
+	return "; " + trimmed
+}
@@ -0,0 +1,90 @@
+package formatter
+
+import (
+	"reflect"
+	"testing"
+
+	"tspl-simulator/parser"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "spacing and case",
+			input: "size 50.0 mm , 30 mm\r\n  gap  2 mm , 0\r\ncls\r\nTEXT 10,20,\"3\",0,1,1,\"Hi\"   \r\nprint 1\r\n\r\n\r\n",
+			want:  "SIZE 50 mm, 30 mm\nGAP 2 mm, 0\nCLS\nTEXT 10,20,\"3\",0,1,1,\"Hi\"\nPRINT 1\n",
+		},
+		{
+			name:  "comments keep line numbers",
+			input: "SIZE 50 mm, 30 mm\n;header\n;-----\n  ;   indented\n\nCLS\nBAR 10,10,100,4 \nPRINT 1\n",
+			want:  "SIZE 50 mm, 30 mm\n; header\n;-----\n; indented\n\nCLS\nBAR 10,10,100,4\nPRINT 1\n",
+		},
+		{
+			name:  "quoted commas",
+			input: "SIZE 50 mm, 30 mm\nCLS\n  TEXT 10,10,\"3\",0,1,1,\"a, b ,c\"\nQRCODE 10,60,L,4,A,0,\"x,y,  z\"  \nbar 10 , 10,100,4\nPRINT 1\n",
+			want:  "SIZE 50 mm, 30 mm\nCLS\nTEXT 10,10,\"3\",0,1,1,\"a, b ,c\"\nQRCODE 10,60,L,4,A,0,\"x,y,  z\"\nBAR 10,10,100,4\nPRINT 1\n",
+		},
+		{
+			name:  "box radius",
+			input: "SIZE 50 mm, 30 mm\nCLS\nbox 10, 10 ,200 , 100,3 , 12\nBOX 5,5,300,200,2\nPRINT 1\n",
+			want:  "SIZE 50 mm, 30 mm\nCLS\nBOX 10,10,200,100,3,12\nBOX 5,5,300,200,2\nPRINT 1\n",
+		},
+		{
+			name:  "barcode and set keywords",
+			input: "SIZE 2 inch,1 inch\nset tear on\ndirection 1\nCLS\n\tBARCODE 10,10,\"128\",50,1,0,2,2,\"A, B\"\nPRINT 1,2\n",
+			want:  "SIZE 2 inch, 1 inch\nSET TEAR ON\nDIRECTION 1\nCLS\nBARCODE 10,10,\"128\",50,1,0,2,2,\"A, B\"\nPRINT 1,2\n",
+		},
+		{
+			name:  "download data kept",
+			input: "download \"A.TXT\",5,a,b\nc\nSIZE 50 mm, 30 mm\nCLS\nPRINT 1\n",
+			want:  "DOWNLOAD \"A.TXT\",5,a,b\nc\nSIZE 50 mm, 30 mm\nCLS\nPRINT 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.input).Source(); got != tt.input {
+				t.Fatalf("Source() = %q, want 原始輸入 %q", got, tt.input)
+			}
+
+			got := Format(tt.input)
+			if got != tt.want {
+				t.Errorf("Format = %q, want %q", got, tt.want)
+			}
+			if again := Format(got); again != got {
+				t.Errorf("Format 不穩定: 第二次 = %q", again)
+			}
+
+			before, err := parser.ParseTSPLWithStore(tt.input, parser.NewScratchStore())
+			if err != nil {
+				t.Fatalf("原始碼解析失敗: %v", err)
+			}
+			after, err := parser.ParseTSPLWithStore(got, parser.NewScratchStore())
+			if err != nil {
+				t.Fatalf("整理後的程式碼解析失敗: %v", err)
+			}
+			if !reflect.DeepEqual(before, after) {
+				t.Errorf("RenderData 不同:\n before %+v\n after  %+v", before, after)
+			}
+			if _, err := FormatVerified(tt.input); err != nil {
+				t.Errorf("FormatVerified: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsChanges(t *testing.T) {
+	original := "SIZE 50 mm, 30 mm\nCLS\nBOX 10,10,200,100,3,12\nPRINT 1\n"
+	if err := Verify(original, "SIZE 50 mm, 30 mm\nCLS\nBOX 10,10,200,100,3\nPRINT 1\n"); err == nil {
+		t.Error("BOX 圓角不同時 Verify 應回報錯誤")
+	}
+	if err := Verify(original, "SIZE 50 mm, 30 mm\nCLS\nBOX 10,10,200,100,3,12\nPRINT x\n"); err == nil {
+		t.Error("整理後無法解析時 Verify 應回報錯誤")
+	}
+	if err := Verify("SIZE x mm\n", "SIZE x mm\n"); err != nil {
+		t.Errorf("原始碼無法解析時不比較: %v", err)
+	}
+}
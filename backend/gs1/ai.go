@@ -0,0 +1,163 @@
+package gs1
+
+import "strings"
+
+// AI GS1 應用識別碼 (Application Identifier) 的定義
+type AI struct {
+	Code   string
+	Title  string
+	Format string // 例如 "N14", "X..20", "N3+N..15"; N 為數字, X 為 GS1 字元集 82, ".." 為可變長度上限
+	Check  bool   // 第一個欄位的最後一位為 Mod 10 檢查碼
+	Date   bool   // 第一個欄位為 YYMMDD 日期
+}
+
+// ais 常用的 GS1 應用識別碼 (GS1 General Specifications)
+// 310n-369n、390n-393n 等末位為小數位數的 AI 由 lookupDecimal 處理
+var ais = map[string]AI{
+	"00":   {Code: "00", Title: "SSCC", Format: "N18", Check: true},
+	"01":   {Code: "01", Title: "GTIN", Format: "N14", Check: true},
+	"02":   {Code: "02", Title: "CONTENT", Format: "N14", Check: true},
+	"10":   {Code: "10", Title: "BATCH/LOT", Format: "X..20"},
+	"11":   {Code: "11", Title: "PROD DATE", Format: "N6", Date: true},
+	"12":   {Code: "12", Title: "DUE DATE", Format: "N6", Date: true},
+	"13":   {Code: "13", Title: "PACK DATE", Format: "N6", Date: true},
+	"15":   {Code: "15", Title: "BEST BEFORE", Format: "N6", Date: true},
+	"16":   {Code: "16", Title: "SELL BY", Format: "N6", Date: true},
+	"17":   {Code: "17", Title: "USE BY/EXPIRY", Format: "N6", Date: true},
+	"20":   {Code: "20", Title: "VARIANT", Format: "N2"},
+	"21":   {Code: "21", Title: "SERIAL", Format: "X..20"},
+	"22":   {Code: "22", Title: "CPV", Format: "X..20"},
+	"235":  {Code: "235", Title: "TPX", Format: "X..28"},
+	"240":  {Code: "240", Title: "ADDITIONAL ID", Format: "X..30"},
+	"241":  {Code: "241", Title: "CUST. PART No.", Format: "X..30"},
+	"242":  {Code: "242", Title: "MTO VARIANT", Format: "N..6"},
+	"243":  {Code: "243", Title: "PCN", Format: "X..20"},
+	"250":  {Code: "250", Title: "SECONDARY SERIAL", Format: "X..30"},
+	"251":  {Code: "251", Title: "REF. TO SOURCE", Format: "X..30"},
+	"253":  {Code: "253", Title: "GDTI", Format: "N13+X..17", Check: true},
+	"254":  {Code: "254", Title: "GLN EXTENSION COMPONENT", Format: "X..20"},
+	"255":  {Code: "255", Title: "GCN", Format: "N13+N..12", Check: true},
+	"30":   {Code: "30", Title: "VAR. COUNT", Format: "N..8"},
+	"37":   {Code: "37", Title: "COUNT", Format: "N..8"},
+	"400":  {Code: "400", Title: "ORDER NUMBER", Format: "X..30"},
+	"401":  {Code: "401", Title: "GINC", Format: "X..30"},
+	"402":  {Code: "402", Title: "GSIN", Format: "N17", Check: true},
+	"403":  {Code: "403", Title: "ROUTE", Format: "X..30"},
+	"410":  {Code: "410", Title: "SHIP TO LOC", Format: "N13", Check: true},
+	"411":  {Code: "411", Title: "BILL TO", Format: "N13", Check: true},
+	"412":  {Code: "412", Title: "PURCHASE FROM", Format: "N13", Check: true},
+	"413":  {Code: "413", Title: "SHIP FOR LOC", Format: "N13", Check: true},
+	"414":  {Code: "414", Title: "LOC No.", Format: "N13", Check: true},
+	"415":  {Code: "415", Title: "PAY TO", Format: "N13", Check: true},
+	"416":  {Code: "416", Title: "PROD/SERV LOC", Format: "N13", Check: true},
+	"417":  {Code: "417", Title: "PARTY", Format: "N13", Check: true},
+	"420":  {Code: "420", Title: "SHIP TO POST", Format: "X..20"},
+	"421":  {Code: "421", Title: "SHIP TO POST", Format: "N3+X..9"},
+	"422":  {Code: "422", Title: "ORIGIN", Format: "N3"},
+	"423":  {Code: "423", Title: "COUNTRY - INITIAL PROCESS", Format: "N3+N..12"},
+	"424":  {Code: "424", Title: "COUNTRY - PROCESS", Format: "N3"},
+	"425":  {Code: "425", Title: "COUNTRY - DISASSEMBLY", Format: "N3+N..12"},
+	"426":  {Code: "426", Title: "COUNTRY - FULL PROCESS", Format: "N3"},
+	"7001": {Code: "7001", Title: "NSN", Format: "N13"},
+	"7002": {Code: "7002", Title: "MEAT CUT", Format: "X..30"},
+	"7003": {Code: "7003", Title: "EXPIRY TIME", Format: "N10", Date: true},
+	"7004": {Code: "7004", Title: "ACTIVE POTENCY", Format: "N..4"},
+	"8001": {Code: "8001", Title: "DIMENSIONS", Format: "N14"},
+	"8002": {Code: "8002", Title: "CMT No.", Format: "X..20"},
+	"8003": {Code: "8003", Title: "GRAI", Format: "N14+X..16", Check: true},
+	"8004": {Code: "8004", Title: "GIAI", Format: "X..30"},
+	"8005": {Code: "8005", Title: "PRICE PER UNIT", Format: "N6"},
+	"8006": {Code: "8006", Title: "ITIP", Format: "N14+N2+N2", Check: true},
+	"8007": {Code: "8007", Title: "IBAN", Format: "X..34"},
+	"8008": {Code: "8008", Title: "PROD TIME", Format: "N8+N..4", Date: true},
+	"8017": {Code: "8017", Title: "GSRN - PROVIDER", Format: "N18", Check: true},
+	"8018": {Code: "8018", Title: "GSRN - RECIPIENT", Format: "N18", Check: true},
+	"8020": {Code: "8020", Title: "REF No.", Format: "X..25"},
+	"8200": {Code: "8200", Title: "PRODUCT URL", Format: "X..70"},
+	"90":   {Code: "90", Title: "INTERNAL", Format: "X..30"},
+}
+
+// decimalAIs 末位代表小數位數 (0-5 或 0-9) 的 4 位 AI 前綴
+var decimalAIs = map[string]AI{
+	"310": {Title: "NET WEIGHT (kg)", Format: "N6"},
+	"311": {Title: "LENGTH (m)", Format: "N6"},
+	"312": {Title: "WIDTH (m)", Format: "N6"},
+	"313": {Title: "HEIGHT (m)", Format: "N6"},
+	"314": {Title: "AREA (m2)", Format: "N6"},
+	"315": {Title: "NET VOLUME (l)", Format: "N6"},
+	"316": {Title: "NET VOLUME (m3)", Format: "N6"},
+	"320": {Title: "NET WEIGHT (lb)", Format: "N6"},
+	"330": {Title: "GROSS WEIGHT (kg)", Format: "N6"},
+	"331": {Title: "LENGTH (m), log", Format: "N6"},
+	"332": {Title: "WIDTH (m), log", Format: "N6"},
+	"333": {Title: "HEIGHT (m), log", Format: "N6"},
+	"334": {Title: "AREA (m2), log", Format: "N6"},
+	"335": {Title: "VOLUME (l), log", Format: "N6"},
+	"336": {Title: "VOLUME (m3), log", Format: "N6"},
+	"340": {Title: "GROSS WEIGHT (lb)", Format: "N6"},
+	"390": {Title: "AMOUNT", Format: "N..15"},
+	"391": {Title: "AMOUNT", Format: "N3+N..15"},
+	"392": {Title: "PRICE", Format: "N..15"},
+	"393": {Title: "PRICE", Format: "N3+N..15"},
+}
+
+// predefinedLength GS1 規範中長度固定、不需要 FNC1 分隔的 AI 前兩碼
+var predefinedLength = map[string]bool{
+	"00": true, "01": true, "02": true, "03": true, "04": true,
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"20": true, "31": true, "32": true, "33": true, "34": true, "35": true, "36": true, "41": true,
+}
+
+// Lookup 查詢 AI 定義
+func Lookup(code string) (AI, bool) {
+	if ai, ok := ais[code]; ok {
+		return ai, true
+	}
+	if len(code) == 4 {
+		if ai, ok := decimalAIs[code[:3]]; ok {
+			if code[:2] != "39" && code[3] > '5' {
+				return AI{}, false
+			}
+			ai.Code = code
+			return ai, true
+		}
+	}
+	if len(code) == 2 && code[0] == '9' && code[1] >= '1' {
+		return AI{Code: code, Title: "INTERNAL", Format: "X..90"}, true
+	}
+	return AI{}, false
+}
+
+// NeedsSeparator 判斷 AI 之後若還有其他 AI 時是否需要 FNC1 分隔
+func NeedsSeparator(code string) bool {
+	return len(code) < 2 || !predefinedLength[code[:2]]
+}
+
+// component 格式中的單一欄位
+type component struct {
+	numeric bool
+	min     int
+	max     int
+}
+
+// parseFormat 解析 "N3+X..9" 形式的格式
+func parseFormat(format string) []component {
+	var result []component
+	for _, part := range strings.Split(format, "+") {
+		c := component{numeric: part[0] == 'N'}
+		spec := part[1:]
+		variable := strings.HasPrefix(spec, "..")
+		spec = strings.TrimPrefix(spec, "..")
+		n := 0
+		for _, ch := range spec {
+			n = n*10 + int(ch-'0')
+		}
+		c.max = n
+		c.min = n
+		if variable {
+			c.min = 1
+		}
+		result = append(result, c)
+	}
+	return result
+}
@@ -0,0 +1,256 @@
+package gs1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxDataLength GS1-128 單一符號最多可容納的資料字元數 (不含 FNC1)
+const MaxDataLength = 48
+
+// charset82 GS1 AI 可編碼字元集 82
+const charset82 = "!\"%&'()*+,-./0123456789:;<=>?ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+
+// Element 元素字串中的單一 AI 與資料
+type Element struct {
+	AI     string
+	Value  string
+	Offset int // AI 左括號在元素字串中的位置 (從 0 開始)
+}
+
+// Error 指向元素字串中特定 AI 的錯誤
+type Error struct {
+	AI      string
+	Offset  int // 錯誤位置 (從 0 開始, 相對於元素字串)
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.AI == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("AI (%s): %s", e.AI, e.Message)
+}
+
+// IsElementString 判斷資料是否為以 (AI) 開頭的 GS1 元素字串
+func IsElementString(s string) bool {
+	if !strings.HasPrefix(s, "(") {
+		return false
+	}
+	end := strings.IndexByte(s, ')')
+	if end < 3 || end > 5 {
+		return false
+	}
+	for _, ch := range s[1:end] {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse 將 "(01)09501101530003(17)251231" 形式的元素字串拆為 AI 與資料
+// 資料中的 GS (0x1D) 或 TSPL 的 "!102" 視為使用者明確指定的 FNC1
+func Parse(s string) ([]Element, []*Error) {
+	var elements []Element
+	var errs []*Error
+	if !strings.HasPrefix(s, "(") {
+		return nil, []*Error{{Offset: 0, Message: "GS1 元素字串必須以 (AI) 開頭, FNC1 會自動加在起始位置"}}
+	}
+
+	for i := 0; i < len(s); {
+		if s[i] != '(' {
+			errs = append(errs, &Error{Offset: i, Message: "預期為 (AI)"})
+			break
+		}
+		end := strings.IndexByte(s[i:], ')')
+		if end < 0 {
+			errs = append(errs, &Error{Offset: i, Message: "AI 缺少右括號"})
+			break
+		}
+		code := s[i+1 : i+end]
+		valueStart := i + end + 1
+		next := strings.IndexByte(s[valueStart:], '(')
+		valueEnd := len(s)
+		if next >= 0 {
+			valueEnd = valueStart + next
+		}
+		elements = append(elements, Element{AI: code, Value: s[valueStart:valueEnd], Offset: i})
+		i = valueEnd
+	}
+	return elements, errs
+}
+
+// Validate 檢查元素字串的 AI 語法、長度、字元集、日期、檢查碼與 FNC1 位置
+func Validate(s string) []*Error {
+	elements, errs := Parse(s)
+	seen := map[string]string{}
+	dataLength := 0
+
+	for i, el := range elements {
+		at := func(format string, args ...interface{}) {
+			errs = append(errs, &Error{AI: el.AI, Offset: el.Offset, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if len(el.AI) < 2 || len(el.AI) > 4 || strings.Trim(el.AI, "0123456789") != "" {
+			at("AI 必須為 2-4 位數字")
+			continue
+		}
+		ai, ok := Lookup(el.AI)
+		if !ok {
+			at("未知的應用識別碼")
+			continue
+		}
+		if prev, dup := seen[el.AI]; dup && prev != el.Value {
+			at("重複出現且資料不同")
+		}
+		seen[el.AI] = el.Value
+
+		value, explicitFNC1 := stripFNC1(el.Value)
+		if explicitFNC1 {
+			switch {
+			case i == len(elements)-1:
+				at("最後一個 AI 之後不需要 FNC1")
+			case !NeedsSeparator(el.AI):
+				at("%s 為固定長度, 之後不需要 FNC1", ai.Title)
+			}
+		}
+		dataLength += len(el.AI) + len(value)
+		if i < len(elements)-1 && NeedsSeparator(el.AI) {
+			dataLength++ // 可變長度 AI 之後的 FNC1 也佔一個符號字元
+		}
+
+		if msg := checkValue(ai, value); msg != "" {
+			at("%s %s", ai.Title, msg)
+		}
+	}
+
+	if dataLength > MaxDataLength {
+		errs = append(errs, &Error{Offset: 0, Message: fmt.Sprintf("GS1-128 資料長度 %d 超過上限 %d 個字元", dataLength, MaxDataLength)})
+	}
+	return errs
+}
+
+// stripFNC1 移除資料尾端使用者明確指定的 FNC1
+func stripFNC1(value string) (string, bool) {
+	for _, marker := range []string{"\x1d", "!102"} {
+		if strings.HasSuffix(value, marker) {
+			return strings.TrimSuffix(value, marker), true
+		}
+	}
+	return value, false
+}
+
+// checkValue 依格式檢查資料, 回傳錯誤說明 (空字串代表正確)
+func checkValue(ai AI, value string) string {
+	components := parseFormat(ai.Format)
+	pos := 0
+	for idx, c := range components {
+		remaining := len(value) - pos
+		length := c.max
+		if c.min != c.max || idx == len(components)-1 {
+			length = remaining
+			if length > c.max {
+				return fmt.Sprintf("資料長度 %d 超過上限 %d", len(value), formatLength(components))
+			}
+		}
+		if remaining < length || length < c.min {
+			return fmt.Sprintf("資料長度 %d 不符, 格式為 %s", len(value), ai.Format)
+		}
+		field := value[pos : pos+length]
+		for j := 0; j < len(field); j++ {
+			if c.numeric && (field[j] < '0' || field[j] > '9') {
+				return fmt.Sprintf("第 %d 個字元 %q 必須為數字", pos+j+1, field[j])
+			}
+			if !c.numeric && strings.IndexByte(charset82, field[j]) < 0 {
+				return fmt.Sprintf("第 %d 個字元 %q 不在 GS1 字元集 82 中", pos+j+1, field[j])
+			}
+		}
+		if idx == 0 {
+			if ai.Check {
+				if msg := checkDigit(field); msg != "" {
+					return msg
+				}
+			}
+			if ai.Date {
+				if msg := checkDate(field[:6], ai.Code != "7003" && ai.Code != "8008"); msg != "" {
+					return msg
+				}
+			}
+		}
+		pos += length
+	}
+	if pos != len(value) {
+		return fmt.Sprintf("資料長度 %d 不符, 格式為 %s", len(value), ai.Format)
+	}
+	return ""
+}
+
+// formatLength 格式允許的最大總長度
+func formatLength(components []component) int {
+	total := 0
+	for _, c := range components {
+		total += c.max
+	}
+	return total
+}
+
+// checkDigit 驗證數字欄位最後一位的 Mod 10 檢查碼
+func checkDigit(digits string) string {
+	sum := 0
+	n := len(digits)
+	for i := n - 2; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (n-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	expected := (10 - sum%10) % 10
+	if int(digits[n-1]-'0') != expected {
+		return fmt.Sprintf("檢查碼錯誤: 應為 %d", expected)
+	}
+	return ""
+}
+
+// checkDate 驗證 YYMMDD 日期; allowZeroDay 為 true 時 DD 可為 00 (代表當月最後一天)
+func checkDate(date string, allowZeroDay bool) string {
+	month := int(date[2]-'0')*10 + int(date[3]-'0')
+	day := int(date[4]-'0')*10 + int(date[5]-'0')
+	if month < 1 || month > 12 {
+		return fmt.Sprintf("日期 %s 的月份 %02d 無效 (格式 YYMMDD)", date, month)
+	}
+	if day == 0 {
+		if allowZeroDay {
+			return ""
+		}
+		return fmt.Sprintf("日期 %s 的日期 00 無效", date)
+	}
+	year := 2000 + int(date[0]-'0')*10 + int(date[1]-'0')
+	days := []int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}[month-1]
+	if month == 2 && year%4 == 0 {
+		days = 29
+	}
+	if day > days {
+		return fmt.Sprintf("日期 %s 的日期 %02d 超出 %d 月的天數", date, day, month)
+	}
+	return ""
+}
+
+// Content 產生編碼用的資料: 移除括號, 並在非最後一個的可變長度 AI 之後插入 FNC1 (以 GS 0x1D 表示)
+func Content(s string) string {
+	elements, errs := Parse(s)
+	if len(errs) > 0 || len(elements) == 0 {
+		return strings.NewReplacer("(", "", ")", "").Replace(s)
+	}
+	var b strings.Builder
+	for i, el := range elements {
+		value, _ := stripFNC1(el.Value)
+		b.WriteString(el.AI)
+		b.WriteString(value)
+		if i < len(elements)-1 && NeedsSeparator(el.AI) {
+			b.WriteByte(0x1D)
+		}
+	}
+	return b.String()
+}
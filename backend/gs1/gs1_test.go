@@ -0,0 +1,99 @@
+package gs1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // 錯誤訊息應包含的字串, 空字串代表應通過
+	}{
+		{"gtin expiry batch", "(01)09501101530003(17)251231(10)ABC123", ""},
+		{"sscc", "(00)106141411234567897", ""},
+		{"variable then fixed", "(10)ABC123(17)251231", ""},
+		{"variable then FNC1 GS", "(10)ABC\x1d(21)123", ""},
+		{"variable then FNC1 !102", "(10)ABC!102(21)XYZ", ""},
+		{"day 00", "(17)250200", ""},
+		{"leap day", "(11)240229", ""},
+		{"decimal AI", "(3103)000150", ""},
+		{"multi component", "(421)528ABC", ""},
+
+		{"no leading AI", "0109501101530003", "必須以 (AI) 開頭"},
+		{"missing bracket", "(01", "缺少右括號"},
+		{"non-numeric AI", "(1A)123", "2-4 位數字"},
+		{"unknown AI", "(23)12", "未知的應用識別碼"},
+		{"wrong check digit", "(01)09501101530004", "檢查碼錯誤: 應為 3"},
+		{"short GTIN", "(01)0950110153000", "資料長度 13 不符"},
+		{"letter in numeric", "(01)0950110153000A", "必須為數字"},
+		{"invalid month", "(17)251301", "月份 13 無效"},
+		{"not a leap year", "(11)230229", "超出 2 月的天數"},
+		{"day past month end", "(15)250431", "超出 4 月的天數"},
+		{"too long variable", "(10)" + strings.Repeat("A", 21), "超過上限 20"},
+		{"charset 82", "(10)AB#", "不在 GS1 字元集 82 中"},
+		{"FNC1 after fixed length", "(01)09501101530003\x1d(10)ABC", "固定長度"},
+		{"FNC1 after last AI", "(10)ABC!102", "最後一個 AI 之後不需要 FNC1"},
+		{"duplicate AI", "(10)ABC(10)DEF", "重複出現"},
+		{"symbol too long", "(00)106141411234567897(01)09501101530003(10)" + strings.Repeat("A", 20), "超過上限 48"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Validate(tt.data)
+			if tt.want == "" {
+				for _, err := range errs {
+					t.Errorf("Validate(%q): 不應有錯誤: %v", tt.data, err)
+				}
+				return
+			}
+			for _, err := range errs {
+				if strings.Contains(err.Error(), tt.want) {
+					return
+				}
+			}
+			t.Errorf("Validate(%q) = %v, want 包含 %q 的錯誤", tt.data, errs, tt.want)
+		})
+	}
+}
+
+func TestValidateErrorOffset(t *testing.T) {
+	errs := Validate("(01)09501101530003(17)251301")
+	if len(errs) != 1 || errs[0].AI != "17" || errs[0].Offset != 18 {
+		t.Fatalf("Validate = %v, want AI 17 在位置 18 的一個錯誤", errs)
+	}
+}
+
+func TestContent(t *testing.T) {
+	tests := []struct {
+		data, want string
+	}{
+		{"(01)09501101530003(17)251231", "0109501101530003" + "17251231"},
+		{"(10)ABC(21)123", "10ABC\x1d21123"},
+		{"(10)ABC!102(21)123", "10ABC\x1d21123"},
+		{"(10)ABC\x1d(21)123", "10ABC\x1d21123"},
+		{"(01)09501101530003(10)ABC", "0109501101530003" + "10ABC"},
+		{"(21)123", "21123"},
+	}
+	for _, tt := range tests {
+		if got := Content(tt.data); got != tt.want {
+			t.Errorf("Content(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestIsElementString(t *testing.T) {
+	tests := map[string]bool{
+		"(01)09501101530003": true,
+		"(3103)000150":       true,
+		"(1)23":              false,
+		"(12345)6":           false,
+		"(AB)12":             false,
+		"0109501101530003":   false,
+	}
+	for data, want := range tests {
+		if got := IsElementString(data); got != want {
+			t.Errorf("IsElementString(%q) = %v, want %v", data, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package imagestore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// decodeBMP 解碼未壓縮的 BMP (1/4/8/24/32 位元)
+func decodeBMP(data []byte) (image.Image, error) {
+	if len(data) < 54 {
+		return nil, fmt.Errorf("BMP 檔案過短")
+	}
+	le := binary.LittleEndian
+	pixelOffset := int(le.Uint32(data[10:14]))
+	headerSize := int(le.Uint32(data[14:18]))
+	if headerSize < 40 {
+		return nil, fmt.Errorf("不支援的 BMP 標頭 (%d 位元組)", headerSize)
+	}
+	width := int(int32(le.Uint32(data[18:22])))
+	rawHeight := int(int32(le.Uint32(data[22:26])))
+	bpp := int(le.Uint16(data[28:30]))
+	compression := le.Uint32(data[30:34])
+	colorsUsed := int(le.Uint32(data[46:50]))
+
+	if compression != 0 && !(compression == 3 && bpp == 32) {
+		return nil, fmt.Errorf("不支援壓縮的 BMP (壓縮方式 %d)", compression)
+	}
+	topDown := rawHeight < 0
+	height := rawHeight
+	if topDown {
+		height = -rawHeight
+	}
+	if err := checkSize("BMP", width, height); err != nil {
+		return nil, err
+	}
+
+	var palette []color.Color
+	if bpp <= 8 {
+		count := colorsUsed
+		if count == 0 {
+			count = 1 << uint(bpp)
+		}
+		start := 14 + headerSize
+		for i := 0; i < count; i++ {
+			p := start + i*4
+			if p+4 > len(data) {
+				return nil, fmt.Errorf("BMP 調色盤不完整")
+			}
+			palette = append(palette, color.RGBA{R: data[p+2], G: data[p+1], B: data[p], A: 255})
+		}
+	}
+
+	// 尺寸已限制在上限內, stride*height 不會溢位
+	stride := (width*bpp + 31) / 32 * 4
+	if pixelOffset > len(data) || stride*height > len(data)-pixelOffset {
+		return nil, fmt.Errorf("BMP 像素資料不完整")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for row := 0; row < height; row++ {
+		y := height - 1 - row
+		if topDown {
+			y = row
+		}
+		line := data[pixelOffset+row*stride : pixelOffset+(row+1)*stride]
+		for x := 0; x < width; x++ {
+			var c color.Color
+			switch bpp {
+			case 1, 4, 8:
+				bit := x * bpp
+				idx := int(line[bit/8]>>uint(8-bpp-bit%8)) & (1<<uint(bpp) - 1)
+				if idx >= len(palette) {
+					return nil, fmt.Errorf("BMP 調色盤索引超出範圍")
+				}
+				c = palette[idx]
+			case 24:
+				p := x * 3
+				c = color.RGBA{R: line[p+2], G: line[p+1], B: line[p], A: 255}
+			case 32:
+				p := x * 4
+				c = color.RGBA{R: line[p+2], G: line[p+1], B: line[p], A: 255}
+			default:
+				return nil, fmt.Errorf("不支援的 BMP 色彩深度: %d", bpp)
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img, nil
+}
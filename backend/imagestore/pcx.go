@@ -0,0 +1,109 @@
+package imagestore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// decodePCX 解碼 PCX (單色 1 位元、256 色調色盤、24 位元三平面)
+func decodePCX(data []byte) (image.Image, error) {
+	if len(data) < 128 {
+		return nil, fmt.Errorf("PCX 檔案過短")
+	}
+	le := binary.LittleEndian
+	encoding := data[2]
+	bpp := int(data[3])
+	xMin, yMin := int(le.Uint16(data[4:6])), int(le.Uint16(data[6:8]))
+	xMax, yMax := int(le.Uint16(data[8:10])), int(le.Uint16(data[10:12]))
+	planes := int(data[65])
+	bytesPerLine := int(le.Uint16(data[66:68]))
+	width, height := xMax-xMin+1, yMax-yMin+1
+
+	if encoding != 1 {
+		return nil, fmt.Errorf("不支援的 PCX 編碼方式: %d", encoding)
+	}
+	if err := checkSize("PCX", width, height); err != nil {
+		return nil, err
+	}
+	if !(bpp == 1 && planes == 1) && !(bpp == 8 && (planes == 1 || planes == 3)) {
+		return nil, fmt.Errorf("不支援的 PCX 格式: %d 位元 x %d 平面", bpp, planes)
+	}
+	if bytesPerLine*8 < width*bpp {
+		return nil, fmt.Errorf("PCX 每行位元組數 %d 不足以容納寬度 %d", bytesPerLine, width)
+	}
+
+	// RLE 解壓縮; 每個輸入位元組最多展開為 63 個, 不依標頭預先配置
+	total := bytesPerLine * planes * height
+	if total > len(data)*63 {
+		return nil, fmt.Errorf("PCX 像素資料不完整")
+	}
+	pixels := make([]byte, 0, min(total, len(data)))
+	for i := 128; i < len(data) && len(pixels) < total; {
+		b := data[i]
+		i++
+		if b&0xC0 == 0xC0 {
+			count := int(b & 0x3F)
+			if i >= len(data) {
+				break
+			}
+			for n := 0; n < count; n++ {
+				pixels = append(pixels, data[i])
+			}
+			i++
+		} else {
+			pixels = append(pixels, b)
+		}
+	}
+	if len(pixels) < total {
+		return nil, fmt.Errorf("PCX 像素資料不完整")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	lineSize := bytesPerLine * planes
+	switch {
+	case bpp == 1 && planes == 1:
+		// 標頭調色盤的前兩色
+		palette := [2]color.RGBA{
+			{R: data[16], G: data[17], B: data[18], A: 255},
+			{R: data[19], G: data[20], B: data[21], A: 255},
+		}
+		if palette[0] == palette[1] {
+			palette = [2]color.RGBA{{A: 255}, {R: 255, G: 255, B: 255, A: 255}}
+		}
+		for y := 0; y < height; y++ {
+			line := pixels[y*lineSize:]
+			for x := 0; x < width; x++ {
+				img.Set(x, y, palette[(line[x/8]>>uint(7-x%8))&1])
+			}
+		}
+	case bpp == 8 && planes == 1:
+		if len(data) < 769 || data[len(data)-769] != 0x0C {
+			return nil, fmt.Errorf("PCX 缺少 256 色調色盤")
+		}
+		pal := data[len(data)-768:]
+		for y := 0; y < height; y++ {
+			line := pixels[y*lineSize:]
+			for x := 0; x < width; x++ {
+				idx := int(line[x]) * 3
+				img.Set(x, y, color.RGBA{R: pal[idx], G: pal[idx+1], B: pal[idx+2], A: 255})
+			}
+		}
+	case bpp == 8 && planes == 3:
+		for y := 0; y < height; y++ {
+			line := pixels[y*lineSize:]
+			for x := 0; x < width; x++ {
+				img.Set(x, y, color.RGBA{
+					R: line[x],
+					G: line[bytesPerLine+x],
+					B: line[bytesPerLine*2+x],
+					A: 255,
+				})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("不支援的 PCX 格式: %d 位元 x %d 平面", bpp, planes)
+	}
+	return img, nil
+}
@@ -0,0 +1,79 @@
+package imagestore
+
+import (
+	"image"
+	"image/color"
+)
+
+// Bitmap 轉換後的 1 位元點陣, 每列 (Width+7)/8 位元組, 最高位元在左, 1 代表印出
+type Bitmap struct {
+	Width  int
+	Height int
+	Bits   []byte
+}
+
+// Get 取得 (x, y) 是否印出
+func (b *Bitmap) Get(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return false
+	}
+	stride := (b.Width + 7) / 8
+	return b.Bits[y*stride+x/8]&(0x80>>uint(x%8)) != 0
+}
+
+// Rasterize 將影像轉換為熱感列印點陣
+// dither 為 true 時使用 Floyd-Steinberg 誤差擴散 (對應 PUTBMP 的 8 bpp), 否則以 50% 門檻二值化
+// contrast 為 0-100, 50 為原始對比
+func Rasterize(img image.Image, dither bool, contrast int) *Bitmap {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if contrast < 0 || contrast > 100 {
+		contrast = 50
+	}
+	factor := float64(contrast) / 50
+
+	// 轉為灰階 (0 黑 - 255 白), 透明像素視為白色
+	gray := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			lum := float64(color.GrayModel.Convert(color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: 0xFFFF}).(color.Gray).Y)
+			alpha := float64(a) / 0xFFFF
+			lum = lum*alpha + 255*(1-alpha)
+			lum = (lum-128)*factor + 128
+			gray[y*w+x] = lum
+		}
+	}
+
+	stride := (w + 7) / 8
+	bitmap := &Bitmap{Width: w, Height: h, Bits: make([]byte, stride*h)}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			old := gray[y*w+x]
+			black := old < 128
+			if black {
+				bitmap.Bits[y*stride+x/8] |= 0x80 >> uint(x%8)
+			}
+			if !dither {
+				continue
+			}
+			newValue := 255.0
+			if black {
+				newValue = 0
+			}
+			diff := old - newValue
+			spread := func(dx, dy int, weight float64) {
+				nx, ny := x+dx, y+dy
+				if nx >= 0 && nx < w && ny < h {
+					gray[ny*w+nx] += diff * weight
+				}
+			}
+			spread(1, 0, 7.0/16)
+			spread(-1, 1, 3.0/16)
+			spread(0, 1, 5.0/16)
+			spread(1, 1, 1.0/16)
+		}
+	}
+	return bitmap
+}
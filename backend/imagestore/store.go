@@ -0,0 +1,196 @@
+package imagestore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 打印機記憶體的預設容量
+const (
+	DefaultMaxBytes = 64 << 20 // 所有檔案的總大小上限 (位元組)
+	DefaultMaxFiles = 256      // 檔案數上限
+)
+
+// ErrStoreFull 寫入後會超過打印機記憶體的容量或檔案數上限
+var ErrStoreFull = errors.New("打印機記憶體不足")
+
+// Store 模擬打印機記憶體中的檔案 (DOWNLOAD 或 API 上傳的 BMP/PCX/PNG)
+type Store struct {
+	mu       sync.RWMutex
+	files    map[string][]byte
+	size     int64 // 目前所有檔案的總大小
+	maxBytes int64
+	maxFiles int
+}
+
+// FileInfo 檔案資訊
+type FileInfo struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Format string `json:"format"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// NewStore 建立預設容量 (DefaultMaxBytes, DefaultMaxFiles) 的檔案儲存區
+func NewStore() *Store {
+	return NewStoreWithLimits(DefaultMaxBytes, DefaultMaxFiles)
+}
+
+// NewStoreWithLimits 建立指定總大小與檔案數上限的檔案儲存區, 小於等於 0 時使用預設值
+// 超過上限的寫入回傳 ErrStoreFull, 不會淘汰既有的檔案 (與實體打印機記憶體已滿時相同)
+func NewStoreWithLimits(maxBytes int64, maxFiles int) *Store {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	return &Store{
+		files:    make(map[string][]byte),
+		maxBytes: maxBytes,
+		maxFiles: maxFiles,
+	}
+}
+
+// normalizeName 打印機檔名不分大小寫
+func normalizeName(name string) string {
+	return strings.ToUpper(strings.TrimSpace(strings.Trim(name, `"`)))
+}
+
+// Put 寫入檔案; 影像檔 (BMP/PCX/PNG) 會先確認可以解碼
+func (s *Store) Put(name string, data []byte) error {
+	name = normalizeName(name)
+	if name == "" {
+		return fmt.Errorf("檔名不可為空")
+	}
+	if isImageName(name) {
+		if _, _, err := Decode(name, data); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, exists := s.files[name]
+	size := s.size - int64(len(old)) + int64(len(data))
+	if size > s.maxBytes {
+		return fmt.Errorf("%w: 寫入 %s 後共 %d 位元組, 超過上限 %d", ErrStoreFull, name, size, s.maxBytes)
+	}
+	if !exists && len(s.files) >= s.maxFiles {
+		return fmt.Errorf("%w: 已有 %d 個檔案, 超過上限", ErrStoreFull, len(s.files))
+	}
+	s.files[name] = append([]byte(nil), data...)
+	s.size = size
+	return nil
+}
+
+// Usage 目前所有檔案的總大小與檔案數
+func (s *Store) Usage() (int64, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size, len(s.files)
+}
+
+// Get 讀取檔案
+func (s *Store) Get(name string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.files[normalizeName(name)]
+	return data, ok
+}
+
+// Delete 刪除檔案, 回傳檔案是否存在
+func (s *Store) Delete(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = normalizeName(name)
+	data, ok := s.files[name]
+	if !ok {
+		return false
+	}
+	delete(s.files, name)
+	s.size -= int64(len(data))
+	return true
+}
+
+// List 列出所有檔案 (依檔名排序)
+func (s *Store) List() []FileInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files := make([]FileInfo, 0, len(s.files))
+	for name, data := range s.files {
+		info := FileInfo{Name: name, Size: len(data), Format: strings.TrimPrefix(filepath.Ext(name), ".")}
+		if isImageName(name) {
+			if img, format, err := Decode(name, data); err == nil {
+				info.Format = format
+				info.Width = img.Bounds().Dx()
+				info.Height = img.Bounds().Dy()
+			}
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// isImageName 依副檔名判斷是否為影像檔
+func isImageName(name string) bool {
+	switch strings.ToUpper(filepath.Ext(name)) {
+	case ".BMP", ".PCX", ".PNG":
+		return true
+	}
+	return false
+}
+
+// 影像尺寸上限, 避免依檔案標頭配置過大的記憶體
+const (
+	maxImageSide   = 8192        // 單邊像素上限
+	maxImagePixels = 4096 * 4096 // 總像素上限
+)
+
+// checkSize 檢查影像尺寸是否在上限內
+func checkSize(format string, width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("%s 尺寸錯誤: %dx%d", format, width, height)
+	}
+	if width > maxImageSide || height > maxImageSide || width*height > maxImagePixels {
+		return fmt.Errorf("%s 尺寸 %dx%d 超過上限 (單邊 %d, 總像素 %d)", format, width, height, maxImageSide, maxImagePixels)
+	}
+	return nil
+}
+
+// Decode 依檔案內容 (必要時參考副檔名) 解碼 BMP、PCX 或 PNG 影像
+func Decode(name string, data []byte) (image.Image, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("BM")):
+		img, err := decodeBMP(data)
+		return img, "bmp", err
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, "png", fmt.Errorf("PNG 解碼失敗: %v", err)
+		}
+		if err := checkSize("PNG", cfg.Width, cfg.Height); err != nil {
+			return nil, "png", err
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "png", fmt.Errorf("PNG 解碼失敗: %v", err)
+		}
+		return img, "png", nil
+	case len(data) > 0 && data[0] == 0x0A:
+		img, err := decodePCX(data)
+		return img, "pcx", err
+	default:
+		return nil, "", fmt.Errorf("%s 不是可辨識的 BMP、PCX 或 PNG 檔案", name)
+	}
+}
@@ -0,0 +1,42 @@
+package imagestore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStoreLimits(t *testing.T) {
+	s := NewStoreWithLimits(10, 2)
+	if err := s.Put("a.txt", []byte("123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("b.txt", []byte("12345")); !errors.Is(err, ErrStoreFull) {
+		t.Errorf("超過總大小: got %v, want ErrStoreFull", err)
+	}
+	// 覆寫同名檔案時以新的大小計算
+	if err := s.Put("A.TXT", []byte("1234567890")); err != nil {
+		t.Errorf("覆寫同名檔案: %v", err)
+	}
+	if used, count := s.Usage(); used != 10 || count != 1 {
+		t.Errorf("Usage = %d, %d, want 10, 1", used, count)
+	}
+
+	if !s.Delete("a.txt") {
+		t.Fatal("Delete 回傳 false")
+	}
+	if used, count := s.Usage(); used != 0 || count != 0 {
+		t.Errorf("刪除後 Usage = %d, %d, want 0, 0", used, count)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := s.Put(name, []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Put("c.txt", []byte("1")); !errors.Is(err, ErrStoreFull) {
+		t.Errorf("超過檔案數: got %v, want ErrStoreFull", err)
+	}
+	if _, ok := s.Get("c.txt"); ok {
+		t.Error("寫入失敗的檔案不應存在")
+	}
+}
@@ -0,0 +1,185 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tspl-simulator/formatter"
+	"tspl-simulator/parser"
+	"tspl-simulator/validator"
+)
+
+// 嚴重程度
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding 單一檢查結果
+type Finding struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Command  string `json:"command,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Rules 規則名稱與說明
+var Rules = map[string]string{
+	"syntax":           "TSPL 語法與內容驗證錯誤",
+	"validation":       "TSPL 驗證警告 (自動加入的檢查碼、QR Code 容量等)",
+	"command-case":     "命令名稱應為大寫",
+	"format":           "行內容與 tspl fmt 的輸出不一致",
+	"missing-cls":      "繪圖命令之前應先以 CLS 清除影像緩衝區",
+	"out-of-bounds":    "元素起點超出標籤範圍",
+	"after-print":      "最後一個 PRINT 之後的繪圖命令不會被列印",
+	"unused-download":  "DOWNLOAD 的檔案沒有被 PUTBMP/PUTPCX 使用",
+	"missing-download": "PUTBMP/PUTPCX 使用的檔案沒有在本檔案中 DOWNLOAD (需已存在於打印機記憶體)",
+}
+
+// drawingCommands 會寫入影像緩衝區的命令
+var drawingCommands = map[string]bool{
+	"TEXT": true, "BARCODE": true, "QRCODE": true, "BOX": true, "BAR": true,
+	"CIRCLE": true, "ELLIPSE": true, "DIAGONAL": true, "TRIANGLE": true,
+	"REVERSE": true, "ERASE": true, "PUTBMP": true, "PUTPCX": true, "BITMAP": true, "BLOCK": true,
+}
+
+// Validate 將 validator 的錯誤與警告轉換為檢查結果
+func Validate(tsplCode string) []Finding {
+	var findings []Finding
+	result := validator.ValidateTSPL(tsplCode)
+	for _, e := range result.Errors {
+		findings = append(findings, Finding{Line: e.Line, Column: e.Column, Rule: "syntax", Severity: SeverityError, Command: e.Command, Message: e.Message})
+	}
+	for _, e := range result.Warnings {
+		findings = append(findings, Finding{Line: e.Line, Column: e.Column, Rule: "validation", Severity: SeverityWarning, Command: e.Command, Message: e.Message})
+	}
+	return findings
+}
+
+// Lint 檢查 TSPL 程式碼: 包含 validator 的錯誤與警告以及風格與常見錯誤規則
+func Lint(tsplCode string) []Finding {
+	findings := Validate(tsplCode)
+
+	var (
+		width, height int
+		cleared       bool
+		lastPrint     int
+		downloads     = map[string]int{}
+		used          = map[string]bool{}
+		drawn         []int
+	)
+	warn := func(line, column int, rule, command, format string, args ...interface{}) {
+		findings = append(findings, Finding{Line: line, Column: column, Rule: rule, Severity: SeverityWarning, Command: command, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, src := range parser.SplitSource(tsplCode) {
+		raw := strings.TrimRight(src.Text, "\r")
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, ":") {
+			continue
+		}
+		word := strings.Fields(line)[0]
+		command := strings.ToUpper(word)
+		args := parser.SplitArgs(line)
+
+		if src.Download == nil {
+			if word != command {
+				warn(src.Line, 1+len(raw)-len(strings.TrimLeft(raw, " \t")), "command-case", command, "命令 %s 應寫為 %s", word, command)
+			} else if formatted := formatter.Format(line); strings.TrimSuffix(formatted, "\n") != raw {
+				warn(src.Line, 1, "format", command, "格式與 tspl fmt 不一致, 應為: %s", strings.TrimSuffix(formatted, "\n"))
+			}
+		}
+
+		switch command {
+		case "SIZE":
+			if len(args) >= 2 {
+				w, u1, err1 := parseLength(args[0])
+				h, u2, err2 := parseLength(args[1])
+				if err1 == nil && err2 == nil {
+					width, height = parser.ToDots(w, u1), parser.ToDots(h, u2)
+				}
+			}
+		case "CLS":
+			cleared = true
+		case "PRINT":
+			lastPrint = src.Line
+			drawn = nil
+		case "DOWNLOAD":
+			if src.Download != nil {
+				downloads[strings.ToUpper(src.Download.Name)] = src.Line
+			}
+		}
+
+		if !drawingCommands[command] {
+			continue
+		}
+		drawn = append(drawn, src.Line)
+		if !cleared {
+			warn(src.Line, 1, "missing-cls", command, "%s 之前沒有 CLS, 影像緩衝區可能殘留上一張標籤的內容", command)
+			cleared = true
+		}
+		if command == "PUTBMP" || command == "PUTPCX" {
+			if len(args) >= 3 {
+				name := strings.ToUpper(strings.Trim(args[2], `"`))
+				used[name] = true
+				if _, ok := downloads[name]; !ok {
+					warn(src.Line, 1, "missing-download", command, "檔案 %q 沒有在本檔案中 DOWNLOAD, 需已存在於打印機記憶體", name)
+				}
+			}
+		}
+		if width > 0 && len(args) >= 2 {
+			x, errX := strconv.Atoi(strings.TrimSpace(args[0]))
+			y, errY := strconv.Atoi(strings.TrimSpace(args[1]))
+			if errX == nil && errY == nil && (x >= width || y >= height) {
+				warn(src.Line, 1, "out-of-bounds", command, "起點 (%d, %d) 超出標籤範圍 %d×%d 點", x, y, width, height)
+			}
+		}
+	}
+
+	for _, line := range drawn {
+		if lastPrint > 0 {
+			warn(line, 1, "after-print", "", "位於最後一個 PRINT (第 %d 行) 之後, 不會被列印", lastPrint)
+		}
+	}
+	for name, line := range downloads {
+		if !used[name] && !strings.HasSuffix(name, ".BAS") {
+			warn(line, 1, "unused-download", "DOWNLOAD", "檔案 %q 沒有被 PUTBMP/PUTPCX 使用", name)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings
+}
+
+// HasErrors 判斷檢查結果中是否有錯誤
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLength 解析 SIZE 的 "100 mm" 或 "4 inch"
+func parseLength(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	unit := "mm"
+	for _, u := range []string{"mm", "inch"} {
+		if strings.HasSuffix(s, u) {
+			unit = u
+			s = strings.TrimSpace(strings.TrimSuffix(s, u))
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, unit, err
+}
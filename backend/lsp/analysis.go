@@ -0,0 +1,215 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"tspl-simulator/parser"
+	"tspl-simulator/validator"
+)
+
+// downloadPattern 定義的來源: DOWNLOAD 檔案
+var downloadPattern = regexp.MustCompile(`(?i)^\s*DOWNLOAD\s+(?:[FE]\s*,\s*)?"([^"]+)"`)
+
+// symbol 文件中定義的名稱
+type symbol struct {
+	kind string // download
+	name string
+	line int
+	span [2]int // 名稱在該行的位元組範圍
+}
+
+// splitLines 將文件拆成行 (去除行尾的 \r)
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// utf16Column 將行內的位元組位移換算為 LSP 使用的 UTF-16 位移
+func utf16Column(line string, offset int) int {
+	if offset > len(line) {
+		offset = len(line)
+	}
+	n := 0
+	for _, r := range line[:offset] {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// byteOffset 將 UTF-16 位移換算為行內的位元組位移
+func byteOffset(line string, character int) int {
+	n := 0
+	for i, r := range line {
+		if n >= character {
+			return i
+		}
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// runeOffset 將 1 起算的字元欄位換算為位元組位移
+func runeOffset(line string, column int) int {
+	offset := 0
+	for i := 1; i < column && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// lineRange 建立同一行內位元組範圍的 Range
+func lineRange(lines []string, line, start, end int) Range {
+	text := ""
+	if line >= 0 && line < len(lines) {
+		text = lines[line]
+	}
+	return Range{
+		Start: Position{Line: line, Character: utf16Column(text, start)},
+		End:   Position{Line: line, Character: utf16Column(text, end)},
+	}
+}
+
+// tokenEnd 從 start 開始的參數結尾: 引號字串到右引號, 否則到下一個逗號
+func tokenEnd(line string, start int) int {
+	if start < len(line) && line[start] == '"' {
+		if end := strings.IndexByte(line[start+1:], '"'); end >= 0 {
+			return start + end + 2
+		}
+		return len(line)
+	}
+	if end := strings.IndexByte(line[start:], ','); end >= 0 {
+		return start + end
+	}
+	return len(strings.TrimRight(line, " \t"))
+}
+
+// diagnostics 以 validator 檢查文件並轉換為 LSP 診斷
+func diagnostics(text string) []Diagnostic {
+	result := validator.ValidateTSPL(text)
+	lines := splitLines(text)
+	out := []Diagnostic{}
+	add := func(e validator.ValidationError, severity int) {
+		line := e.Line - 1
+		if line < 0 {
+			line = 0
+		}
+		content := ""
+		if line < len(lines) {
+			content = lines[line]
+		}
+		start := len(content) - len(strings.TrimLeft(content, " \t"))
+		end := len(strings.TrimRight(content, " \t"))
+		if e.Column > 0 {
+			start = runeOffset(content, e.Column)
+			end = tokenEnd(content, start)
+		}
+		if end < start {
+			end = start
+		}
+		d := Diagnostic{
+			Range:    lineRange(lines, line, start, end),
+			Severity: severity,
+			Source:   "tspl",
+			Message:  e.Message,
+		}
+		if e.AI != "" {
+			d.Code = "AI " + e.AI
+		}
+		out = append(out, d)
+	}
+	for _, e := range result.Errors {
+		add(e, severityError)
+	}
+	for _, e := range result.Warnings {
+		add(e, severityWarning)
+	}
+	return out
+}
+
+// cursor 游標所在的命令與參數
+type cursor struct {
+	command string // 大寫命令名稱
+	param   int    // 參數索引, -1 代表在命令名稱上
+	start   int    // 目前 token 的位元組範圍
+	end     int
+	value   string // 參數值 (去除引號)
+}
+
+// locate 分析游標所在的命令與參數 (引號內的逗號不分割)
+func locate(line string, offset int) (cursor, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent := len(line) - len(trimmed)
+	if trimmed == "" || strings.HasPrefix(trimmed, ";") || offset < indent {
+		return cursor{param: -1, start: offset, end: offset}, trimmed == "" || offset < indent
+	}
+	cmdEnd := indent
+	for cmdEnd < len(line) && line[cmdEnd] != ' ' && line[cmdEnd] != '\t' {
+		cmdEnd++
+	}
+	c := cursor{command: strings.ToUpper(line[indent:cmdEnd]), param: -1, start: indent, end: cmdEnd}
+	if offset <= cmdEnd {
+		return c, true
+	}
+
+	argStart := cmdEnd
+	for argStart < len(line) && (line[argStart] == ' ' || line[argStart] == '\t') {
+		argStart++
+	}
+	param, start := 0, argStart
+	inQuote := false
+	for i := argStart; i < len(line) && i < offset; i++ {
+		switch line[i] {
+		case '"':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				param++
+				start = i + 1
+			}
+		}
+	}
+	for start < len(line) && line[start] == ' ' {
+		start++
+	}
+	c.param = param
+	c.start = start
+	c.end = tokenEnd(line, start)
+	if c.end < offset {
+		c.end = offset
+	}
+	c.value = strings.Trim(strings.TrimSpace(line[c.start:c.end]), `"`)
+	return c, true
+}
+
+// symbols 掃描文件中的定義 (DOWNLOAD 的二進位內容不參與掃描)
+func symbols(text string) []symbol {
+	code, _ := parser.ExtractDownloads(text)
+	var out []symbol
+	for i, line := range splitLines(code) {
+		if m := downloadPattern.FindStringSubmatchIndex(line); m != nil {
+			out = append(out, symbol{kind: "download", name: line[m[2]:m[3]], line: i, span: [2]int{m[2], m[3]}})
+		}
+	}
+	return out
+}
+
+// referenceKind 命令參數所引用的定義種類
+func referenceKind(command string, param int) string {
+	switch {
+	case (command == "PUTBMP" || command == "PUTPCX") && param == 2:
+		return "download"
+	}
+	return ""
+}
+
+// sameName 比對名稱 (印表機檔名不分大小寫)
+func sameName(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
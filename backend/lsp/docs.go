@@ -0,0 +1,240 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// commandDoc 命令的語法與參數說明 (hover 與補全使用)
+type commandDoc struct {
+	Syntax  string
+	Summary string
+	Params  []paramDoc
+}
+
+// paramDoc 參數名稱與說明
+type paramDoc struct {
+	Name string
+	Doc  string
+}
+
+// commandDocs TSPL2 命令說明
+var commandDocs = map[string]commandDoc{
+	"SIZE": {`SIZE m,n`, "設定標籤寬度與高度, 單位為 mm (預設) 或 inch", []paramDoc{
+		{"m", "標籤寬度, 例如 100 mm 或 4 inch"},
+		{"n", "標籤高度, 例如 60 mm 或 2.5 inch"},
+	}},
+	"GAP": {`GAP m,n`, "設定標籤間隙 (間隙紙)", []paramDoc{
+		{"m", "兩張標籤之間的間隙距離"},
+		{"n", "間隙的偏移距離"},
+	}},
+	"BLINE": {`BLINE m,n`, "設定黑標高度與偏移 (黑標紙)", []paramDoc{
+		{"m", "黑標高度"},
+		{"n", "黑標的額外進紙距離"},
+	}},
+	"DIRECTION": {`DIRECTION n[,m]`, "設定列印方向與鏡像", []paramDoc{
+		{"n", "0 或 1, 1 時整張標籤旋轉 180 度"},
+		{"m", "0 一般, 1 鏡像"},
+	}},
+	"REFERENCE": {`REFERENCE x,y`, "設定標籤原點的參考點", []paramDoc{
+		{"x", "水平座標 (點)"},
+		{"y", "垂直座標 (點)"},
+	}},
+	"OFFSET":    {`OFFSET m`, "設定撕紙/剝離位置的額外進紙距離", []paramDoc{{"m", "偏移距離"}}},
+	"SHIFT":     {`SHIFT n`, "設定垂直方向的列印位置微調", []paramDoc{{"n", "位移點數, 可為負值"}}},
+	"CLS":       {`CLS`, "清除影像緩衝區", nil},
+	"HOME":      {`HOME`, "進紙至下一張標籤的起點", nil},
+	"FORMFEED":  {`FORMFEED`, "進紙一張標籤", nil},
+	"BACKFEED":  {`BACKFEED n`, "退紙指定的點數", []paramDoc{{"n", "退紙點數"}}},
+	"LIMITFEED": {`LIMITFEED n`, "設定偵測間隙時的最大進紙長度", []paramDoc{{"n", "最大進紙長度"}}},
+	"SELFTEST":  {`SELFTEST`, "列印自我測試頁", nil},
+	"SOUND":     {`SOUND level,interval`, "控制蜂鳴器", []paramDoc{{"level", "音階 0-9"}, {"interval", "持續時間 1-4095"}}},
+	"CODEPAGE":  {`CODEPAGE n`, "設定字元編碼頁", []paramDoc{{"n", "編碼頁, 例如 437、850、UTF-8"}}},
+	"COUNTRY":   {`COUNTRY n`, "設定鍵盤國碼", []paramDoc{{"n", "國碼, 例如 001"}}},
+	"DENSITY":   {`DENSITY n`, "設定列印濃度 (影響熱感模擬的點擴散)", []paramDoc{{"n", "濃度 0-15, 預設 8"}}},
+	"SPEED":     {`SPEED n`, "設定列印速度 (英吋/秒)", []paramDoc{{"n", "速度, 例如 2、4、6"}}},
+	"SET":       {`SET option value`, "設定印表機選項, 例如 SET RIBBON ON", []paramDoc{{"option", "選項名稱"}, {"value", "選項值"}}},
+	"TEXT": {`TEXT x,y,"font",rotation,x-multiplication,y-multiplication,"content"`, "列印文字", []paramDoc{
+		{"x", "文字左上角 X 座標 (點)"},
+		{"y", "文字左上角 Y 座標 (點)"},
+		{"font", "字型名稱, 內建點陣字型 \"1\"-\"8\" 或 TTF 字型"},
+		{"rotation", "旋轉角度 0、90、180、270"},
+		{"x-multiplication", "水平放大倍數 1-10"},
+		{"y-multiplication", "垂直放大倍數 1-10"},
+		{"content", "文字內容"},
+	}},
+	"BARCODE": {`BARCODE x,y,"code type",height,human readable,rotation,narrow,wide,"code"`, "列印一維條碼", []paramDoc{
+		{"x", "條碼左上角 X 座標 (點)"},
+		{"y", "條碼左上角 Y 座標 (點)"},
+		{"code type", "條碼類型, 例如 \"128\"、\"EAN13\"、\"39\""},
+		{"height", "條碼高度 (點)"},
+		{"human readable", "0 不列印人眼可讀文字, 1 列印"},
+		{"rotation", "旋轉角度 0、90、180、270"},
+		{"narrow", "窄條寬度 (點)"},
+		{"wide", "寬條寬度 (點)"},
+		{"code", "條碼內容"},
+	}},
+	"QRCODE": {`QRCODE x,y,ECC level,cell width,mode,rotation,"data"`, "列印 QR Code", []paramDoc{
+		{"x", "QR Code 左上角 X 座標 (點)"},
+		{"y", "QR Code 左上角 Y 座標 (點)"},
+		{"ECC level", "糾錯等級 L (7%)、M (15%)、Q (25%)、H (30%)"},
+		{"cell width", "模組寬度 1-10 (點)"},
+		{"mode", "A 自動編碼, M 手動編碼 (資料以 N/A/B/K 開頭)"},
+		{"rotation", "旋轉角度 0、90、180、270"},
+		{"data", "QR Code 內容"},
+	}},
+	"BOX": {`BOX x,y,x_end,y_end,line thickness[,radius]`, "繪製矩形外框", []paramDoc{
+		{"x", "左上角 X 座標 (點)"},
+		{"y", "左上角 Y 座標 (點)"},
+		{"x_end", "右下角 X 座標 (點)"},
+		{"y_end", "右下角 Y 座標 (點)"},
+		{"line thickness", "線寬 (點)"},
+		{"radius", "圓角半徑 (點), 選用"},
+	}},
+	"BAR": {`BAR x,y,width,height`, "繪製實心矩形", []paramDoc{
+		{"x", "左上角 X 座標 (點)"},
+		{"y", "左上角 Y 座標 (點)"},
+		{"width", "寬度 (點)"},
+		{"height", "高度 (點)"},
+	}},
+	"CIRCLE": {`CIRCLE x,y,diameter,thickness`, "繪製圓形", []paramDoc{
+		{"x", "外接正方形左上角 X 座標 (點)"},
+		{"y", "外接正方形左上角 Y 座標 (點)"},
+		{"diameter", "直徑 (點)"},
+		{"thickness", "線寬 (點)"},
+	}},
+	"ELLIPSE": {`ELLIPSE x,y,width,height,thickness`, "繪製橢圓", []paramDoc{
+		{"x", "外接矩形左上角 X 座標 (點)"},
+		{"y", "外接矩形左上角 Y 座標 (點)"},
+		{"width", "寬度 (點)"},
+		{"height", "高度 (點)"},
+		{"thickness", "線寬 (點)"},
+	}},
+	"DIAGONAL": {`DIAGONAL x1,y1,x2,y2,thickness`, "繪製斜線", []paramDoc{
+		{"x1", "起點 X 座標 (點)"},
+		{"y1", "起點 Y 座標 (點)"},
+		{"x2", "終點 X 座標 (點)"},
+		{"y2", "終點 Y 座標 (點)"},
+		{"thickness", "線寬 (點)"},
+	}},
+	"TRIANGLE": {`TRIANGLE x1,y1,x2,y2,x3,y3,thickness`, "繪製三角形", []paramDoc{
+		{"x1", "第一個頂點 X 座標 (點)"},
+		{"y1", "第一個頂點 Y 座標 (點)"},
+		{"x2", "第二個頂點 X 座標 (點)"},
+		{"y2", "第二個頂點 Y 座標 (點)"},
+		{"x3", "第三個頂點 X 座標 (點)"},
+		{"y3", "第三個頂點 Y 座標 (點)"},
+		{"thickness", "線寬 (點)"},
+	}},
+	"REVERSE": {`REVERSE x,y,width,height`, "將區域反白", []paramDoc{
+		{"x", "左上角 X 座標 (點)"},
+		{"y", "左上角 Y 座標 (點)"},
+		{"width", "寬度 (點)"},
+		{"height", "高度 (點)"},
+	}},
+	"ERASE": {`ERASE x,y,width,height`, "清除區域", []paramDoc{
+		{"x", "左上角 X 座標 (點)"},
+		{"y", "左上角 Y 座標 (點)"},
+		{"width", "寬度 (點)"},
+		{"height", "高度 (點)"},
+	}},
+	"BITMAP": {`BITMAP x,y,width,height,mode,bitmap data`, "繪製點陣圖", []paramDoc{
+		{"x", "左上角 X 座標 (點)"},
+		{"y", "左上角 Y 座標 (點)"},
+		{"width", "寬度 (位元組)"},
+		{"height", "高度 (點)"},
+		{"mode", "0 覆蓋, 1 OR, 2 XOR"},
+		{"bitmap data", "點陣資料"},
+	}},
+	"PUTBMP": {`PUTBMP x,y,"filename"[,bpp][,contrast]`, "列印已下載至印表機記憶體的 BMP 檔", []paramDoc{
+		{"x", "左上角 X 座標 (點)"},
+		{"y", "左上角 Y 座標 (點)"},
+		{"filename", "以 DOWNLOAD 下載的檔名"},
+		{"bpp", "1 黑白, 8 灰階 (依對比二值化)"},
+		{"contrast", "灰階二值化對比 0-100"},
+	}},
+	"PUTPCX": {`PUTPCX x,y,"filename"`, "列印已下載至印表機記憶體的 PCX 檔", []paramDoc{
+		{"x", "左上角 X 座標 (點)"},
+		{"y", "左上角 Y 座標 (點)"},
+		{"filename", "以 DOWNLOAD 下載的檔名"},
+	}},
+	"DOWNLOAD": {`DOWNLOAD [n,]"FILENAME",DATA SIZE,DATA CONTENT`, "下載檔案至印表機記憶體", []paramDoc{
+		{"n", "F 快閃記憶體, E 擴充記憶體 (預設 DRAM)"},
+		{"FILENAME", "檔名"},
+		{"DATA SIZE", "資料位元組數"},
+		{"DATA CONTENT", "檔案內容"},
+	}},
+	"EOP":   {`EOP`, "結束以 DOWNLOAD 下載的程式", nil},
+	"BLOCK": {`BLOCK x,y,width,height,"font",rotation,x-multiplication,y-multiplication,"content"`, "在區塊內列印自動換行的文字", nil},
+	"PRINT": {`PRINT m[,n]`, "列印影像緩衝區內容", []paramDoc{{"m", "列印的標籤組數"}, {"n", "每組重複列印的份數"}}},
+}
+
+// barcodeTypes BARCODE 支援的條碼類型與說明
+var barcodeTypes = map[string]string{
+	"128":    "Code 128, 自動切換字元集",
+	"128M":   "Code 128, 手動切換字元集",
+	"EAN128": "GS1-128, 資料以 (AI) 表示",
+	"39":     "Code 39 (full ASCII)",
+	"39S":    "Code 39 (standard)",
+	"39C":    "Code 39, 自動加入 Mod 43 檢查字元",
+	"EAN13":  "EAN-13, 12 位時自動加入檢查碼",
+	"EAN8":   "EAN-8, 7 位時自動加入檢查碼",
+	"UPCA":   "UPC-A, 11 位時自動加入檢查碼",
+	"25":     "Interleaved 2 of 5",
+	"25C":    "Interleaved 2 of 5, 自動加入 Mod 10 檢查碼",
+	"ITF14":  "ITF-14, 13 位時自動加入檢查碼",
+	"CODA":   "Codabar",
+}
+
+// fontNames TEXT 內建點陣字型與說明
+var fontNames = map[string]string{
+	"1": "8 x 12 點",
+	"2": "12 x 20 點",
+	"3": "16 x 24 點",
+	"4": "24 x 32 點",
+	"5": "32 x 48 點",
+	"6": "14 x 19 點 (OCR-B)",
+	"7": "21 x 27 點 (OCR-B)",
+	"8": "14 x 25 點 (OCR-A)",
+}
+
+// eccLevels QRCODE 糾錯等級
+var eccLevels = map[string]string{
+	"L": "約可修復 7% 資料",
+	"M": "約可修復 15% 資料",
+	"Q": "約可修復 25% 資料",
+	"H": "約可修復 30% 資料",
+}
+
+// commandMarkdown 產生命令的 hover 說明; param >= 0 時強調該參數
+func commandMarkdown(command string, param int) (string, bool) {
+	doc, ok := commandDocs[command]
+	if !ok {
+		return "", false
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "```tspl\n%s\n```\n\n%s", doc.Syntax, doc.Summary)
+	if param >= 0 && param < len(doc.Params) {
+		p := doc.Params[param]
+		fmt.Fprintf(&b, "\n\n**%s** — %s", p.Name, p.Doc)
+		return b.String(), true
+	}
+	if len(doc.Params) > 0 {
+		b.WriteString("\n")
+		for _, p := range doc.Params {
+			fmt.Fprintf(&b, "\n- `%s` — %s", p.Name, p.Doc)
+		}
+	}
+	return b.String(), true
+}
+
+// sortedKeys 依字母排序取出 map 的鍵
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
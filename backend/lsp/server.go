@@ -0,0 +1,312 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Server 以 stdio 提供 TSPL 的 Language Server Protocol 服務
+// 支援診斷、hover、補全與跳至定義 (DOWNLOAD 檔案)
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]string // URI -> 文件內容
+	order    []string          // 開啟順序, 跨文件尋找定義時依序搜尋
+	shutdown bool
+}
+
+// NewServer 建立 LSP 服務
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]string{},
+	}
+}
+
+// Run 處理訊息直到收到 exit 或輸入結束
+func (s *Server) Run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			s.reply(nil, nil, msg.Error)
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("未收到 shutdown 即結束")
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			log.Printf("處理 %s 失敗: %v", msg.Method, err)
+		}
+	}
+}
+
+// handle 分派請求與通知
+func (s *Server) handle(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1, // 全文同步
+				"hoverProvider":      true,
+				"definitionProvider": true,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{",", `"`},
+				},
+			},
+			"serverInfo": map[string]string{"name": "tspl-lsp"},
+		}, nil)
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(msg.ID, nil, nil)
+
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return err
+		}
+		if _, ok := s.docs[p.TextDocument.URI]; !ok {
+			s.order = append(s.order, p.TextDocument.URI)
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		return s.publish(p.TextDocument.URI)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		return s.publish(p.TextDocument.URI)
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		for i, uri := range s.order {
+			if uri == p.TextDocument.URI {
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				break
+			}
+		}
+		return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+
+	case "textDocument/hover", "textDocument/completion", "textDocument/definition":
+		var p positionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return s.reply(msg.ID, nil, &responseError{Code: codeInvalidParams, Message: err.Error()})
+		}
+		switch msg.Method {
+		case "textDocument/hover":
+			return s.reply(msg.ID, s.hover(p), nil)
+		case "textDocument/completion":
+			return s.reply(msg.ID, s.completion(p), nil)
+		default:
+			return s.reply(msg.ID, s.definition(p), nil)
+		}
+	}
+
+	if msg.ID != nil {
+		return s.reply(msg.ID, nil, &responseError{Code: codeMethodNotFound, Message: "不支援的方法: " + msg.Method})
+	}
+	return nil
+}
+
+// reply 回應請求 (result 為 nil 時輸出 null)
+func (s *Server) reply(id *json.RawMessage, result interface{}, rpcErr *responseError) error {
+	msg := &message{ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		if result == nil {
+			result = json.RawMessage("null")
+		}
+		msg.Result = result
+	}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+	return writeMessage(s.out, msg)
+}
+
+// notify 送出通知
+func (s *Server) notify(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: method, Params: raw})
+}
+
+// publish 重新驗證文件並發布診斷
+func (s *Server) publish(uri string) error {
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics(s.docs[uri])})
+}
+
+// lineAt 取得文件中的一行與游標的位元組位移
+func (s *Server) lineAt(p positionParams) (string, int, bool) {
+	text, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return "", 0, false
+	}
+	lines := splitLines(text)
+	if p.Position.Line < 0 || p.Position.Line >= len(lines) {
+		return "", 0, false
+	}
+	line := lines[p.Position.Line]
+	return line, byteOffset(line, p.Position.Character), true
+}
+
+// hover 顯示命令或參數說明
+func (s *Server) hover(p positionParams) *Hover {
+	line, offset, ok := s.lineAt(p)
+	if !ok {
+		return nil
+	}
+	c, ok := locate(line, offset)
+	if !ok || c.command == "" {
+		return nil
+	}
+	text, ok := commandMarkdown(c.command, c.param)
+	if !ok {
+		return nil
+	}
+	switch {
+	case c.command == "BARCODE" && c.param == 2:
+		if doc, ok := barcodeTypes[strings.ToUpper(c.value)]; ok {
+			text += "\n\n`" + strings.ToUpper(c.value) + "`: " + doc
+		}
+	case c.command == "TEXT" && c.param == 2:
+		if doc, ok := fontNames[c.value]; ok {
+			text += "\n\n字型 `" + c.value + "`: " + doc
+		}
+	case c.command == "QRCODE" && c.param == 2:
+		if doc, ok := eccLevels[strings.ToUpper(c.value)]; ok {
+			text += "\n\n`" + strings.ToUpper(c.value) + "`: " + doc
+		}
+	}
+	r := lineRange([]string{line}, 0, c.start, c.end)
+	r.Start.Line, r.End.Line = p.Position.Line, p.Position.Line
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &r}
+}
+
+// completion 依游標位置補全命令名稱、字型、條碼類型、糾錯等級與已定義的名稱
+func (s *Server) completion(p positionParams) []CompletionItem {
+	line, offset, ok := s.lineAt(p)
+	if !ok {
+		return []CompletionItem{}
+	}
+	c, ok := locate(line, offset)
+	if !ok {
+		return []CompletionItem{}
+	}
+	items := []CompletionItem{}
+	if c.param < 0 {
+		names := make([]string, 0, len(commandDocs))
+		for name := range commandDocs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			doc := commandDocs[name]
+			items = append(items, CompletionItem{Label: name, Kind: completionKeyword, Detail: doc.Syntax, Documentation: doc.Summary})
+		}
+		return items
+	}
+
+	// 游標前已有左引號時只插入名稱, 否則連同引號插入
+	quoted := offset > c.start && line[c.start] == '"'
+	values := func(m map[string]string, quote bool) {
+		for _, name := range sortedKeys(m) {
+			item := CompletionItem{Label: name, Kind: completionConstant, Detail: m[name]}
+			if quote && !quoted {
+				item.InsertText = `"` + name + `"`
+			}
+			items = append(items, item)
+		}
+	}
+	switch {
+	case c.command == "TEXT" && c.param == 2:
+		values(fontNames, true)
+	case c.command == "BARCODE" && c.param == 2:
+		values(barcodeTypes, true)
+	case c.command == "QRCODE" && c.param == 2:
+		values(eccLevels, false)
+	case c.command == "QRCODE" && c.param == 4:
+		values(map[string]string{"A": "自動編碼", "M": "手動編碼"}, false)
+	default:
+		kind := referenceKind(c.command, c.param)
+		if kind == "" {
+			return items
+		}
+		defined := map[string]string{}
+		for _, uri := range s.order {
+			for _, sym := range symbols(s.docs[uri]) {
+				if sym.kind == kind {
+					defined[sym.name] = fmt.Sprintf("%s 第 %d 行", uri, sym.line+1)
+				}
+			}
+		}
+		values(defined, kind == "download")
+	}
+	return items
+}
+
+// definition 跳至 PUTBMP/PUTPCX 檔名的 DOWNLOAD
+// 先搜尋目前文件, 再依開啟順序搜尋其他文件
+func (s *Server) definition(p positionParams) []Location {
+	line, offset, ok := s.lineAt(p)
+	if !ok {
+		return nil
+	}
+	c, ok := locate(line, offset)
+	if !ok {
+		return nil
+	}
+	kind := referenceKind(c.command, c.param)
+	if kind == "" || c.value == "" {
+		return nil
+	}
+
+	uris := append([]string{p.TextDocument.URI}, s.order...)
+	seen := map[string]bool{}
+	for _, uri := range uris {
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		text, ok := s.docs[uri]
+		if !ok {
+			continue
+		}
+		lines := splitLines(text)
+		var found []Location
+		for _, sym := range symbols(text) {
+			if sym.kind == kind && sameName(sym.name, c.value) {
+				found = append(found, Location{URI: uri, Range: lineRange(lines, sym.line, sym.span[0], sym.span[1])})
+			}
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
@@ -7,10 +7,14 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"tspl-simulator/api"
 	"tspl-simulator/config"
+	"tspl-simulator/imagestore"
 	"tspl-simulator/mqtt"
+	"tspl-simulator/parser"
+	"tspl-simulator/search"
 	"tspl-simulator/storage"
 )
 
@@ -19,14 +23,53 @@ func main() {
 	cfg := config.LoadConfig()
 
 	// 初始化儲存服務
-	storagePath := getEnv("STORAGE_PATH", "./data")
-	storageService := storage.NewStorageService(storagePath)
-	api.InitStorage(storagePath)
-	log.Printf("儲存服務已初始化,資料路徑: %s", storagePath)
+	storageService, err := newStorageService(cfg)
+	if err != nil {
+		log.Fatalf("儲存服務初始化失敗: %v", err)
+	}
+	defer storageService.Close()
+	api.InitStorage(storageService)
+
+	// 全文搜尋索引: 背景由儲存的工作重建, 之後隨儲存與刪除更新
+	searchIndex := search.NewIndex()
+	storageService.AddListener(searchIndex)
+	api.InitSearchIndex(searchIndex)
+	go func() {
+		start := time.Now()
+		if err := searchIndex.Rebuild(storageService); err != nil {
+			log.Printf("警告: 搜尋索引重建失敗: %v", err)
+			return
+		}
+		log.Printf("搜尋索引已重建: %d 筆工作 (%s)", searchIndex.Len(), time.Since(start).Round(time.Millisecond))
+	}()
+	log.Printf("儲存服務已初始化,後端: %s", cfg.StorageBackend)
+
+	// 保留規則 (背景清理過期工作與壓縮舊資料夾)
+	janitor := storage.NewJanitor(storageService, storage.RetentionConfig{
+		Policies: map[string]storage.RetentionPolicy{
+			"api":  {MaxAge: cfg.RetentionAPIMaxAge, MaxJobs: cfg.RetentionAPIMaxJobs, MaxBytes: cfg.RetentionAPIMaxBytes},
+			"mqtt": {MaxAge: cfg.RetentionMQTTMaxAge, MaxJobs: cfg.RetentionMQTTMaxJobs, MaxBytes: cfg.RetentionMQTTMaxBytes},
+		},
+		CompressAfter: cfg.RetentionCompressAfter,
+		Interval:      cfg.RetentionInterval,
+	})
+	api.InitJanitor(janitor)
+	if janitor.Enabled() {
+		janitor.Start()
+		defer janitor.Stop()
+		log.Println("保留規則已啟用")
+	}
+
+	// 初始化模擬打印機記憶體 (DOWNLOAD/PUTBMP/PUTPCX 使用)
+	imageStore := imagestore.NewStoreWithLimits(cfg.ImageStoreMaxBytes, cfg.ImageStoreMaxFiles)
+	parser.SetFileStore(imageStore)
+	api.InitImageStore(imageStore)
+
+	// 重送 API 允許的打印機位址
+	api.InitReplay(cfg.ReplayPrinters)
 
 	// 初始化 MQTT 客戶端 (可選)
 	var mqttClient *mqtt.Client
-	var err error
 
 	if cfg.MQTTBroker != "" && cfg.MQTTBroker != "localhost" {
 		mqttClient, err = mqtt.NewClient(cfg)
@@ -49,8 +92,10 @@ func main() {
 	serverAddr := fmt.Sprintf("0.0.0.0:%s", cfg.ServerPort)
 	log.Printf("TSPL Simulator 服務器啟動於 %s", serverAddr)
 	log.Printf("API 端點: http://localhost:%s/api", cfg.ServerPort)
-	log.Printf("API 資料儲存: %s", filepath.Join(storagePath, "API_print"))
-	log.Printf("MQTT 資料儲存: %s", filepath.Join(storagePath, "MQTT_print"))
+	if cfg.StorageBackend == "fs" {
+		log.Printf("API 資料儲存: %s", filepath.Join(cfg.StoragePath, "API_print"))
+		log.Printf("MQTT 資料儲存: %s", filepath.Join(cfg.StoragePath, "MQTT_print"))
+	}
 
 	// 優雅關閉
 	go func() {
@@ -67,10 +112,14 @@ func main() {
 	log.Println("正在關閉服務器...")
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
+// newStorageService 依 STORAGE_BACKEND 建立儲存服務
+func newStorageService(cfg *config.Config) (*storage.StorageService, error) {
+	return storage.Open(cfg.StorageBackend, cfg.StoragePath, storage.S3Config{
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		Prefix:    cfg.S3Prefix,
+		AccessKey: cfg.S3AccessKey,
+		SecretKey: cfg.S3SecretKey,
+	})
 }
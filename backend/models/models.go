@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // RenderRequest 渲染請求
 type RenderRequest struct {
 	TSPLCode string `json:"tspl_code" binding:"required"`
@@ -7,29 +9,54 @@ type RenderRequest struct {
 
 // RenderResponse 渲染回應
 type RenderResponse struct {
-	Success          bool              `json:"success"`
-	Data             *RenderData       `json:"data,omitempty"`
-	Error            string            `json:"error,omitempty"`
-	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+	Success            bool              `json:"success"`
+	Data               *RenderData       `json:"data,omitempty"`
+	Error              string            `json:"error,omitempty"`
+	ValidationErrors   []ValidationError `json:"validation_errors,omitempty"`
+	ValidationWarnings []ValidationError `json:"validation_warnings,omitempty"`
+	QRCapacity         []QRCapacity      `json:"qr_capacity,omitempty"`
+	Verification       []ScanResult      `json:"verification,omitempty"`
 }
 
 // ValidationError 驗證錯誤
 type ValidationError struct {
 	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"` // 錯誤在該行的位置 (從 1 開始)
 	Command string `json:"command"`
+	AI      string `json:"ai,omitempty"` // GS1 應用識別碼
 	Message string `json:"message"`
 }
 
+// QRCapacity QR Code 資料容量與實際尺寸, 與元素 x/y 到標籤邊緣的剩餘空間比較
+type QRCapacity struct {
+	Line              int     `json:"line"`
+	ECC               string  `json:"ecc"`
+	Mode              string  `json:"mode"`    // numeric / alphanumeric / byte
+	Version           int     `json:"version"` // 所需最小版本, 0 代表超出版本 40
+	Modules           int     `json:"modules"` // 每邊模組數
+	CellSize          int     `json:"cell_size"`
+	SizeDots          int     `json:"size_dots"`
+	SizeMM            float64 `json:"size_mm"`
+	AvailableWidth    int     `json:"available_width"` // 剩餘空間 (點)
+	AvailableHeight   int     `json:"available_height"`
+	AvailableWidthMM  float64 `json:"available_width_mm"`
+	AvailableHeightMM float64 `json:"available_height_mm"`
+	Fits              bool    `json:"fits"`
+}
+
 // RenderData 渲染資料
 type RenderData struct {
-	Width      int            `json:"width"`
-	Height     int            `json:"height"`
-	Elements   []Element      `json:"elements"`
-	LabelSize  LabelSize      `json:"labelSize"`
-	Gap        Gap            `json:"gap"`
-	Direction  int            `json:"direction"`
-	Reference  Reference      `json:"reference"`
-	DPI        int            `json:"dpi"`
+	Width     int           `json:"width"`
+	Height    int           `json:"height"`
+	Elements  []Element     `json:"elements"`
+	LabelSize LabelSize     `json:"labelSize"`
+	Gap       Gap           `json:"gap"`
+	Direction int           `json:"direction"`
+	Reference Reference     `json:"reference"`
+	DPI       int           `json:"dpi"`
+	Media     Media         `json:"media"`
+	Print     PrintSettings `json:"print"`
+	Steps     []JobStep     `json:"steps,omitempty"`
 }
 
 // Element 渲染元素
@@ -54,12 +81,116 @@ type Gap struct {
 	Unit     string  `json:"unit"`
 }
 
+// Media 媒體 (標籤紙) 設定
+type Media struct {
+	Type     string  `json:"type"`     // gap (間隙紙), bline (黑標紙), continuous (連續紙)
+	Distance float64 `json:"distance"` // 間隙或黑標高度
+	Offset   float64 `json:"offset"`   // 間隙偏移或黑標額外進紙長度
+	Unit     string  `json:"unit"`
+}
+
+// PrintSettings 影響熱感列印品質的打印機設定
+type PrintSettings struct {
+	Density int     `json:"density"` // DENSITY 濃度 0-15
+	Speed   float64 `json:"speed"`   // SPEED 英吋/秒
+	Ribbon  bool    `json:"ribbon"`  // SET RIBBON ON: 熱轉印 (碳帶), 否則為感熱紙直接列印
+}
+
+// JobStep 列印工作中影響進紙的步驟 (依命令順序)
+type JobStep struct {
+	Command      string `json:"command"`          // PRINT, FORMFEED, BACKFEED, HOME, LIMITFEED, CLS
+	Line         int    `json:"line"`             // 原始 TSPL 行號
+	Dots         int    `json:"dots,omitempty"`   // BACKFEED 退紙點數或 LIMITFEED 上限點數
+	Sets         int    `json:"sets,omitempty"`   // PRINT 組數
+	Copies       int    `json:"copies,omitempty"` // PRINT 每組份數
+	ElementStart int    `json:"elementStart"`     // PRINT 時緩衝區的第一個元素索引
+	ElementEnd   int    `json:"elementEnd"`       // PRINT 時緩衝區的最後一個元素索引 (不含)
+}
+
 // Reference 參考點
 type Reference struct {
 	X int `json:"x"`
 	Y int `json:"y"`
 }
 
+// LabelPlacement 標籤在模擬紙捲上的落點
+type LabelPlacement struct {
+	Index      int  `json:"index"`      // 第幾張輸出 (從 1 開始)
+	Line       int  `json:"line"`       // 對應的 PRINT 行號
+	Y          int  `json:"y"`          // 列印起點在紙捲上的位置 (點)
+	MediaLabel int  `json:"mediaLabel"` // 落在紙捲上的第幾張標籤 (從 1 開始)
+	Aligned    bool `json:"aligned"`    // 列印起點是否對齊標籤起點
+	CrossesGap bool `json:"crossesGap"` // 列印範圍是否跨越間隙或黑標
+}
+
+// StripResponse 紙捲模擬回應
+type StripResponse struct {
+	Success          bool              `json:"success"`
+	Image            string            `json:"image,omitempty"` // PNG data URL
+	Width            int               `json:"width,omitempty"`
+	Height           int               `json:"height,omitempty"`
+	Pitch            int               `json:"pitch,omitempty"` // 標籤間距 (點)
+	Placements       []LabelPlacement  `json:"placements,omitempty"`
+	Warnings         []string          `json:"warnings,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+}
+
+// BarcodeQuality 單一條碼在模擬列印後的窄條/窄空量測
+type BarcodeQuality struct {
+	Element     int     `json:"element"`     // 元素索引 (從 1 開始)
+	Type        string  `json:"type"`        // 條碼類型
+	Orientation string  `json:"orientation"` // picket (條與走紙方向平行) 或 ladder (條與列印頭平行)
+	Narrow      int     `json:"narrow"`      // 設計的窄條寬度 (點)
+	NarrowBar   float64 `json:"narrowBar"`   // 模擬列印後窄條平均寬度 (點)
+	NarrowSpace float64 `json:"narrowSpace"` // 模擬列印後窄空平均寬度 (點)
+	LostBars    int     `json:"lostBars"`    // 完全消失的條數
+	FilledGaps  int     `json:"filledGaps"`  // 被暈開填滿的空白數
+	Status      string  `json:"status"`      // ok, bleed (暈開), dropout (斷條)
+}
+
+// QualityResponse 熱感列印品質模擬回應
+type QualityResponse struct {
+	Success          bool              `json:"success"`
+	Image            string            `json:"image,omitempty"` // 灰階 PNG data URL
+	Print            *PrintSettings    `json:"print,omitempty"`
+	Energy           float64           `json:"energy,omitempty"` // 相對加熱能量 (1.0 為預設濃度/速度)
+	DotGain          float64           `json:"dotGain"`          // 印出面積相對設計面積的增減比例
+	Dropouts         int               `json:"dropouts"`         // 應印卻未印出的點數
+	Barcodes         []BarcodeQuality  `json:"barcodes,omitempty"`
+	Warnings         []string          `json:"warnings,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+}
+
+// ScanResult 條碼/QR Code 掃描驗證結果, 等級為依 ISO/IEC 15416 (一維) 與 15415 (QR) 的估計值
+type ScanResult struct {
+	Element         int               `json:"element"`   // 元素索引 (從 1 開始)
+	Type            string            `json:"type"`      // barcode 或 qrcode
+	Symbology       string            `json:"symbology"` // TSPL 條碼類型或 QR
+	Expected        string            `json:"expected"`  // 元素的 code/data 屬性
+	Decoded         string            `json:"decoded,omitempty"`
+	Match           bool              `json:"match"`                     // 解碼結果與屬性相符
+	Grade           string            `json:"grade"`                     // 整體等級 A-F
+	Score           float64           `json:"score"`                     // 整體分數 0-4
+	Grades          map[string]string `json:"grades,omitempty"`          // 各參數等級
+	XDimension      float64           `json:"xDimension"`                // 模組寬度 (mm)
+	XDots           float64           `json:"xDots"`                     // 模組寬度 (點)
+	QuietZoneLeft   float64           `json:"quietZoneLeft,omitempty"`   // 左側靜區 (模組數, 一維)
+	QuietZoneRight  float64           `json:"quietZoneRight,omitempty"`  // 右側靜區 (模組數, 一維)
+	QuietZone       float64           `json:"quietZone,omitempty"`       // 四周最小靜區 (模組數, QR)
+	Decodability    float64           `json:"decodability,omitempty"`    // 可解碼度 0-1 (一維)
+	BarGain         float64           `json:"barGain"`                   // 條寬平均偏差 (mm, 正值為變粗)
+	WideNarrowRatio float64           `json:"wideNarrowRatio,omitempty"` // 寬窄比 (兩種寬度的條碼)
+	Scans           int               `json:"scans,omitempty"`           // 掃描線數 (一維)
+	ScansPassed     int               `json:"scansPassed,omitempty"`     // 解碼成功且相符的掃描線數
+	Version         int               `json:"version,omitempty"`         // QR 版本
+	ECC             string            `json:"ecc,omitempty"`             // QR 糾錯等級
+	Corrected       int               `json:"corrected,omitempty"`       // QR 修正的碼字數
+	UnusedECC       float64           `json:"unusedEcc,omitempty"`       // QR 未使用的糾錯能力 0-1
+	Messages        []string          `json:"messages,omitempty"`
+}
+
 // ExampleInfo 範例資訊
 type ExampleInfo struct {
 	ID          string `json:"id"`
@@ -87,6 +218,8 @@ type MQTTMessage struct {
 	Type      string `json:"type"`
 	TSPLCode  string `json:"tspl_code,omitempty"`
 	Timestamp int64  `json:"timestamp"`
+	ClientID  string `json:"client_id,omitempty"`  // 發送端用戶端 ID (記錄於工作中繼資料)
+	RequestID string `json:"request_id,omitempty"` // 發送端的請求 ID (記錄於工作中繼資料)
 }
 
 // HealthResponse 健康檢查回應
@@ -94,3 +227,319 @@ type HealthResponse struct {
 	Status string `json:"status"`
 	MQTT   string `json:"mqtt"`
 }
+
+// FormatResponse 格式化回應
+type FormatResponse struct {
+	Success   bool   `json:"success"`
+	Formatted string `json:"formatted,omitempty"`
+	Changed   bool   `json:"changed"` // 整理後的內容是否與原始碼不同
+	Error     string `json:"error,omitempty"`
+}
+
+// ConvertRequest 標籤語言轉換請求
+type ConvertRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ConversionIssue 轉換時不支援或有落差的內容
+type ConversionIssue struct {
+	Line    int    `json:"line,omitempty"` // 來源的行號 (從 1 開始)
+	Command string `json:"command"`
+	Message string `json:"message"`
+}
+
+// ConvertResponse 標籤語言轉換回應
+type ConvertResponse struct {
+	Success     bool              `json:"success"`
+	From        string            `json:"from,omitempty"`
+	To          string            `json:"to,omitempty"`
+	Output      string            `json:"output,omitempty"`
+	Unsupported []ConversionIssue `json:"unsupported,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// Job 儲存的列印工作
+type Job struct {
+	ID        string       `json:"id"`
+	Source    string       `json:"source"`   // api / mqtt
+	Rejected  bool         `json:"rejected"` // 驗證失敗的工作
+	CreatedAt time.Time    `json:"created_at"`
+	Size      int64        `json:"size"`           // TSPL 原始碼位元組數
+	Hash      string       `json:"hash,omitempty"` // TSPL 內容的 SHA-256
+	Metadata  *JobMetadata `json:"metadata,omitempty"`
+}
+
+// JobListResponse 列印工作列表回應
+type JobListResponse struct {
+	Success  bool   `json:"success"`
+	Jobs     []Job  `json:"jobs"`
+	Total    int    `json:"total"` // 符合條件的工作總數 (分頁前)
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Error    string `json:"error,omitempty"`
+}
+
+// JobDetailResponse 列印工作詳情回應: 原始 TSPL 與重新解析的渲染資料
+type JobDetailResponse struct {
+	Success            bool              `json:"success"`
+	Job                *Job              `json:"job,omitempty"`
+	TSPLCode           string            `json:"tspl_code,omitempty"`
+	Data               *RenderData       `json:"data,omitempty"`
+	ValidationErrors   []ValidationError `json:"validation_errors,omitempty"`
+	ValidationWarnings []ValidationError `json:"validation_warnings,omitempty"`
+	Error              string            `json:"error,omitempty"`
+}
+
+// JobMetadata 列印工作的中繼資料, 以 JSON 附檔與 TSPL 一同儲存
+type JobMetadata struct {
+	Source             string            `json:"source"`                   // api / mqtt
+	RemoteAddr         string            `json:"remote_addr,omitempty"`    // API 用戶端位址
+	MQTTClientID       string            `json:"mqtt_client_id,omitempty"` // MQTT 訊息中發送端自報的用戶端 ID
+	MQTTTopic          string            `json:"mqtt_topic,omitempty"`
+	RequestID          string            `json:"request_id,omitempty"`
+	Valid              bool              `json:"valid"`
+	ValidationErrors   []ValidationError `json:"validation_errors,omitempty"`
+	ValidationWarnings []ValidationError `json:"validation_warnings,omitempty"`
+	ParseError         string            `json:"parse_error,omitempty"`
+	ParseDurationMS    float64           `json:"parse_duration_ms"`
+	ElementCounts      map[string]int    `json:"element_counts,omitempty"` // 依元素類型統計
+	Printer            *PrinterProfile   `json:"printer,omitempty"`
+}
+
+// PrinterProfile 工作解析出的打印機與標籤設定
+type PrinterProfile struct {
+	DPI       int           `json:"dpi"`
+	Width     int           `json:"width"`  // 標籤寬度 (點)
+	Height    int           `json:"height"` // 標籤高度 (點)
+	LabelSize LabelSize     `json:"labelSize"`
+	Media     Media         `json:"media"`
+	Direction int           `json:"direction"`
+	Reference Reference     `json:"reference"`
+	Print     PrintSettings `json:"print"`
+}
+
+// RetentionReport 保留規則的執行 (或試算) 結果
+type RetentionReport struct {
+	DryRun      bool                    `json:"dry_run"`
+	StartedAt   time.Time               `json:"started_at"`
+	Sources     []RetentionSourceReport `json:"sources"`
+	Compression *CompressionReport      `json:"compression,omitempty"` // 未設定壓縮或儲存後端不支援時省略
+	Errors      []string                `json:"errors,omitempty"`
+}
+
+// RetentionSourceReport 單一來源 (含驗證失敗的工作) 的保留結果; 限制為 0 代表不限
+type RetentionSourceReport struct {
+	Source       string       `json:"source"` // api / mqtt
+	MaxAge       string       `json:"max_age,omitempty"`
+	MaxJobs      int          `json:"max_jobs,omitempty"`
+	MaxBytes     int64        `json:"max_bytes,omitempty"`
+	Jobs         int          `json:"jobs"`  // 執行前的工作數
+	Bytes        int64        `json:"bytes"` // 執行前的 TSPL 總位元組數
+	ExpiredJobs  int          `json:"expired_jobs"`
+	ExpiredBytes int64        `json:"expired_bytes"`
+	Expired      []ExpiredJob `json:"expired,omitempty"` // 過期的工作 (最多列出 1000 筆)
+}
+
+// ExpiredJob 超出保留規則的工作
+type ExpiredJob struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+	Reason    string    `json:"reason"` // max_age / max_jobs / max_bytes
+}
+
+// CompressionReport 舊日期資料夾的壓縮結果
+type CompressionReport struct {
+	Before          time.Time `json:"before"`  // 壓縮整天都早於此時間的資料夾
+	Folders         []string  `json:"folders"` // 資料夾/年_月_日
+	Files           int       `json:"files"`
+	Bytes           int64     `json:"bytes"`                      // 壓縮前的大小
+	CompressedBytes int64     `json:"compressed_bytes,omitempty"` // 壓縮後的大小 (試算時省略)
+}
+
+// RetentionResponse 保留規則試算回應
+type RetentionResponse struct {
+	Success bool             `json:"success"`
+	Report  *RetentionReport `json:"report,omitempty"`   // 目前規則的試算結果
+	LastRun *RetentionReport `json:"last_run,omitempty"` // 背景清理最近一次的執行結果
+	Error   string           `json:"error,omitempty"`
+}
+
+// JobSearchMatch 搜尋命中的位置
+type JobSearchMatch struct {
+	Line    int    `json:"line"`
+	Command string `json:"command"` // TSPL 指令, 註解行為空字串
+	Field   string `json:"field"`   // text / code / data (解析出的元素屬性) 或 line (原始行)
+	Value   string `json:"value"`
+}
+
+// JobSearchResult 一筆命中的列印工作
+type JobSearchResult struct {
+	Job     Job              `json:"job"`
+	Matches []JobSearchMatch `json:"matches"`
+}
+
+// JobSearchResponse 列印工作搜尋回應
+type JobSearchResponse struct {
+	Success    bool              `json:"success"`
+	Query      string            `json:"query"`
+	Results    []JobSearchResult `json:"results"`
+	Total      int               `json:"total"` // 符合條件的工作總數 (分頁前)
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	Rebuilding bool              `json:"rebuilding,omitempty"` // 啟動時的索引重建尚未完成, 結果可能不完整
+	Error      string            `json:"error,omitempty"`
+}
+
+// ReplayRequest 重送列印工作的請求
+// 單筆重送只使用 target、topic、printer; 批次重送再依 source、status、from、to 選取工作,
+// 預設只選取驗證通過的工作, include_rejected 為 true 時才包含驗證失敗的工作
+type ReplayRequest struct {
+	Target  string `json:"target"`            // render (預設) / mqtt / printer
+	Topic   string `json:"topic,omitempty"`   // mqtt: 發布的主題, 預設為訂閱的指令主題
+	Printer string `json:"printer,omitempty"` // printer: 打印機位址 host[:port], 預設埠 9100
+	Source  string `json:"source,omitempty"`  // api / mqtt
+	Status  string `json:"status,omitempty"`  // accepted / rejected
+	From    string `json:"from,omitempty"`    // YYYY-MM-DD 或 RFC 3339
+	To      string `json:"to,omitempty"`
+	Limit   int    `json:"limit,omitempty"` // 批次重送的工作數上限
+
+	IncludeRejected bool `json:"include_rejected,omitempty"` // 批次重送是否包含驗證失敗的工作
+}
+
+// ReplayRender 以目前的解析與渲染流程重新處理工作的結果
+type ReplayRender struct {
+	Metadata       *JobMetadata `json:"metadata"`                  // 重新驗證與解析的結果
+	RenderWarnings []string     `json:"render_warnings,omitempty"` // 渲染時的警告
+	ScanFailures   int          `json:"scan_failures"`             // 條碼 / QR 碼掃描驗證不符的數量
+	Changes        []string     `json:"changes,omitempty"`         // 與原本儲存的結果不同之處
+}
+
+// ReplayResult 單一工作的重送結果
+type ReplayResult struct {
+	JobID      string        `json:"job_id"`
+	Target     string        `json:"target"`
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+	DurationMS float64       `json:"duration_ms"`
+	Topic      string        `json:"topic,omitempty"`      // mqtt
+	Printer    string        `json:"printer,omitempty"`    // printer
+	BytesSent  int           `json:"bytes_sent,omitempty"` // printer
+	Render     *ReplayRender `json:"render,omitempty"`     // render
+}
+
+// ReplayResponse 重送回應
+type ReplayResponse struct {
+	Success   bool           `json:"success"`
+	Results   []ReplayResult `json:"results"`
+	Total     int            `json:"total"`               // 符合條件的工作數
+	Succeeded int            `json:"succeeded"`           // 重送成功的數量
+	Failed    int            `json:"failed"`              // 重送失敗的數量
+	Changed   int            `json:"changed"`             // render: 結果與原本不同的數量
+	Truncated bool           `json:"truncated,omitempty"` // 符合的工作超過上限, 只重送最早的部分
+	Error     string         `json:"error,omitempty"`
+}
+
+// JobArchiveManifest 工作封存檔的目錄 (manifest.json)
+type JobArchiveManifest struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Jobs       []JobArchiveEntry `json:"jobs"`
+}
+
+// JobArchiveEntry 封存檔中的一筆工作 (含中繼資料) 與其檔案
+type JobArchiveEntry struct {
+	Job
+	TSPL string `json:"tspl"`          // TSPL 原始碼在封存檔中的路徑
+	PNG  string `json:"png,omitempty"` // 繪製的預覽圖, 驗證失敗或無法解析的工作沒有
+}
+
+// JobImportResult 封存檔中一筆工作的匯入結果
+type JobImportResult struct {
+	Entry  string `json:"entry"`            // 封存檔中的工作 ID 或檔案路徑
+	Status string `json:"status"`           // imported / duplicate / failed
+	JobID  string `json:"job_id,omitempty"` // 匯入的工作 ID, 重複時為既有工作的 ID
+	Error  string `json:"error,omitempty"`
+}
+
+// JobImportResponse 工作封存檔匯入回應
+type JobImportResponse struct {
+	Success    bool              `json:"success"`
+	Imported   int               `json:"imported"`
+	Duplicates int               `json:"duplicates"` // TSPL 內容已存在而略過的數量
+	Failed     int               `json:"failed"`
+	Results    []JobImportResult `json:"results"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// DiffRequest 比較兩份標籤的請求
+type DiffRequest struct {
+	Before DiffSource `json:"before"`
+	After  DiffSource `json:"after"`
+}
+
+// DiffSource 比較的一側: TSPL 原始碼或儲存的列印工作 ID (擇一)
+type DiffSource struct {
+	TSPLCode string `json:"tspl_code,omitempty"`
+	JobID    string `json:"job_id,omitempty"`
+}
+
+// PropertyChange 屬性或標籤設定的變更, 不存在的一側為 null
+type PropertyChange struct {
+	Property string      `json:"property"`
+	Before   interface{} `json:"before"`
+	After    interface{} `json:"after"`
+}
+
+// ElementDiff 元素的差異
+type ElementDiff struct {
+	Status      string           `json:"status"` // added / removed / moved (只有位置不同) / changed
+	Type        string           `json:"type"`
+	Key         string           `json:"key,omitempty"`         // 元素內容 (text/code/data/file), 過長時截斷
+	BeforeIndex int              `json:"beforeIndex,omitempty"` // 在 before 中的元素索引 (從 1 開始)
+	AfterIndex  int              `json:"afterIndex,omitempty"`  // 在 after 中的元素索引 (從 1 開始)
+	From        *Reference       `json:"from,omitempty"`        // before 中的位置
+	To          *Reference       `json:"to,omitempty"`          // after 中的位置
+	Changes     []PropertyChange `json:"changes,omitempty"`     // 位置以外不同的屬性
+}
+
+// DiffSummary 元素差異統計
+type DiffSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Moved     int `json:"moved"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// PixelDiff 點陣差異
+type PixelDiff struct {
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	Added     int        `json:"added"`            // 只有 after 印出的點
+	Removed   int        `json:"removed"`          // 只有 before 印出的點
+	Unchanged int        `json:"unchanged"`        // 兩側都印出的點
+	Bounds    *DiffBound `json:"bounds,omitempty"` // 有差異的點的範圍
+	Image     string     `json:"image,omitempty"`  // PNG data URL: 相同的點為灰色, 移除的為紅色, 新增的為綠色
+}
+
+// DiffBound 點陣範圍 (點)
+type DiffBound struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// DiffResponse 標籤比較回應
+type DiffResponse struct {
+	Success          bool              `json:"success"`
+	Identical        bool              `json:"identical"` // 元素、標籤設定與點陣都相同
+	Summary          *DiffSummary      `json:"summary,omitempty"`
+	Elements         []ElementDiff     `json:"elements,omitempty"` // 只列出有差異的元素
+	Settings         []PropertyChange  `json:"settings,omitempty"` // 標籤尺寸、方向、參考點等設定的變更
+	Pixels           *PixelDiff        `json:"pixels,omitempty"`
+	Side             string            `json:"side,omitempty"` // 發生錯誤的一側 (before / after)
+	Error            string            `json:"error,omitempty"`
+	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+}
@@ -31,18 +31,27 @@ func SetStorageService(s *storage.StorageService) {
 	}
 }
 
-// NewClient 建立新的 MQTT 客戶端
-func NewClient(cfg *config.Config) (*Client, error) {
+// clientOptions 依配置建立連線選項
+func clientOptions(cfg *config.Config, clientID string) *mqtt.ClientOptions {
 	opts := mqtt.NewClientOptions()
-	brokerURL := fmt.Sprintf("tcp://%s:%s", cfg.MQTTBroker, cfg.MQTTPort)
-	opts.AddBroker(brokerURL)
-	opts.SetClientID(cfg.MQTTClientID)
+	opts.AddBroker(brokerURL(cfg))
+	opts.SetClientID(clientID)
 
 	if cfg.MQTTUsername != "" {
 		opts.SetUsername(cfg.MQTTUsername)
 		opts.SetPassword(cfg.MQTTPassword)
 	}
+	return opts
+}
+
+// brokerURL MQTT 代理的連線位址
+func brokerURL(cfg *config.Config) string {
+	return fmt.Sprintf("tcp://%s:%s", cfg.MQTTBroker, cfg.MQTTPort)
+}
 
+// NewClient 建立新的 MQTT 客戶端
+func NewClient(cfg *config.Config) (*Client, error) {
+	opts := clientOptions(cfg, cfg.MQTTClientID)
 	opts.SetDefaultPublishHandler(messageHandler)
 	opts.OnConnect = connectHandler
 	opts.OnConnectionLost = connectionLostHandler
@@ -66,11 +75,20 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		return nil, fmt.Errorf("MQTT 訂閱失敗: %v", token.Error())
 	}
 
-	log.Printf("MQTT 客戶端已連接到 %s 並訂閱主題 %s", brokerURL, cfg.MQTTTopic)
+	log.Printf("MQTT 客戶端已連接到 %s 並訂閱主題 %s", brokerURL(cfg), cfg.MQTTTopic)
 
 	return mqttClient, nil
 }
 
+// NewPublisher 建立只用於發布的 MQTT 客戶端 (不訂閱主題, 不取代全域客戶端), 供命令列工具使用
+func NewPublisher(cfg *config.Config) (*Client, error) {
+	client := mqtt.NewClient(clientOptions(cfg, cfg.MQTTClientID+"-replay"))
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("MQTT 連接失敗: %v", token.Error())
+	}
+	return &Client{client: client, config: cfg}, nil
+}
+
 // GetClient 取得 MQTT 客戶端實例
 func GetClient() *Client {
 	return mqttClient
@@ -118,6 +136,11 @@ func (c *Client) Close() {
 	}
 }
 
+// Topic 訂閱的指令主題 (發送 render_request 的預設主題)
+func (c *Client) Topic() string {
+	return c.config.MQTTTopic
+}
+
 // IsConnected 檢查 MQTT 是否已連接
 func (c *Client) IsConnected() bool {
 	return c.client.IsConnected()
@@ -136,15 +159,16 @@ var messageHandler mqtt.MessageHandler = func(client mqtt.Client, msg mqtt.Messa
 	// 處理不同類型的訊息
 	switch message.Type {
 	case "render_request":
-		handleRenderRequest(message.TSPLCode)
+		handleRenderRequest(message, msg.Topic())
 	default:
 		log.Printf("未知的訊息類型: %s", message.Type)
 	}
 }
 
 // 處理渲染請求
-func handleRenderRequest(tsplCode string) {
+func handleRenderRequest(message models.MQTTMessage, topic string) {
 	log.Printf("處理 MQTT 渲染請求")
+	tsplCode := message.TSPLCode
 
 	// 驗證 TSPL 語法
 	validationResult := validator.ValidateTSPL(tsplCode)
@@ -153,19 +177,45 @@ func handleRenderRequest(tsplCode string) {
 		for _, err := range validationResult.Errors {
 			log.Printf("  行 %d [%s]: %s", err.Line, err.Command, err.Message)
 		}
+		// 驗證失敗的資料另存, 以便重現問題
+		if mqttClient != nil && mqttClient.storageService != nil {
+			meta := storage.NewJobMetadata("mqtt", validationResult, nil, 0, nil)
+			meta.MQTTClientID = message.ClientID
+			meta.MQTTTopic = topic
+			meta.RequestID = message.RequestID
+			if filePath, err := mqttClient.storageService.SaveRejectedMQTTData(tsplCode, meta); err != nil {
+				log.Printf("儲存驗證失敗的 MQTT 資料失敗: %v", err)
+			} else {
+				log.Printf("驗證失敗的 MQTT 資料已儲存至: %s", filePath)
+			}
+		}
 		return
 	}
+	for _, warning := range validationResult.Warnings {
+		log.Printf("TSPL 警告: 行 %d [%s]: %s", warning.Line, warning.Command, warning.Message)
+	}
+
+	start := time.Now()
+	renderData, err := parser.ParseTSPL(tsplCode)
+	parseDuration := time.Since(start)
 
-	// 儲存 MQTT 接收的資料
+	// 儲存 MQTT 接收的資料與中繼資料; 解析失敗的資料與驗證失敗的一樣另存
 	if mqttClient != nil && mqttClient.storageService != nil {
-		if filePath, err := mqttClient.storageService.SaveMQTTData(tsplCode); err != nil {
+		meta := storage.NewJobMetadata("mqtt", validationResult, renderData, parseDuration, err)
+		meta.MQTTClientID = message.ClientID
+		meta.MQTTTopic = topic
+		meta.RequestID = message.RequestID
+		save := mqttClient.storageService.SaveMQTTData
+		if err != nil {
+			save = mqttClient.storageService.SaveRejectedMQTTData
+		}
+		if filePath, err := save(tsplCode, meta); err != nil {
 			log.Printf("儲存 MQTT 資料失敗: %v", err)
 		} else {
 			log.Printf("MQTT 資料已儲存至: %s", filePath)
 		}
 	}
 
-	renderData, err := parser.ParseTSPL(tsplCode)
 	if err != nil {
 		log.Printf("解析 TSPL 失敗: %v", err)
 		return
@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tspl-simulator/imagestore"
+	"tspl-simulator/models"
+)
+
+// FileStore 模擬打印機記憶體的檔案來源 (DOWNLOAD 寫入, PUTBMP/PUTPCX 讀取)
+type FileStore interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, bool)
+}
+
+var fileStore FileStore
+
+// MaxDownloadSize DOWNLOAD 單一檔案的大小上限 (位元組)
+const MaxDownloadSize = 16 << 20
+
+// SetFileStore 設定打印機記憶體檔案來源
+func SetFileStore(s FileStore) {
+	fileStore = s
+}
+
+// scratchStore 暫存的檔案來源: 寫入只保留在本身, 讀取時先查本身再查打印機記憶體
+type scratchStore struct {
+	files *imagestore.Store
+	names []string // 寫入的順序
+	base  FileStore
+}
+
+// NewScratchStore 建立以目前打印機記憶體為底的暫存檔案來源, 解析時的 DOWNLOAD 不會寫入打印機記憶體
+func NewScratchStore() FileStore {
+	return &scratchStore{files: imagestore.NewStore(), base: fileStore}
+}
+
+func (s *scratchStore) Put(name string, data []byte) error {
+	if err := s.files.Put(name, data); err != nil {
+		return err
+	}
+	s.names = append(s.names, name)
+	return nil
+}
+
+// commit 依寫入順序將暫存的檔案寫入打印機記憶體
+func (s *scratchStore) commit() error {
+	if s.base == nil {
+		return nil
+	}
+	for _, name := range s.names {
+		data, _ := s.files.Get(name)
+		if err := s.base.Put(name, data); err != nil {
+			return fmt.Errorf("DOWNLOAD %q 失敗: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *scratchStore) Get(name string) ([]byte, bool) {
+	if data, ok := s.files.Get(name); ok {
+		return data, true
+	}
+	if s.base != nil {
+		return s.base.Get(name)
+	}
+	return nil, false
+}
+
+// Download DOWNLOAD 指令下載的檔案
+type Download struct {
+	Line   int    // DOWNLOAD 所在行號
+	Memory string // F (Flash), E (記憶卡) 或空白 (DRAM)
+	Name   string
+	Data   []byte
+}
+
+// ExtractDownloads 取出 DOWNLOAD "NAME",size,DATA 的二進位內容
+// 回傳的程式碼以相同數量的換行取代資料區段, 使後續命令的行號不變
+func ExtractDownloads(tsplCode string) (string, []Download) {
+	src := []byte(tsplCode)
+	var out bytes.Buffer
+	var downloads []Download
+	line := 1
+
+	for i := 0; i < len(src); {
+		end := bytes.IndexByte(src[i:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += i
+		}
+
+		header, dl, dataStart, size, ok := parseDownloadHeader(src[i:end])
+		if !ok || size > len(src)-i-dataStart {
+			out.Write(src[i:end])
+			if end < len(src) {
+				out.WriteByte('\n')
+			}
+			i = end + 1
+			line++
+			continue
+		}
+
+		dl.Line = line
+		dl.Data = append([]byte(nil), src[i+dataStart:i+dataStart+size]...)
+		downloads = append(downloads, dl)
+
+		out.WriteString(header)
+		newlines := bytes.Count(dl.Data, []byte{'\n'})
+		out.WriteString(strings.Repeat("\n", newlines))
+		line += newlines
+		i += dataStart + size
+	}
+
+	return out.String(), downloads
+}
+
+// parseDownloadHeader 解析 DOWNLOAD [n,]"NAME",size, 標頭
+// 回傳標頭文字、檔案資訊 (不含 Data)、資料起點與宣告的大小; 大小超過 MaxDownloadSize 時視為一般命令
+// 呼叫端須確認原始碼中剩餘的內容足夠後再取出資料
+func parseDownloadHeader(line []byte) (string, Download, int, int, bool) {
+	text := string(line)
+	trimmed := strings.TrimLeft(text, " \t")
+	if len(trimmed) < 8 || !strings.EqualFold(trimmed[:8], "DOWNLOAD") {
+		return "", Download{}, 0, 0, false
+	}
+	pos := len(text) - len(trimmed) + 8
+	rest := text[pos:]
+
+	var dl Download
+	r := strings.TrimLeft(rest, " \t")
+	if len(r) > 0 && strings.ContainsRune("FEfe", rune(r[0])) {
+		after := strings.TrimLeft(r[1:], " \t")
+		if strings.HasPrefix(after, ",") {
+			dl.Memory = strings.ToUpper(r[:1])
+			r = strings.TrimLeft(after[1:], " \t")
+		}
+	}
+	if len(r) == 0 || r[0] != '"' {
+		return "", Download{}, 0, 0, false
+	}
+	closeQuote := strings.IndexByte(r[1:], '"')
+	if closeQuote < 0 {
+		return "", Download{}, 0, 0, false
+	}
+	dl.Name = r[1 : closeQuote+1]
+	r = strings.TrimLeft(r[closeQuote+2:], " \t")
+	if len(r) == 0 || r[0] != ',' {
+		return "", Download{}, 0, 0, false
+	}
+	r = strings.TrimLeft(r[1:], " \t")
+	digits := 0
+	for digits < len(r) && r[digits] >= '0' && r[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 {
+		return "", Download{}, 0, 0, false
+	}
+	size, err := strconv.Atoi(r[:digits])
+	if err != nil || size > MaxDownloadSize {
+		return "", Download{}, 0, 0, false
+	}
+	r = strings.TrimLeft(r[digits:], " \t")
+	if len(r) == 0 || r[0] != ',' {
+		return "", Download{}, 0, 0, false
+	}
+
+	dataStart := len(text) - len(r) + 1
+	header := strings.TrimRight(text[:dataStart-1], " \t")
+	return header, dl, dataStart, size, true
+}
+
+// storeDownload 將下載的檔案寫入本次工作與檔案來源
+func storeDownload(dl Download, files map[string][]byte, store FileStore) error {
+	name := strings.ToUpper(dl.Name)
+	files[name] = dl.Data
+	if store != nil {
+		if err := store.Put(name, dl.Data); err != nil {
+			return fmt.Errorf("DOWNLOAD %q 失敗: %v", dl.Name, err)
+		}
+	}
+	return nil
+}
+
+// lookupFile 先從本次工作的 DOWNLOAD 中尋找檔案, 再查詢檔案來源
+func lookupFile(name string, files map[string][]byte, store FileStore) ([]byte, bool) {
+	if data, ok := files[strings.ToUpper(name)]; ok {
+		return data, true
+	}
+	if store != nil {
+		return store.Get(name)
+	}
+	return nil, false
+}
+
+// parsePutImage 解析 PUTBMP/PUTPCX x,y,"檔名"[,bpp,contrast]
+// 將記憶體中的影像轉換為點陣: bpp 為 8 時使用誤差擴散抖色, 否則以門檻值二值化
+func parsePutImage(command string, parts []string, files map[string][]byte, store FileStore, renderData *models.RenderData) error {
+	if len(parts) < 3 {
+		return fmt.Errorf("%s 指令參數不足", command)
+	}
+
+	x, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return fmt.Errorf("%s X 參數格式錯誤: %v", command, err)
+	}
+	y, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("%s Y 參數格式錯誤: %v", command, err)
+	}
+	name := strings.Trim(parts[2], `"`)
+
+	bpp, contrast := 1, 50
+	if len(parts) >= 4 {
+		if bpp, err = strconv.Atoi(parts[3]); err != nil {
+			return fmt.Errorf("%s bpp 參數格式錯誤: %v", command, err)
+		}
+	}
+	if len(parts) >= 5 {
+		if contrast, err = strconv.Atoi(parts[4]); err != nil {
+			return fmt.Errorf("%s 對比參數格式錯誤: %v", command, err)
+		}
+	}
+
+	data, ok := lookupFile(name, files, store)
+	if !ok {
+		return fmt.Errorf("%s 找不到檔案 %q: 打印機記憶體中沒有此檔案, 請先以 DOWNLOAD 或 POST /api/images 上傳", command, name)
+	}
+
+	img, format, err := imagestore.Decode(name, data)
+	if err != nil {
+		return fmt.Errorf("%s 無法讀取 %q: %v", command, name, err)
+	}
+
+	bitmap := imagestore.Rasterize(img, bpp == 8, contrast)
+	element := models.Element{
+		Type: "image",
+		X:    x,
+		Y:    y,
+		Properties: map[string]interface{}{
+			"file":     strings.ToUpper(name),
+			"format":   format,
+			"width":    bitmap.Width,
+			"height":   bitmap.Height,
+			"bpp":      bpp,
+			"contrast": contrast,
+			"bitmap":   base64.StdEncoding.EncodeToString(bitmap.Bits),
+		},
+	}
+
+	renderData.Elements = append(renderData.Elements, element)
+	return nil
+}
+
+// SourceLine 原始碼中的一行; DOWNLOAD 指令的二進位內容保留在 Download.Data 中
+type SourceLine struct {
+	Line     int       // 行號 (從 1 開始)
+	Text     string    // 行內容 (不含換行); DOWNLOAD 行為資料之前的標頭 (含逗號)
+	Download *Download // DOWNLOAD 的檔案, 其他行為 nil
+	Trailer  string    // DOWNLOAD 資料之後到行尾的內容
+}
+
+// SplitSource 將原始碼拆成行, DOWNLOAD 的資料 (可能含換行) 不拆開
+// 依序串接 Text + Data + Trailer 並以 "\n" 分隔即可還原原始碼 (行尾的 \r 保留在 Text/Trailer 中)
+func SplitSource(tsplCode string) []SourceLine {
+	src := []byte(tsplCode)
+	var lines []SourceLine
+	line := 1
+
+	for i := 0; i <= len(src); {
+		end := bytes.IndexByte(src[i:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += i
+		}
+
+		_, dl, dataStart, size, ok := parseDownloadHeader(src[i:end])
+		if !ok || size > len(src)-i-dataStart {
+			lines = append(lines, SourceLine{Line: line, Text: string(src[i:end])})
+			i = end + 1
+			line++
+			continue
+		}
+
+		dl.Line = line
+		dataEnd := i + dataStart + size
+		dl.Data = append([]byte(nil), src[i+dataStart:dataEnd]...)
+		trailerEnd := bytes.IndexByte(src[dataEnd:], '\n')
+		if trailerEnd < 0 {
+			trailerEnd = len(src)
+		} else {
+			trailerEnd += dataEnd
+		}
+		lines = append(lines, SourceLine{
+			Line:     line,
+			Text:     string(src[i : i+dataStart]),
+			Download: &dl,
+			Trailer:  string(src[dataEnd:trailerEnd]),
+		})
+		line += bytes.Count(dl.Data, []byte{'\n'}) + 1
+		i = trailerEnd + 1
+	}
+	return lines
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"tspl-simulator/imagestore"
+)
+
+// withFileStore 測試期間以 s 作為打印機記憶體
+func withFileStore(t *testing.T, s FileStore) {
+	t.Helper()
+	old := fileStore
+	SetFileStore(s)
+	t.Cleanup(func() { SetFileStore(old) })
+}
+
+func TestParseTSPLCommitsDownloadsOnSuccess(t *testing.T) {
+	store := imagestore.NewStore()
+	withFileStore(t, store)
+
+	if _, err := ParseTSPL("DOWNLOAD \"OK.TXT\",3,abc\nSIZE 50 mm, 30 mm\nCLS\nPRINT 1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := store.Get("OK.TXT"); !ok || string(data) != "abc" {
+		t.Errorf("解析成功後打印機記憶體中的檔案 = %q, %v", data, ok)
+	}
+
+	if _, err := ParseTSPL("DOWNLOAD \"BAD.TXT\",3,abc\nSIZE x mm, 30 mm\n"); err == nil {
+		t.Fatal("SIZE 格式錯誤應解析失敗")
+	}
+	if _, ok := store.Get("BAD.TXT"); ok {
+		t.Error("解析失敗的工作不應寫入打印機記憶體")
+	}
+}
+
+func TestParseTSPLWithScratchStore(t *testing.T) {
+	store := imagestore.NewStore()
+	withFileStore(t, store)
+	if err := store.Put("BASE.TXT", []byte("base")); err != nil {
+		t.Fatal(err)
+	}
+
+	scratch := NewScratchStore()
+	if _, err := ParseTSPLWithStore("DOWNLOAD \"NEW.TXT\",3,abc\nSIZE 50 mm, 30 mm\n", scratch); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get("NEW.TXT"); ok {
+		t.Error("暫存檔案來源不應寫入打印機記憶體")
+	}
+	if _, ok := scratch.Get("NEW.TXT"); !ok {
+		t.Error("暫存檔案來源應保留 DOWNLOAD 的檔案")
+	}
+	if _, ok := scratch.Get("BASE.TXT"); !ok {
+		t.Error("暫存檔案來源應可讀取打印機記憶體中的檔案")
+	}
+}
+
+func TestParseTSPLStoreFull(t *testing.T) {
+	withFileStore(t, imagestore.NewStoreWithLimits(2, 1))
+	_, err := ParseTSPL("DOWNLOAD \"BIG.TXT\",3,abc\nSIZE 50 mm, 30 mm\n")
+	if !errors.Is(err, imagestore.ErrStoreFull) {
+		t.Errorf("打印機記憶體不足: got %v, want ErrStoreFull", err)
+	}
+}
@@ -5,24 +5,58 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"tspl-simulator/imagestore"
 	"tspl-simulator/models"
 )
 
 const DPI = 203 // 標準熱感打印機 DPI
 
-// ParseTSPL 解析 TSPL 指令
+// 未指定 DENSITY/SPEED 時的打印機預設值
+const (
+	DefaultDensity = 8
+	DefaultSpeed   = 4.0
+)
+
+// ParseTSPL 解析 TSPL 指令; 整份工作解析成功後 DOWNLOAD 的檔案才寫入打印機記憶體 (SetFileStore),
+// 解析失敗的工作不會留下檔案
 func ParseTSPL(tsplCode string) (*models.RenderData, error) {
+	scratch := &scratchStore{files: imagestore.NewStore(), base: fileStore}
+	renderData, err := ParseTSPLWithStore(tsplCode, scratch)
+	if err != nil {
+		return nil, err
+	}
+	if err := scratch.commit(); err != nil {
+		return nil, err
+	}
+	return renderData, nil
+}
+
+// ParseTSPLWithStore 以指定的檔案來源解析 TSPL 指令, DOWNLOAD 寫入該來源, PUTBMP/PUTPCX 從該來源讀取
+// 不應改變打印機記憶體的呼叫端 (預覽、格式化、搜尋、匯出等) 傳入 NewScratchStore()
+func ParseTSPLWithStore(tsplCode string, store FileStore) (*models.RenderData, error) {
 	renderData := &models.RenderData{
 		Elements:  []models.Element{},
 		DPI:       DPI,
 		Direction: 0,
 		Reference: models.Reference{X: 0, Y: 0},
+		Media:     models.Media{Type: "continuous", Unit: "mm"},
+		Print:     models.PrintSettings{Density: DefaultDensity, Speed: DefaultSpeed},
+	}
+
+	tsplCode, downloads := ExtractDownloads(tsplCode)
+	downloaded := make(map[int]Download, len(downloads))
+	for _, dl := range downloads {
+		downloaded[dl.Line] = dl
 	}
+	files := make(map[string][]byte)
 
 	lines := strings.Split(tsplCode, "\n")
+	bufferStart := 0
 
-	for _, line := range lines {
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
+		lineNum := i + 1
 		if line == "" || strings.HasPrefix(line, ";") {
 			continue
 		}
@@ -33,26 +67,44 @@ func ParseTSPL(tsplCode string) (*models.RenderData, error) {
 		}
 
 		command := strings.ToUpper(parts[0])
+		args := SplitArgs(line)
 
 		switch command {
 		case "SIZE":
-			if err := parseSize(parts[1:], renderData); err != nil {
+			if err := parseSize(args, renderData); err != nil {
 				return nil, err
 			}
 		case "GAP":
-			if err := parseGap(parts[1:], renderData); err != nil {
+			if err := parseGap(args, renderData); err != nil {
+				return nil, err
+			}
+		case "BLINE":
+			if err := parseBline(args, renderData); err != nil {
 				return nil, err
 			}
 		case "DIRECTION":
-			if err := parseDirection(parts[1:], renderData); err != nil {
+			if err := parseDirection(args, renderData); err != nil {
 				return nil, err
 			}
 		case "REFERENCE":
-			if err := parseReference(parts[1:], renderData); err != nil {
+			if err := parseReference(args, renderData); err != nil {
+				return nil, err
+			}
+		case "DENSITY", "SPEED":
+			if err := parsePrintSetting(command, args, renderData); err != nil {
 				return nil, err
 			}
+		case "SET":
+			parseSet(parts[1:], renderData)
 		case "CLS":
-			// 清除緩衝區,不需要特別處理
+			// 清除緩衝區: 之後的 PRINT 只輸出此後加入的元素
+			bufferStart = len(renderData.Elements)
+		case "FORMFEED", "HOME":
+			renderData.Steps = append(renderData.Steps, models.JobStep{Command: command, Line: lineNum})
+		case "BACKFEED", "LIMITFEED":
+			if err := parseFeed(command, args, lineNum, renderData); err != nil {
+				return nil, err
+			}
 		case "TEXT":
 			if err := parseText(line, renderData); err != nil {
 				return nil, err
@@ -66,25 +118,76 @@ func ParseTSPL(tsplCode string) (*models.RenderData, error) {
 				return nil, err
 			}
 		case "BOX":
-			if err := parseBox(parts[1:], renderData); err != nil {
+			if err := parseBox(args, renderData); err != nil {
 				return nil, err
 			}
 		case "BAR":
-			if err := parseBar(parts[1:], renderData); err != nil {
+			if err := parseBar(args, renderData); err != nil {
+				return nil, err
+			}
+		case "CIRCLE", "ELLIPSE", "DIAGONAL", "TRIANGLE":
+			if err := parseShape(command, args, renderData); err != nil {
+				return nil, err
+			}
+		case "DOWNLOAD":
+			if dl, ok := downloaded[lineNum]; ok {
+				if err := storeDownload(dl, files, store); err != nil {
+					return nil, err
+				}
+			}
+		case "PUTBMP", "PUTPCX":
+			if err := parsePutImage(command, args, files, store, renderData); err != nil {
+				return nil, err
+			}
+		case "REVERSE", "ERASE":
+			if err := parseRegion(command, args, renderData); err != nil {
 				return nil, err
 			}
 		case "PRINT":
-			// 列印指令,不需要特別處理
+			if err := parsePrint(args, lineNum, bufferStart, renderData); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	// 計算畫布尺寸
-	renderData.Width = mmToPixels(renderData.LabelSize.Width)
-	renderData.Height = mmToPixels(renderData.LabelSize.Height)
+	renderData.Width = ToDots(renderData.LabelSize.Width, renderData.LabelSize.Unit)
+	renderData.Height = ToDots(renderData.LabelSize.Height, renderData.LabelSize.Unit)
 
 	return renderData, nil
 }
 
+// SplitArgs 取出命令名稱之後以逗號分隔的參數 (引號內的逗號不分割)
+func SplitArgs(line string) []string {
+	line = strings.TrimSpace(line)
+	idx := strings.IndexAny(line, " \t")
+	if idx < 0 {
+		return nil
+	}
+	rest := strings.TrimSpace(line[idx:])
+	if rest == "" {
+		return nil
+	}
+
+	var args []string
+	var current strings.Builder
+	inQuote := false
+	for _, ch := range rest {
+		switch {
+		case ch == '"':
+			inQuote = !inQuote
+			current.WriteRune(ch)
+		case ch == ',' && !inQuote:
+			args = append(args, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(ch)
+		}
+	}
+	args = append(args, strings.TrimSpace(current.String()))
+	return args
+}
+
 // parseSize 解析 SIZE 指令
 func parseSize(parts []string, renderData *models.RenderData) error {
 	if len(parts) < 2 {
@@ -140,6 +243,146 @@ func parseGap(parts []string, renderData *models.RenderData) error {
 		Unit:     unit1,
 	}
 
+	// GAP 0,0 代表連續紙
+	mediaType := "gap"
+	if distance == 0 {
+		mediaType = "continuous"
+	}
+	renderData.Media = models.Media{
+		Type:     mediaType,
+		Distance: distance,
+		Offset:   offset,
+		Unit:     unit1,
+	}
+
+	return nil
+}
+
+// parseBline 解析 BLINE 指令 (黑標紙: 黑標高度, 額外進紙長度)
+func parseBline(parts []string, renderData *models.RenderData) error {
+	if len(parts) < 2 {
+		return fmt.Errorf("BLINE 指令參數不足")
+	}
+
+	height, unit1, err := parseValueWithUnit(parts[0])
+	if err != nil {
+		return fmt.Errorf("BLINE 黑標高度格式錯誤: %v", err)
+	}
+
+	extra, unit2, err := parseValueWithUnit(parts[1])
+	if err != nil {
+		return fmt.Errorf("BLINE 額外進紙長度格式錯誤: %v", err)
+	}
+
+	if unit1 != unit2 {
+		return fmt.Errorf("BLINE 單位不一致")
+	}
+
+	mediaType := "bline"
+	if height == 0 {
+		mediaType = "continuous"
+	}
+	renderData.Media = models.Media{
+		Type:     mediaType,
+		Distance: height,
+		Offset:   extra,
+		Unit:     unit1,
+	}
+
+	return nil
+}
+
+// parseFeed 解析 BACKFEED (點) 與 LIMITFEED (長度) 指令
+func parseFeed(command string, parts []string, lineNum int, renderData *models.RenderData) error {
+	if len(parts) < 1 {
+		return fmt.Errorf("%s 指令參數不足", command)
+	}
+
+	var dots int
+	if command == "BACKFEED" {
+		n, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return fmt.Errorf("BACKFEED 參數格式錯誤: %v", err)
+		}
+		dots = n
+	} else {
+		value, unit, err := parseValueWithUnit(parts[0])
+		if err != nil {
+			return fmt.Errorf("LIMITFEED 參數格式錯誤: %v", err)
+		}
+		dots = ToDots(value, unit)
+	}
+
+	renderData.Steps = append(renderData.Steps, models.JobStep{
+		Command: command,
+		Line:    lineNum,
+		Dots:    dots,
+	})
+	return nil
+}
+
+// parsePrintSetting 解析 DENSITY (濃度 0-15) 與 SPEED (英吋/秒) 指令
+func parsePrintSetting(command string, parts []string, renderData *models.RenderData) error {
+	if len(parts) < 1 {
+		return fmt.Errorf("%s 指令參數不足", command)
+	}
+
+	if command == "DENSITY" {
+		density, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return fmt.Errorf("DENSITY 參數格式錯誤: %v", err)
+		}
+		renderData.Print.Density = density
+		return nil
+	}
+
+	speed, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return fmt.Errorf("SPEED 參數格式錯誤: %v", err)
+	}
+	renderData.Print.Speed = speed
+	return nil
+}
+
+// parseSet 解析會影響列印效果的 SET 指令 (目前僅 SET RIBBON ON/OFF), 其餘設定忽略
+func parseSet(parts []string, renderData *models.RenderData) {
+	if len(parts) < 2 || strings.ToUpper(parts[0]) != "RIBBON" {
+		return
+	}
+	switch strings.ToUpper(strings.TrimSuffix(parts[1], ",")) {
+	case "ON":
+		renderData.Print.Ribbon = true
+	case "OFF":
+		renderData.Print.Ribbon = false
+	}
+}
+
+// parsePrint 解析 PRINT 指令 (組數, 每組份數)
+func parsePrint(parts []string, lineNum, bufferStart int, renderData *models.RenderData) error {
+	sets, copies := 1, 1
+	if len(parts) >= 1 && parts[0] != "" {
+		n, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return fmt.Errorf("PRINT 組數格式錯誤: %v", err)
+		}
+		sets = n
+	}
+	if len(parts) >= 2 {
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("PRINT 份數格式錯誤: %v", err)
+		}
+		copies = n
+	}
+
+	renderData.Steps = append(renderData.Steps, models.JobStep{
+		Command:      "PRINT",
+		Line:         lineNum,
+		Sets:         sets,
+		Copies:       copies,
+		ElementStart: bufferStart,
+		ElementEnd:   len(renderData.Elements),
+	})
 	return nil
 }
 
@@ -252,7 +495,7 @@ func parseBarcode(line string, renderData *models.RenderData) error {
 
 // parseQRCode 解析 QRCODE 指令
 func parseQRCode(line string, renderData *models.RenderData) error {
-	re := regexp.MustCompile(`QRCODE\s+(\d+),(\d+),([HML]),(\d+),([AM]),(\d+)(?:,(\d+),(\d+),(\d+))?,(?:"([^"]*)")`)
+	re := regexp.MustCompile(`QRCODE\s+(\d+),(\d+),([LMQH]),(\d+),([AM]),(\d+)(?:,(\d+),(\d+),(\d+))?,(?:"([^"]*)")`)
 	matches := re.FindStringSubmatch(line)
 
 	if len(matches) < 11 {
@@ -307,6 +550,15 @@ func parseBox(parts []string, renderData *models.RenderData) error {
 		},
 	}
 
+	// 選用的圓角半徑
+	if len(parts) >= 6 {
+		radius, err := strconv.Atoi(parts[5])
+		if err != nil {
+			return fmt.Errorf("BOX 圓角半徑格式錯誤: %v", err)
+		}
+		element.Properties["radius"] = radius
+	}
+
 	renderData.Elements = append(renderData.Elements, element)
 	return nil
 }
@@ -336,6 +588,73 @@ func parseBar(parts []string, renderData *models.RenderData) error {
 	return nil
 }
 
+// shapeParams 各圖形指令的參數名稱 (前兩個為元素座標 x, y)
+var shapeParams = map[string][]string{
+	"CIRCLE":   {"x", "y", "diameter", "thickness"},
+	"ELLIPSE":  {"x", "y", "width", "height", "thickness"},
+	"DIAGONAL": {"x", "y", "endX", "endY", "thickness"},
+	"TRIANGLE": {"x", "y", "x2", "y2", "x3", "y3", "thickness"},
+}
+
+// parseShape 解析 CIRCLE、ELLIPSE、DIAGONAL 與 TRIANGLE 指令
+func parseShape(command string, parts []string, renderData *models.RenderData) error {
+	names := shapeParams[command]
+	if len(parts) < len(names) {
+		return fmt.Errorf("%s 指令參數不足", command)
+	}
+
+	values := make([]int, len(names))
+	for i := range names {
+		v, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return fmt.Errorf("%s 第 %d 個參數格式錯誤: %v", command, i+1, err)
+		}
+		values[i] = v
+	}
+
+	element := models.Element{
+		Type:       strings.ToLower(command),
+		X:          values[0],
+		Y:          values[1],
+		Properties: map[string]interface{}{},
+	}
+	for i, name := range names[2:] {
+		element.Properties[name] = values[i+2]
+	}
+
+	renderData.Elements = append(renderData.Elements, element)
+	return nil
+}
+
+// parseRegion 解析 REVERSE (反白) 與 ERASE (清除) 指令
+func parseRegion(command string, parts []string, renderData *models.RenderData) error {
+	if len(parts) < 4 {
+		return fmt.Errorf("%s 指令參數不足", command)
+	}
+
+	values := make([]int, 4)
+	for i, param := range parts[:4] {
+		v, err := strconv.Atoi(param)
+		if err != nil {
+			return fmt.Errorf("%s 第 %d 個參數格式錯誤: %v", command, i+1, err)
+		}
+		values[i] = v
+	}
+
+	element := models.Element{
+		Type: strings.ToLower(command),
+		X:    values[0],
+		Y:    values[1],
+		Properties: map[string]interface{}{
+			"width":  values[2],
+			"height": values[3],
+		},
+	}
+
+	renderData.Elements = append(renderData.Elements, element)
+	return nil
+}
+
 // parseValueWithUnit 解析帶單位的數值
 func parseValueWithUnit(s string) (float64, string, error) {
 	s = strings.TrimSpace(s)
@@ -366,3 +685,11 @@ func mmToPixels(mm float64) int {
 	inches := mm / 25.4
 	return int(inches * float64(DPI))
 }
+
+// ToDots 將帶單位的長度轉換為點數
+func ToDots(value float64, unit string) int {
+	if unit == "inch" {
+		return int(value * float64(DPI))
+	}
+	return mmToPixels(value)
+}
@@ -0,0 +1,254 @@
+package qrcode
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Decoded 由模組矩陣解碼的結果
+type Decoded struct {
+	Data      string
+	Version   int
+	ECC       ECCLevel
+	Mask      int
+	Corrected int     // 各區塊修正的碼字總數
+	UnusedECC float64 // 未使用的糾錯能力 (各區塊最小值, 1 代表沒有任何錯誤)
+}
+
+// readFormat 讀取兩份格式資訊並找出漢明距離最小的糾錯等級與遮罩
+func readFormat(grid [][]bool) (ECCLevel, int, error) {
+	size := len(grid)
+	get := func(x, y int) int {
+		if grid[y][x] {
+			return 1
+		}
+		return 0
+	}
+
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= get(8, i) << uint(i)
+	}
+	first |= get(8, 7) << 6
+	first |= get(8, 8) << 7
+	first |= get(7, 8) << 8
+	for i := 9; i < 15; i++ {
+		first |= get(14-i, 8) << uint(i)
+	}
+	for i := 0; i < 8; i++ {
+		second |= get(size-1-i, 8) << uint(i)
+	}
+	for i := 8; i < 15; i++ {
+		second |= get(8, size-15+i) << uint(i)
+	}
+
+	bestDist := 16
+	var bestECC ECCLevel
+	bestMask := 0
+	for ecc := Low; ecc <= High; ecc++ {
+		for mask := 0; mask < 8; mask++ {
+			want := FormatBits(ecc, mask)
+			for _, got := range []int{first, second} {
+				if d := bits.OnesCount(uint(want ^ got)); d < bestDist {
+					bestDist, bestECC, bestMask = d, ecc, mask
+				}
+			}
+		}
+	}
+	if bestDist > 3 {
+		return 0, 0, fmt.Errorf("格式資訊無法辨識")
+	}
+	return bestECC, bestMask, nil
+}
+
+// Decode 解碼已取樣並轉正 (定位圖形位於左上、右上、左下) 的模組矩陣, grid[y][x] 為 true 代表深色
+func Decode(grid [][]bool) (*Decoded, error) {
+	size := len(grid)
+	if size < 21 || size > 177 || (size-17)%4 != 0 {
+		return nil, fmt.Errorf("模組矩陣大小 %d 不是有效的 QR Code 尺寸", size)
+	}
+	for _, row := range grid {
+		if len(row) != size {
+			return nil, fmt.Errorf("模組矩陣必須為正方形")
+		}
+	}
+	version := (size - 17) / 4
+
+	ecc, mask, err := readFormat(grid)
+	if err != nil {
+		return nil, err
+	}
+
+	code := newCode(version, ecc)
+	code.drawFunctionPatterns()
+	blockList := blocks(version, ecc)
+	total := 0
+	for _, b := range blockList {
+		total += b.total
+	}
+
+	raw := make([]byte, total)
+	for i, pos := range code.dataPositions() {
+		if i >= total*8 {
+			break
+		}
+		x, y := pos[0], pos[1]
+		if grid[y][x] != MaskBit(mask, x, y) {
+			raw[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+
+	// 還原交錯排列: 先依序取各區塊資料碼字, 再取糾錯碼字
+	blockData := make([][]byte, len(blockList))
+	offset := 0
+	for i := 0; ; i++ {
+		added := false
+		for j, b := range blockList {
+			if i < b.data {
+				blockData[j] = append(blockData[j], raw[offset])
+				offset++
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	degree := blockList[0].total - blockList[0].data
+	for i := 0; i < degree; i++ {
+		for j := range blockList {
+			blockData[j] = append(blockData[j], raw[offset])
+			offset++
+		}
+	}
+
+	result := &Decoded{Version: version, ECC: ecc, Mask: mask, UnusedECC: 1}
+	var data []byte
+	for j, b := range blockList {
+		n, err := rsCorrect(blockData[j], degree)
+		if err != nil {
+			return nil, fmt.Errorf("區塊 %d 糾錯失敗: %v", j+1, err)
+		}
+		result.Corrected += n
+		if unused := 1 - float64(2*n)/float64(degree); unused < result.UnusedECC {
+			result.UnusedECC = unused
+		}
+		data = append(data, blockData[j][:b.data]...)
+	}
+
+	text, err := decodeSegments(data, version)
+	if err != nil {
+		return nil, err
+	}
+	result.Data = text
+	return result, nil
+}
+
+// bitReader 依序讀取位元
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) remaining() int {
+	return len(r.data)*8 - r.pos
+}
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit := (r.data[r.pos/8] >> uint(7-r.pos%8)) & 1
+		v = v<<1 | int(bit)
+		r.pos++
+	}
+	return v
+}
+
+// decodeSegments 解析資料碼字中的數字、英數與位元組模式區段
+func decodeSegments(data []byte, version int) (string, error) {
+	r := &bitReader{data: data}
+	var out strings.Builder
+	for r.remaining() >= 4 {
+		indicator := r.read(4)
+		var mode Mode
+		switch indicator {
+		case 0:
+			return out.String(), nil
+		case 1:
+			mode = ModeNumeric
+		case 2:
+			mode = ModeAlphanumeric
+		case 4:
+			mode = ModeByte
+		case 7:
+			// ECI: 僅略過指定值, 資料以位元組原樣輸出
+			if r.remaining() < 8 {
+				return "", fmt.Errorf("ECI 區段不完整")
+			}
+			if first := r.read(8); first&0x80 != 0 {
+				r.read(8)
+				if first&0x40 != 0 {
+					r.read(8)
+				}
+			}
+			continue
+		default:
+			return "", fmt.Errorf("不支援的編碼模式 %d", indicator)
+		}
+
+		countBits := charCountBits(mode, version)
+		if r.remaining() < countBits {
+			return "", fmt.Errorf("字元數欄位不完整")
+		}
+		count := r.read(countBits)
+		switch mode {
+		case ModeNumeric:
+			for count > 0 {
+				digits := 3
+				if count < 3 {
+					digits = count
+				}
+				width := digits*3 + 1
+				if r.remaining() < width {
+					return "", fmt.Errorf("數字區段不完整")
+				}
+				fmt.Fprintf(&out, "%0*d", digits, r.read(width))
+				count -= digits
+			}
+		case ModeAlphanumeric:
+			for count > 0 {
+				if count >= 2 {
+					if r.remaining() < 11 {
+						return "", fmt.Errorf("英數區段不完整")
+					}
+					v := r.read(11)
+					if v/45 >= 45 {
+						return "", fmt.Errorf("英數區段數值錯誤")
+					}
+					out.WriteByte(alphanumericCharset[v/45])
+					out.WriteByte(alphanumericCharset[v%45])
+					count -= 2
+					continue
+				}
+				if r.remaining() < 6 {
+					return "", fmt.Errorf("英數區段不完整")
+				}
+				v := r.read(6)
+				if v >= 45 {
+					return "", fmt.Errorf("英數區段數值錯誤")
+				}
+				out.WriteByte(alphanumericCharset[v])
+				count--
+			}
+		default:
+			if r.remaining() < count*8 {
+				return "", fmt.Errorf("位元組區段不完整")
+			}
+			for i := 0; i < count; i++ {
+				out.WriteByte(byte(r.read(8)))
+			}
+		}
+	}
+	return out.String(), nil
+}
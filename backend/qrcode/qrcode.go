@@ -0,0 +1,542 @@
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ECCLevel QR Code 糾錯等級
+type ECCLevel int
+
+const (
+	Low      ECCLevel = iota // L, 約 7%
+	Medium                   // M, 約 15%
+	Quartile                 // Q, 約 25%
+	High                     // H, 約 30%
+)
+
+// Mode 資料編碼模式
+type Mode int
+
+const (
+	ModeNumeric Mode = iota
+	ModeAlphanumeric
+	ModeByte
+)
+
+const alphanumericCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// ParseECC 將 TSPL 的糾錯等級字元轉換為 ECCLevel
+func ParseECC(s string) ECCLevel {
+	switch strings.ToUpper(s) {
+	case "L":
+		return Low
+	case "Q":
+		return Quartile
+	case "H":
+		return High
+	default:
+		return Medium
+	}
+}
+
+// String 回傳糾錯等級字元
+func (e ECCLevel) String() string {
+	return [...]string{"L", "M", "Q", "H"}[e]
+}
+
+// String 回傳編碼模式名稱
+func (m Mode) String() string {
+	return [...]string{"numeric", "alphanumeric", "byte"}[m]
+}
+
+// Code 已編碼的 QR Code 矩陣
+type Code struct {
+	Version int
+	Size    int
+	ECC     ECCLevel
+	Mask    int
+	Mode    Mode
+	modules [][]bool
+	isFunc  [][]bool
+}
+
+// Get 取得 (x, y) 模組是否為深色
+func (c *Code) Get(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// DetectMode 判斷資料最精簡的編碼模式
+func DetectMode(data string) Mode {
+	if data == "" {
+		return ModeByte
+	}
+	numeric, alnum := true, true
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		if ch < '0' || ch > '9' {
+			numeric = false
+		}
+		if strings.IndexByte(alphanumericCharset, ch) < 0 {
+			alnum = false
+		}
+	}
+	switch {
+	case numeric:
+		return ModeNumeric
+	case alnum:
+		return ModeAlphanumeric
+	default:
+		return ModeByte
+	}
+}
+
+// charCountBits 字元數指示位元長度
+func charCountBits(mode Mode, version int) int {
+	group := 0
+	if version >= 27 {
+		group = 2
+	} else if version >= 10 {
+		group = 1
+	}
+	table := map[Mode][3]int{
+		ModeNumeric:      {10, 12, 14},
+		ModeAlphanumeric: {9, 11, 13},
+		ModeByte:         {8, 16, 16},
+	}
+	return table[mode][group]
+}
+
+// dataBitLength 計算資料在指定版本下所需的位元數
+func dataBitLength(data string, mode Mode, version int) int {
+	n := len(data)
+	bits := 4 + charCountBits(mode, version)
+	switch mode {
+	case ModeNumeric:
+		bits += n / 3 * 10
+		switch n % 3 {
+		case 1:
+			bits += 4
+		case 2:
+			bits += 7
+		}
+	case ModeAlphanumeric:
+		bits += n/2*11 + n%2*6
+	default:
+		bits += n * 8
+	}
+	return bits
+}
+
+// MinVersion 計算資料在指定糾錯等級下所需的最小版本
+// 超出版本 40 時回傳錯誤
+func MinVersion(data string, ecc ECCLevel) (int, Mode, error) {
+	mode := DetectMode(data)
+	version, err := MinVersionMode(data, mode, ecc)
+	return version, mode, err
+}
+
+// MinVersionMode 計算資料以指定編碼模式在指定糾錯等級下所需的最小版本
+// 資料不符合編碼模式的字元集或超出版本 40 時回傳錯誤
+func MinVersionMode(data string, mode Mode, ecc ECCLevel) (int, error) {
+	if mode != ModeByte && data != "" && DetectMode(data) > mode {
+		return 0, fmt.Errorf("資料含有 %s 模式無法編碼的字元", mode)
+	}
+	for version := 1; version <= 40; version++ {
+		if mode == ModeByte && len(data) >= 1<<charCountBits(mode, version) {
+			continue
+		}
+		if dataBitLength(data, mode, version) <= DataCapacity(version, ecc)*8 {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("資料長度 %d 超出 QR Code 版本 40 (%s) 的容量", len(data), ecc)
+}
+
+// Encode 將資料編碼為 QR Code
+func Encode(data string, ecc ECCLevel) (*Code, error) {
+	version, mode, err := MinVersion(data, ecc)
+	if err != nil {
+		return nil, err
+	}
+
+	codewords := encodeData(data, mode, version, ecc)
+	all := addErrorCorrection(codewords, version, ecc)
+
+	code := newCode(version, ecc)
+	code.Mode = mode
+	code.drawFunctionPatterns()
+	code.drawCodewords(all)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		code.applyMask(mask)
+		code.drawFormatBits(mask)
+		penalty := code.penalty()
+		if bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		code.applyMask(mask)
+	}
+	code.Mask = bestMask
+	code.applyMask(bestMask)
+	code.drawFormatBits(bestMask)
+
+	return code, nil
+}
+
+// bitBuffer 位元緩衝區
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>uint(i))&1 == 1)
+	}
+}
+
+// encodeData 產生資料碼字 (含模式、長度、終止符與填充)
+func encodeData(data string, mode Mode, version int, ecc ECCLevel) []byte {
+	var bb bitBuffer
+	bb.append([]int{1, 2, 4}[mode], 4)
+	bb.append(len(data), charCountBits(mode, version))
+
+	switch mode {
+	case ModeNumeric:
+		for i := 0; i < len(data); i += 3 {
+			end := i + 3
+			if end > len(data) {
+				end = len(data)
+			}
+			value := 0
+			for _, ch := range data[i:end] {
+				value = value*10 + int(ch-'0')
+			}
+			bb.append(value, (end-i)*3+1)
+		}
+	case ModeAlphanumeric:
+		for i := 0; i < len(data); i += 2 {
+			if i+1 < len(data) {
+				value := strings.IndexByte(alphanumericCharset, data[i])*45 +
+					strings.IndexByte(alphanumericCharset, data[i+1])
+				bb.append(value, 11)
+			} else {
+				bb.append(strings.IndexByte(alphanumericCharset, data[i]), 6)
+			}
+		}
+	default:
+		for i := 0; i < len(data); i++ {
+			bb.append(int(data[i]), 8)
+		}
+	}
+
+	capacityBits := DataCapacity(version, ecc) * 8
+	terminator := capacityBits - len(bb)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bb.append(0, terminator)
+	bb.append(0, (8-len(bb)%8)%8)
+
+	result := make([]byte, 0, capacityBits/8)
+	for i := 0; i < len(bb); i += 8 {
+		var v byte
+		for j := 0; j < 8; j++ {
+			if bb[i+j] {
+				v |= 1 << uint(7-j)
+			}
+		}
+		result = append(result, v)
+	}
+	for pad := byte(0xEC); len(result) < capacityBits/8; pad ^= 0xEC ^ 0x11 {
+		result = append(result, pad)
+	}
+	return result
+}
+
+// addErrorCorrection 分區塊計算糾錯碼並交錯排列
+func addErrorCorrection(data []byte, version int, ecc ECCLevel) []byte {
+	blockList := blocks(version, ecc)
+	dataBlocks := make([][]byte, len(blockList))
+	eccBlocks := make([][]byte, len(blockList))
+
+	offset := 0
+	for i, b := range blockList {
+		dataBlocks[i] = data[offset : offset+b.data]
+		offset += b.data
+		eccBlocks[i] = rsRemainder(dataBlocks[i], b.total-b.data)
+	}
+
+	var result []byte
+	for i := 0; ; i++ {
+		added := false
+		for _, d := range dataBlocks {
+			if i < len(d) {
+				result = append(result, d[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for i := 0; i < len(eccBlocks[0]); i++ {
+		for _, e := range eccBlocks {
+			result = append(result, e[i])
+		}
+	}
+	return result
+}
+
+// newCode 建立空白矩陣
+func newCode(version int, ecc ECCLevel) *Code {
+	size := version*4 + 17
+	c := &Code{Version: version, Size: size, ECC: ecc}
+	c.modules = make([][]bool, size)
+	c.isFunc = make([][]bool, size)
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.isFunc[i] = make([]bool, size)
+	}
+	return c
+}
+
+func (c *Code) setFunc(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunc[y][x] = true
+}
+
+// drawFunctionPatterns 繪製定位、時序、對齊圖形並保留格式區域
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunc(6, i, i%2 == 0)
+		c.setFunc(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions(c.Version)
+	n := len(positions)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue
+			}
+			c.drawAlignment(positions[i], positions[j])
+		}
+	}
+
+	c.drawFormatBits(0)
+	c.drawVersion()
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			dist := abs(dx)
+			if abs(dy) > dist {
+				dist = abs(dy)
+			}
+			c.setFunc(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			dist := abs(dx)
+			if abs(dy) > dist {
+				dist = abs(dy)
+			}
+			c.setFunc(cx+dx, cy+dy, dist != 1)
+		}
+	}
+}
+
+// FormatBits 計算格式資訊的 15 位元 (含 BCH 與遮罩)
+func FormatBits(ecc ECCLevel, mask int) int {
+	data := []int{1, 0, 3, 2}[ecc]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	bits := FormatBits(c.ECC, mask)
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunc(8, i, bit(i))
+	}
+	c.setFunc(8, 7, bit(6))
+	c.setFunc(8, 8, bit(7))
+	c.setFunc(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunc(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunc(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunc(8, c.Size-15+i, bit(i))
+	}
+	c.setFunc(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunc(a, b, dark)
+		c.setFunc(b, a, dark)
+	}
+}
+
+// dataPositions 依 Z 字形順序列出資料模組座標
+func (c *Code) dataPositions() [][2]int {
+	var positions [][2]int
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = c.Size - 1 - vert
+				}
+				if !c.isFunc[y][x] {
+					positions = append(positions, [2]int{x, y})
+				}
+			}
+		}
+	}
+	return positions
+}
+
+func (c *Code) drawCodewords(data []byte) {
+	for i, pos := range c.dataPositions() {
+		if i < len(data)*8 {
+			c.modules[pos[1]][pos[0]] = (data[i/8]>>uint(7-i%8))&1 == 1
+		}
+	}
+}
+
+// MaskBit 判斷遮罩樣式在 (x, y) 是否翻轉
+func MaskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.isFunc[y][x] && MaskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty 計算遮罩評分 (越低越好)
+func (c *Code) penalty() int {
+	result := 0
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i < c.Size; i++ {
+			if get(i) == get(i-1) {
+				run++
+				if run == 5 {
+					result += 3
+				} else if run > 5 {
+					result++
+				}
+			} else {
+				run = 1
+			}
+		}
+		for i := 0; i+7 <= c.Size; i++ {
+			if get(i) && !get(i+1) && get(i+2) && get(i+3) && get(i+4) && !get(i+5) && get(i+6) {
+				before := i >= 4 && !get(i-1) && !get(i-2) && !get(i-3) && !get(i-4)
+				after := i+10 < c.Size && !get(i+7) && !get(i+8) && !get(i+9) && !get(i+10)
+				if before || after {
+					result += 40
+				}
+			}
+		}
+	}
+	for y := 0; y < c.Size; y++ {
+		row := y
+		line(func(i int) bool { return c.modules[row][i] })
+	}
+	for x := 0; x < c.Size; x++ {
+		col := x
+		line(func(i int) bool { return c.modules[i][col] })
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				v := c.modules[y][x]
+				if v == c.modules[y][x+1] && v == c.modules[y+1][x] && v == c.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		result += k * 10
+	}
+	return result
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
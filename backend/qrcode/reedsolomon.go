@@ -0,0 +1,185 @@
+package qrcode
+
+import "fmt"
+
+// gfMultiply GF(2^8) 乘法, 原始多項式 0x11D
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsGenerator 產生指定次數的 Reed-Solomon 生成多項式
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder 計算資料的 Reed-Solomon 糾錯碼字
+func rsRemainder(data []byte, degree int) []byte {
+	generator := rsGenerator(degree)
+	result := make([]byte, degree)
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[degree-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(generator[i], factor)
+		}
+	}
+	return result
+}
+
+// gfExp, gfLog GF(2^8) 指數與對數表, 用於解碼時的除法與求值
+var (
+	gfExp [510]byte
+	gfLog [256]int
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfExp[i+255] = x
+		gfLog[x] = i
+		x = gfMultiply(x, 0x02)
+	}
+}
+
+// gfDivide GF(2^8) 除法 (y 不可為 0)
+func gfDivide(x, y byte) byte {
+	if x == 0 {
+		return 0
+	}
+	return gfExp[(gfLog[x]+255-gfLog[y])%255]
+}
+
+// gfPow 計算 α^n
+func gfPow(n int) byte {
+	n %= 255
+	if n < 0 {
+		n += 255
+	}
+	return gfExp[n]
+}
+
+// polyEval 計算低次項在前的多項式在 x 的值
+func polyEval(poly []byte, x byte) byte {
+	var result byte
+	for i := len(poly) - 1; i >= 0; i-- {
+		result = gfMultiply(result, x) ^ poly[i]
+	}
+	return result
+}
+
+// rsCorrect 以 Berlekamp-Massey 與 Forney 演算法就地修正區塊 (資料碼字 + degree 個糾錯碼字)
+// 回傳修正的碼字數; 錯誤超過糾錯能力時回傳錯誤
+func rsCorrect(block []byte, degree int) (int, error) {
+	syndromes := make([]byte, degree)
+	clean := true
+	for j := 0; j < degree; j++ {
+		var s byte
+		for _, b := range block {
+			s = gfMultiply(s, gfPow(j)) ^ b
+		}
+		syndromes[j] = s
+		if s != 0 {
+			clean = false
+		}
+	}
+	if clean {
+		return 0, nil
+	}
+
+	// Berlekamp-Massey: 求錯誤定位多項式 (低次項在前)
+	locator := []byte{1}
+	prev := []byte{1}
+	errors, shift := 0, 1
+	lastDiscrepancy := byte(1)
+	for n := 0; n < degree; n++ {
+		d := syndromes[n]
+		for i := 1; i <= errors && i < len(locator); i++ {
+			d ^= gfMultiply(locator[i], syndromes[n-i])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		saved := append([]byte(nil), locator...)
+		coef := gfDivide(d, lastDiscrepancy)
+		for len(locator) < len(prev)+shift {
+			locator = append(locator, 0)
+		}
+		for i, p := range prev {
+			locator[i+shift] ^= gfMultiply(coef, p)
+		}
+		if 2*errors <= n {
+			errors = n + 1 - errors
+			prev = saved
+			lastDiscrepancy = d
+			shift = 1
+		} else {
+			shift++
+		}
+	}
+	if 2*errors > degree {
+		return 0, fmt.Errorf("錯誤數超過糾錯能力")
+	}
+
+	// Chien 搜尋: 第 i 個碼字的次方為 len-1-i, 定位多項式的根為 α^-(len-1-i)
+	var positions []int
+	for i := range block {
+		power := len(block) - 1 - i
+		if polyEval(locator, gfPow(-power)) == 0 {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) != errors {
+		return 0, fmt.Errorf("無法定位錯誤位置")
+	}
+
+	// Forney: 錯誤值 = X * Ω(X^-1) / Λ'(X^-1)
+	omega := make([]byte, degree)
+	for i := 0; i < degree; i++ {
+		for j := 0; j <= i && j < len(locator); j++ {
+			omega[i] ^= gfMultiply(locator[j], syndromes[i-j])
+		}
+	}
+	for _, i := range positions {
+		power := len(block) - 1 - i
+		inverse := gfPow(-power)
+		var derivative byte
+		for k := 1; k < len(locator); k += 2 {
+			derivative ^= gfMultiply(locator[k], gfPow(-power*(k-1)))
+		}
+		if derivative == 0 {
+			return 0, fmt.Errorf("無法計算錯誤值")
+		}
+		block[i] ^= gfMultiply(gfPow(power), gfDivide(polyEval(omega, inverse), derivative))
+	}
+
+	for j := 0; j < degree; j++ {
+		var s byte
+		for _, b := range block {
+			s = gfMultiply(s, gfPow(j)) ^ b
+		}
+		if s != 0 {
+			return 0, fmt.Errorf("修正後仍有錯誤")
+		}
+	}
+	return errors, nil
+}
@@ -0,0 +1,155 @@
+package renderer
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Canvas 1 位元點陣畫布, 與熱感打印機的影像緩衝區相同, 每個點只有印/不印兩種狀態
+type Canvas struct {
+	Width  int
+	Height int
+	stride int
+	bits   []byte
+}
+
+// NewCanvas 建立空白畫布
+func NewCanvas(width, height int) *Canvas {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+	stride := (width + 7) / 8
+	return &Canvas{
+		Width:  width,
+		Height: height,
+		stride: stride,
+		bits:   make([]byte, stride*height),
+	}
+}
+
+// Get 取得 (x, y) 是否為黑點, 超出範圍視為白點
+func (c *Canvas) Get(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return false
+	}
+	return c.bits[y*c.stride+x/8]&(0x80>>uint(x%8)) != 0
+}
+
+// Set 設定 (x, y) 的點, 超出範圍時忽略
+func (c *Canvas) Set(x, y int, on bool) {
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return
+	}
+	mask := byte(0x80 >> uint(x%8))
+	if on {
+		c.bits[y*c.stride+x/8] |= mask
+	} else {
+		c.bits[y*c.stride+x/8] &^= mask
+	}
+}
+
+// clip 將矩形裁切到畫布範圍內
+func (c *Canvas) clip(x, y, w, h int) (int, int, int, int) {
+	if w < 0 {
+		x, w = x+w, -w
+	}
+	if h < 0 {
+		y, h = y+h, -h
+	}
+	x1, y1 := x+w, y+h
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x1 > c.Width {
+		x1 = c.Width
+	}
+	if y1 > c.Height {
+		y1 = c.Height
+	}
+	return x, y, x1, y1
+}
+
+// FillRect 將矩形區域設為黑點
+func (c *Canvas) FillRect(x, y, w, h int) {
+	x0, y0, x1, y1 := c.clip(x, y, w, h)
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			c.Set(px, py, true)
+		}
+	}
+}
+
+// XorRect 將矩形區域反白 (黑白互換), 對應 REVERSE
+func (c *Canvas) XorRect(x, y, w, h int) {
+	x0, y0, x1, y1 := c.clip(x, y, w, h)
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			c.Set(px, py, !c.Get(px, py))
+		}
+	}
+}
+
+// ClearRect 將矩形區域清為白點, 對應 ERASE
+func (c *Canvas) ClearRect(x, y, w, h int) {
+	x0, y0, x1, y1 := c.clip(x, y, w, h)
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			c.Set(px, py, false)
+		}
+	}
+}
+
+// Count 計算黑點數量
+func (c *Canvas) Count() int {
+	n := 0
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			if c.Get(x, y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Rotate180 將整張畫布旋轉 180 度 (DIRECTION 1)
+func (c *Canvas) Rotate180() *Canvas {
+	out := NewCanvas(c.Width, c.Height)
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			if c.Get(x, y) {
+				out.Set(c.Width-1-x, c.Height-1-y, true)
+			}
+		}
+	}
+	return out
+}
+
+// palette 黑白調色盤 (索引 0 為白紙, 1 為黑點)
+var palette = color.Palette{color.White, color.Black}
+
+// Image 轉換為 1 位元調色盤影像
+func (c *Canvas) Image() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, c.Width, c.Height), palette)
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; x++ {
+			if c.Get(x, y) {
+				img.Pix[y*img.Stride+x] = 1
+			}
+		}
+	}
+	return img
+}
+
+// EncodePNG 輸出 PNG 影像
+func (c *Canvas) EncodePNG(w io.Writer) error {
+	return png.Encode(w, c.Image())
+}
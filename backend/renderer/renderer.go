@@ -0,0 +1,259 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"image"
+
+	"tspl-simulator/barcode"
+	"tspl-simulator/models"
+	"tspl-simulator/qrcode"
+)
+
+// pen 以元素原點與旋轉角度繪製的畫筆
+// 元素內部座標 (u, v): u 為書寫方向, v 為向下方向, 依 TSPL 順時針旋轉對應到畫布
+type pen struct {
+	canvas   *Canvas
+	x, y     int
+	rotation int
+}
+
+// fill 在元素座標系中填滿矩形
+func (p pen) fill(u, v, w, h int) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	r := p.rect(u, v, w, h)
+	p.canvas.FillRect(r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+}
+
+// rect 將元素座標系中的矩形換算為畫布座標
+func (p pen) rect(u, v, w, h int) image.Rectangle {
+	switch p.rotation {
+	case 90:
+		return image.Rect(p.x-v-h+1, p.y+u, p.x-v+1, p.y+u+w)
+	case 180:
+		return image.Rect(p.x-u-w+1, p.y-v-h+1, p.x-u+1, p.y-v+1)
+	case 270:
+		return image.Rect(p.x+v, p.y-u-w+1, p.x+v+h, p.y-u+1)
+	default:
+		return image.Rect(p.x+u, p.y+v, p.x+u+w, p.y+v+h)
+	}
+}
+
+// Render 將渲染資料繪製為單張標籤點陣, 回傳無法繪製的元素警告
+func Render(data *models.RenderData) (*Canvas, []string) {
+	return RenderElements(data, data.Elements)
+}
+
+// RenderElements 以標籤尺寸、參考點與方向繪製指定的元素
+// 元素依命令順序合成: 一般圖形以 OR 疊加, REVERSE 以 XOR 反白, ERASE 清除已繪製的點
+func RenderElements(data *models.RenderData, elements []models.Element) (*Canvas, []string) {
+	canvas := NewCanvas(data.Width, data.Height)
+	var warnings []string
+
+	for i, el := range elements {
+		if err := drawElement(canvas, data, el); err != nil {
+			warnings = append(warnings, fmt.Sprintf("元素 %d (%s): %v", i+1, el.Type, err))
+		}
+	}
+
+	if data.Direction == 1 {
+		canvas = canvas.Rotate180()
+	}
+	return canvas, warnings
+}
+
+// drawElement 依元素類型繪製
+func drawElement(canvas *Canvas, data *models.RenderData, el models.Element) error {
+	p := elementPen(canvas, data, el)
+
+	switch el.Type {
+	case "text":
+		drawTextElement(p, el)
+	case "barcode":
+		return drawBarcode(p, el)
+	case "qrcode":
+		return drawQRCode(p, el)
+	case "box":
+		drawBox(p, el)
+	case "circle", "ellipse", "diagonal", "triangle":
+		drawShape(canvas, p.x, p.y, el)
+	case "bar":
+		p.fill(0, 0, IntProp(el.Properties, "width"), IntProp(el.Properties, "height"))
+	case "image":
+		return drawImage(canvas, p.x, p.y, el)
+	case "reverse":
+		canvas.XorRect(p.x, p.y, IntProp(el.Properties, "width"), IntProp(el.Properties, "height"))
+	case "erase":
+		canvas.ClearRect(p.x, p.y, IntProp(el.Properties, "width"), IntProp(el.Properties, "height"))
+	default:
+		return fmt.Errorf("不支援的元素類型")
+	}
+	return nil
+}
+
+// elementPen 建立元素原點 (含 REFERENCE 偏移) 與旋轉角度的畫筆
+func elementPen(canvas *Canvas, data *models.RenderData, el models.Element) pen {
+	return pen{
+		canvas:   canvas,
+		x:        el.X + data.Reference.X,
+		y:        el.Y + data.Reference.Y,
+		rotation: IntProp(el.Properties, "rotation"),
+	}
+}
+
+// ElementPoint 回傳將元素座標 (u, v) 換算為最終畫布座標的函式 (含 REFERENCE、旋轉與 DIRECTION 1)
+func ElementPoint(data *models.RenderData, el models.Element) func(u, v int) image.Point {
+	p := elementPen(nil, data, el)
+	return func(u, v int) image.Point {
+		pt := p.rect(u, v, 1, 1).Min
+		if data.Direction == 1 {
+			pt = image.Pt(data.Width-1-pt.X, data.Height-1-pt.Y)
+		}
+		return pt
+	}
+}
+
+// drawTextElement 繪製 TEXT 元素
+func drawTextElement(p pen, el models.Element) {
+	props := el.Properties
+	cellW, cellH := FontCell(StringProp(props, "font"), IntProp(props, "xScale"), IntProp(props, "yScale"))
+	drawText(p, StringProp(props, "text"), cellW, cellH)
+}
+
+// drawBarcode 繪製一維條碼與人眼可讀文字
+func drawBarcode(p pen, el models.Element) error {
+	props := el.Properties
+	pattern, err := barcode.Encode(StringProp(props, "type"), StringProp(props, "code"))
+	if err != nil {
+		return err
+	}
+
+	height := IntProp(props, "height")
+	widths := pattern.ModuleWidths(IntProp(props, "narrow"), IntProp(props, "wide"))
+	u := 0
+	for i, w := range widths {
+		if i%2 == 0 {
+			p.fill(u, 0, w, height)
+		}
+		u += w
+	}
+
+	readable := IntProp(props, "readable")
+	if readable == 0 {
+		return nil
+	}
+	cellW, cellH := FontCell("2", 1, 1)
+	textWidth := len(pattern.Text) * cellW
+	start := 0
+	switch readable {
+	case 2:
+		start = (u - textWidth) / 2
+	case 3:
+		start = u - textWidth
+	}
+	drawText(p.offset(start, height+2), pattern.Text, cellW, cellH)
+	return nil
+}
+
+// offset 回傳原點沿元素座標系平移後的畫筆
+func (p pen) offset(u, v int) pen {
+	switch p.rotation {
+	case 90:
+		p.x, p.y = p.x-v, p.y+u
+	case 180:
+		p.x, p.y = p.x-u, p.y-v
+	case 270:
+		p.x, p.y = p.x+v, p.y-u
+	default:
+		p.x, p.y = p.x+u, p.y+v
+	}
+	return p
+}
+
+// drawQRCode 繪製 QR Code
+func drawQRCode(p pen, el models.Element) error {
+	props := el.Properties
+	code, err := qrcode.Encode(StringProp(props, "data"), qrcode.ParseECC(StringProp(props, "eccLevel")))
+	if err != nil {
+		return err
+	}
+
+	cell := IntProp(props, "cellSize")
+	if cell < 1 {
+		cell = 1
+	}
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Get(x, y) {
+				p.fill(x*cell, y*cell, cell, cell)
+			}
+		}
+	}
+	return nil
+}
+
+// drawBox 繪製 BOX 外框
+func drawBox(p pen, el models.Element) {
+	props := el.Properties
+	w := IntProp(props, "endX") - el.X
+	h := IntProp(props, "endY") - el.Y
+	t := IntProp(props, "thickness")
+	if t < 1 {
+		t = 1
+	}
+	if radius := IntProp(props, "radius"); radius > 0 {
+		drawRoundedBox(p.canvas, p.x, p.y, w, h, t, radius)
+		return
+	}
+	p.fill(0, 0, w, t)
+	p.fill(0, h-t, w, t)
+	p.fill(0, 0, t, h)
+	p.fill(w-t, 0, t, h)
+}
+
+// drawImage 繪製 PUTBMP/PUTPCX 轉換後的點陣 (每列 (width+7)/8 位元組, 1 代表印出)
+func drawImage(c *Canvas, x, y int, el models.Element) error {
+	props := el.Properties
+	bits, err := base64.StdEncoding.DecodeString(StringProp(props, "bitmap"))
+	if err != nil {
+		return fmt.Errorf("點陣資料格式錯誤: %v", err)
+	}
+
+	w, h := IntProp(props, "width"), IntProp(props, "height")
+	stride := (w + 7) / 8
+	if len(bits) < stride*h {
+		return fmt.Errorf("點陣資料不完整")
+	}
+	for py := 0; py < h; py++ {
+		for px := 0; px < w; px++ {
+			if bits[py*stride+px/8]&(0x80>>uint(px%8)) != 0 {
+				c.Set(x+px, y+py, true)
+			}
+		}
+	}
+	return nil
+}
+
+// IntProp 讀取整數屬性 (相容 JSON 解碼後的 float64)
+func IntProp(props map[string]interface{}, key string) int {
+	switch v := props[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// StringProp 讀取字串屬性
+func StringProp(props map[string]interface{}, key string) string {
+	if v, ok := props[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package renderer
+
+import (
+	"math"
+
+	"tspl-simulator/models"
+)
+
+// drawEllipse 繪製橢圓外框: 點的中心落在外橢圓內且不在內橢圓 (內縮線寬) 內時印出
+func drawEllipse(c *Canvas, x, y, w, h, thickness int) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	a, b := float64(w)/2, float64(h)/2
+	cx, cy := float64(x)+a, float64(y)+b
+	t := float64(thickness)
+	ia, ib := a-t, b-t
+
+	left, top, right, bottom := c.clip(x, y, w, h)
+	for py := top; py < bottom; py++ {
+		for px := left; px < right; px++ {
+			dx, dy := float64(px)+0.5-cx, float64(py)+0.5-cy
+			if dx*dx/(a*a)+dy*dy/(b*b) > 1 {
+				continue
+			}
+			if ia > 0 && ib > 0 && dx*dx/(ia*ia)+dy*dy/(ib*ib) < 1 {
+				continue
+			}
+			c.Set(px, py, true)
+		}
+	}
+}
+
+// insideRoundedRect 判斷點是否位於圓角矩形內
+func insideRoundedRect(px, py, x0, y0, x1, y1, r float64) bool {
+	if px < x0 || px > x1 || py < y0 || py > y1 {
+		return false
+	}
+	if r <= 0 {
+		return true
+	}
+	cx := math.Max(x0+r, math.Min(px, x1-r))
+	cy := math.Max(y0+r, math.Min(py, y1-r))
+	dx, dy := px-cx, py-cy
+	return dx*dx+dy*dy <= r*r
+}
+
+// drawRoundedBox 繪製圓角矩形外框
+func drawRoundedBox(c *Canvas, x, y, w, h, thickness, radius int) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	r := math.Min(float64(radius), math.Min(float64(w), float64(h))/2)
+	t := float64(thickness)
+	x0, y0 := float64(x), float64(y)
+	x1, y1 := x0+float64(w), y0+float64(h)
+
+	left, top, right, bottom := c.clip(x, y, w, h)
+	for py := top; py < bottom; py++ {
+		for px := left; px < right; px++ {
+			fx, fy := float64(px)+0.5, float64(py)+0.5
+			if !insideRoundedRect(fx, fy, x0, y0, x1, y1, r) {
+				continue
+			}
+			if insideRoundedRect(fx, fy, x0+t, y0+t, x1-t, y1-t, math.Max(r-t, 0)) {
+				continue
+			}
+			c.Set(px, py, true)
+		}
+	}
+}
+
+// drawLine 以線寬繪製線段: 點中心與線段距離不超過線寬一半時印出
+func drawLine(c *Canvas, x1, y1, x2, y2, thickness int) {
+	if thickness < 1 {
+		thickness = 1
+	}
+	half := float64(thickness) / 2
+	ax, ay := float64(x1), float64(y1)
+	bx, by := float64(x2), float64(y2)
+	dx, dy := bx-ax, by-ay
+	length2 := dx*dx + dy*dy
+
+	minX := int(math.Floor(math.Min(ax, bx) - half))
+	maxX := int(math.Ceil(math.Max(ax, bx) + half))
+	minY := int(math.Floor(math.Min(ay, by) - half))
+	maxY := int(math.Ceil(math.Max(ay, by) + half))
+
+	left, top, right, bottom := c.clip(minX, minY, maxX-minX+1, maxY-minY+1)
+	for py := top; py < bottom; py++ {
+		for px := left; px < right; px++ {
+			fx, fy := float64(px)+0.5, float64(py)+0.5
+			t := 0.0
+			if length2 > 0 {
+				t = math.Max(0, math.Min(1, ((fx-ax)*dx+(fy-ay)*dy)/length2))
+			}
+			ex, ey := fx-(ax+t*dx), fy-(ay+t*dy)
+			if ex*ex+ey*ey <= half*half {
+				c.Set(px, py, true)
+			}
+		}
+	}
+}
+
+// drawShape 繪製 CIRCLE、ELLIPSE、DIAGONAL 與 TRIANGLE 元素
+func drawShape(c *Canvas, x, y int, el models.Element) {
+	props := el.Properties
+	thickness := IntProp(props, "thickness")
+	if thickness < 1 {
+		thickness = 1
+	}
+
+	switch el.Type {
+	case "circle":
+		d := IntProp(props, "diameter")
+		drawEllipse(c, x, y, d, d, thickness)
+	case "ellipse":
+		drawEllipse(c, x, y, IntProp(props, "width"), IntProp(props, "height"), thickness)
+	case "diagonal":
+		dx, dy := x-el.X, y-el.Y
+		drawLine(c, x, y, IntProp(props, "endX")+dx, IntProp(props, "endY")+dy, thickness)
+	case "triangle":
+		dx, dy := x-el.X, y-el.Y
+		x2, y2 := IntProp(props, "x2")+dx, IntProp(props, "y2")+dy
+		x3, y3 := IntProp(props, "x3")+dx, IntProp(props, "y3")+dy
+		drawLine(c, x, y, x2, y2, thickness)
+		drawLine(c, x2, y2, x3, y3, thickness)
+		drawLine(c, x3, y3, x, y, thickness)
+	}
+}
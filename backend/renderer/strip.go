@@ -0,0 +1,274 @@
+package renderer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+)
+
+const (
+	// stripMargin 紙捲兩側保留的邊界 (點), 用於標示黑標與列印起點
+	stripMargin = 24
+	// maxStripLabels 紙捲模擬最多輸出的標籤張數
+	maxStripLabels = 50
+	// maxStripHeight 紙捲影像的最大高度 (點), 超出的部分不繪製
+	maxStripHeight = 16000
+)
+
+var (
+	linerColor   = color.RGBA{R: 214, G: 200, B: 170, A: 255}
+	labelColor   = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	markColor    = color.RGBA{R: 40, G: 40, B: 40, A: 255}
+	inkColor     = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	bleedColor   = color.RGBA{R: 220, G: 30, B: 30, A: 255}
+	registerMark = color.RGBA{R: 30, G: 90, B: 220, A: 255}
+	cutColor     = color.RGBA{R: 170, G: 170, B: 170, A: 255}
+)
+
+// Strip 紙捲模擬結果
+type Strip struct {
+	Image      *image.RGBA
+	Pitch      int
+	Placements []models.LabelPlacement
+	Warnings   []string
+}
+
+// media 以點數表示的媒體幾何
+type media struct {
+	kind   string
+	label  int // 標籤高度
+	gap    int // 間隙或黑標高度
+	offset int // 間隙偏移或黑標額外進紙
+	pitch  int // 相鄰標籤起點的距離
+}
+
+// newMedia 由渲染資料建立媒體幾何
+func newMedia(data *models.RenderData) media {
+	m := media{
+		kind:   data.Media.Type,
+		label:  data.Height,
+		gap:    parser.ToDots(data.Media.Distance, data.Media.Unit),
+		offset: parser.ToDots(data.Media.Offset, data.Media.Unit),
+	}
+	if m.kind == "" || m.gap == 0 {
+		m.kind = "continuous"
+		m.gap = 0
+	}
+	m.pitch = m.label + m.gap
+	if m.pitch < 1 {
+		m.pitch = 1
+	}
+	return m
+}
+
+// registration 取得第 k 張標籤的列印起點 (感測器定位後的位置)
+func (m media) registration(k int) int {
+	return k*m.pitch + m.offset
+}
+
+// nextRegistration 取得不早於位置 pos 的最近列印起點
+func (m media) nextRegistration(pos int) int {
+	k := (pos - m.offset + m.pitch - 1) / m.pitch
+	if pos-m.offset < 0 {
+		k = 0
+	}
+	return m.registration(k)
+}
+
+// labelAt 取得位置 pos 所在的紙捲標籤索引, 以及是否位於間隙內
+func (m media) labelAt(pos int) (int, bool) {
+	if pos < 0 {
+		return 0, true
+	}
+	k := pos / m.pitch
+	return k, pos-k*m.pitch >= m.label
+}
+
+// RenderStrip 依 GAP/BLINE 媒體設定與 PRINT、FORMFEED、BACKFEED、HOME、LIMITFEED
+// 的命令順序, 將整個列印工作的標籤排列在模擬紙捲上
+func RenderStrip(data *models.RenderData) (*Strip, error) {
+	if data.Width <= 0 || data.Height <= 0 {
+		return nil, fmt.Errorf("缺少有效的 SIZE 設定")
+	}
+
+	m := newMedia(data)
+	strip := &Strip{Pitch: m.pitch}
+
+	type printed struct {
+		canvas *Canvas
+		pos    int
+	}
+	var prints []printed
+	truncated := false
+
+	pos := m.registration(0)
+	limit := 0
+	end := pos
+
+	// feedToNext 模擬感測器由位置 from 起尋找下一個間隙/黑標; 連續紙沒有感測目標, 停在 from
+	feedToNext := func(from, line int, command string) int {
+		if m.kind == "continuous" {
+			return from
+		}
+		next := m.nextRegistration(from)
+		if limit > 0 && next-pos > limit {
+			strip.Warnings = append(strip.Warnings,
+				fmt.Sprintf("第 %d 行 %s: 在 LIMITFEED %d 點內未偵測到%s", line, command, limit, m.sensorName()))
+			return pos + limit
+		}
+		return next
+	}
+
+	for _, step := range data.Steps {
+		switch step.Command {
+		case "PRINT":
+			elements := data.Elements[step.ElementStart:step.ElementEnd]
+			count := step.Sets * step.Copies
+			for i := 0; i < count; i++ {
+				if len(prints) >= maxStripLabels {
+					truncated = true
+					break
+				}
+				canvas, warnings := RenderElements(data, elements)
+				if i == 0 {
+					strip.Warnings = append(strip.Warnings, warnings...)
+				}
+				prints = append(prints, printed{canvas: canvas, pos: pos})
+				strip.Placements = append(strip.Placements, m.place(len(prints), step.Line, pos))
+
+				if pos+m.label > end {
+					end = pos + m.label
+				}
+				pos = feedToNext(pos+m.label, step.Line, "PRINT")
+			}
+		case "FORMFEED":
+			// 連續紙進一張標籤長度, 間隙/黑標紙進到目前位置之後的下一個列印起點
+			if m.kind == "continuous" {
+				pos += m.label
+			} else {
+				pos = feedToNext(pos+1, step.Line, "FORMFEED")
+			}
+		case "HOME":
+			if m.kind != "continuous" {
+				pos = feedToNext(pos+1, step.Line, "HOME")
+			}
+		case "BACKFEED":
+			pos -= step.Dots
+			if pos < 0 {
+				strip.Warnings = append(strip.Warnings,
+					fmt.Sprintf("第 %d 行 BACKFEED 超出紙捲起點", step.Line))
+				pos = 0
+			}
+		case "LIMITFEED":
+			limit = step.Dots
+		}
+		if pos > end {
+			end = pos
+		}
+	}
+
+	if truncated {
+		strip.Warnings = append(strip.Warnings,
+			fmt.Sprintf("紙捲模擬最多顯示 %d 張標籤, 其餘已省略", maxStripLabels))
+	}
+	if len(prints) == 0 {
+		strip.Warnings = append(strip.Warnings, "列印工作中沒有 PRINT 命令")
+	}
+
+	labels := (end+m.pitch-1)/m.pitch + 1
+	if limit := max(maxStripHeight/m.pitch, 1); labels > limit {
+		strip.Warnings = append(strip.Warnings,
+			fmt.Sprintf("紙捲模擬最多顯示 %d 點長度, 其餘已省略", limit*m.pitch))
+		labels = limit
+	}
+	strip.Image = m.drawRoll(data.Width, labels)
+	for _, pr := range prints {
+		m.drawPrint(strip.Image, pr.canvas, pr.pos)
+	}
+	return strip, nil
+}
+
+// sensorName 感測目標名稱
+func (m media) sensorName() string {
+	if m.kind == "bline" {
+		return "黑標"
+	}
+	return "間隙"
+}
+
+// place 計算單次列印在紙捲上的落點
+func (m media) place(index, line, pos int) models.LabelPlacement {
+	k, inGap := m.labelAt(pos)
+	placement := models.LabelPlacement{
+		Index:      index,
+		Line:       line,
+		Y:          pos,
+		MediaLabel: k + 1,
+		Aligned:    pos == m.registration(k) || m.kind == "continuous",
+	}
+	if m.kind != "continuous" {
+		lastK, lastInGap := m.labelAt(pos + m.label - 1)
+		placement.CrossesGap = inGap || lastInGap || lastK != k
+	}
+	return placement
+}
+
+// drawRoll 繪製底紙、標籤、間隙與黑標
+func (m media) drawRoll(width, labels int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width+stripMargin*2, labels*m.pitch))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: linerColor}, image.Point{}, draw.Src)
+
+	for k := 0; k < labels; k++ {
+		top := k * m.pitch
+		switch m.kind {
+		case "gap":
+			rect := image.Rect(stripMargin, top, stripMargin+width, top+m.label)
+			draw.Draw(img, rect, &image.Uniform{C: labelColor}, image.Point{}, draw.Src)
+		case "bline":
+			rect := image.Rect(stripMargin, top, stripMargin+width, top+m.pitch)
+			draw.Draw(img, rect, &image.Uniform{C: labelColor}, image.Point{}, draw.Src)
+			mark := image.Rect(0, top+m.label, stripMargin/2, top+m.pitch)
+			draw.Draw(img, mark, &image.Uniform{C: markColor}, image.Point{}, draw.Src)
+		default:
+			rect := image.Rect(stripMargin, top, stripMargin+width, top+m.pitch)
+			draw.Draw(img, rect, &image.Uniform{C: labelColor}, image.Point{}, draw.Src)
+			for x := stripMargin; x < stripMargin+width; x += 8 {
+				for dx := 0; dx < 4 && x+dx < stripMargin+width; dx++ {
+					img.Set(x+dx, top, cutColor)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// drawPrint 將單張列印內容疊到紙捲上, 落在間隙/黑標區的點以紅色標示
+func (m media) drawPrint(img *image.RGBA, canvas *Canvas, pos int) {
+	for y := 0; y < canvas.Height; y++ {
+		rollY := pos + y
+		if rollY < 0 || rollY >= img.Bounds().Dy() {
+			continue
+		}
+		_, inGap := m.labelAt(rollY)
+		ink := inkColor
+		if inGap && m.kind != "continuous" {
+			ink = bleedColor
+		}
+		for x := 0; x < canvas.Width; x++ {
+			if canvas.Get(x, y) {
+				img.Set(stripMargin+x, rollY, ink)
+			}
+		}
+	}
+
+	// 列印起點標記: 右側邊界的三角形箭頭
+	for i := 0; i < stripMargin/2; i++ {
+		for dy := -i / 3; dy <= i/3; dy++ {
+			img.Set(stripMargin+canvas.Width+i+2, pos+dy, registerMark)
+		}
+	}
+}
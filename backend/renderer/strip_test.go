@@ -0,0 +1,43 @@
+package renderer
+
+import (
+	"reflect"
+	"testing"
+
+	"tspl-simulator/parser"
+)
+
+func TestRenderStripPlacements(t *testing.T) {
+	// 50 mm x 10 mm 在 203 dpi 為 79 點, 2 mm 間隙為 15 點
+	tests := []struct {
+		name string
+		code string
+		want []int
+	}{
+		{"continuous print", "SIZE 50 mm, 10 mm\nGAP 0,0\nCLS\nPRINT 3\n", []int{0, 79, 158}},
+		{"continuous formfeed", "SIZE 50 mm, 10 mm\nGAP 0,0\nCLS\nPRINT 1\nFORMFEED\nFORMFEED\nPRINT 1\n", []int{0, 237}},
+		{"continuous home", "SIZE 50 mm, 10 mm\nGAP 0,0\nCLS\nPRINT 1\nHOME\nPRINT 1\n", []int{0, 79}},
+		{"gap print", "SIZE 50 mm, 10 mm\nGAP 2 mm,0\nCLS\nPRINT 2\n", []int{0, 94}},
+		{"gap formfeed", "SIZE 50 mm, 10 mm\nGAP 2 mm,0\nCLS\nPRINT 1\nFORMFEED\nPRINT 1\n", []int{0, 188}},
+		{"gap backfeed realigns", "SIZE 50 mm, 10 mm\nGAP 2 mm,0\nCLS\nPRINT 1\nBACKFEED 5\nFORMFEED\nPRINT 1\n", []int{0, 94}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := parser.ParseTSPL(tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			strip, err := RenderStrip(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, p := range strip.Placements {
+				got = append(got, p.Y)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("placements = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
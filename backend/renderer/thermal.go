@@ -0,0 +1,311 @@
+package renderer
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"tspl-simulator/barcode"
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+)
+
+// 熱感列印模擬的門檻: 累積熱量超過 activation 開始顯色, 再增加 span 後全黑
+const (
+	directActivation   = 0.7
+	transferActivation = 0.75
+	activationSpan     = 0.4
+	// printedDarkness 視為「印出」的墨色深度
+	printedDarkness = 0.5
+)
+
+// Thermal 熱感列印模擬結果, Dark 為每點的墨色深度 (0 為白紙, 255 為全黑)
+// 以每點一個位元組保存, 大尺寸標籤的模擬不需配置浮點數的整張緩衝區
+type Thermal struct {
+	Width  int
+	Height int
+	Energy float64 // 相對加熱能量, 預設濃度與速度約為 1.0
+	Dark   []uint8
+}
+
+// ThermalEnergy 由 DENSITY 與 SPEED 估計每個加熱點的相對能量
+// 濃度越高加熱時間越長; 速度越快每列可用的加熱時間越短
+func ThermalEnergy(s models.PrintSettings) float64 {
+	density := s.Density
+	if density < 0 {
+		density = 0
+	}
+	if density > 15 {
+		density = 15
+	}
+	speed := s.Speed
+	if speed <= 0 {
+		speed = parser.DefaultSpeed
+	}
+	speed = math.Max(1, math.Min(14, speed))
+	return (0.45 + 0.07*float64(density)) * math.Pow(parser.DefaultSpeed/speed, 0.4)
+}
+
+// SimulateThermal 模擬列印頭加熱的結果
+//   - 熱量累積: 走紙方向 (y) 上一列殘留的熱會加到下一列, 速度越快冷卻時間越短殘留越多
+//   - 熱擴散: 熱量向鄰近點擴散, 能量越高擴散範圍越大, 造成細空白被填滿 (暈開)
+//   - 顯色門檻: 孤立的細線得到的熱量較少, 能量不足時會斷線或消失
+//
+// 熱轉印 (SET RIBBON ON) 的碳帶邊緣較銳利, 擴散範圍較小且門檻略高
+// 逐列計算: 只保留上一列的熱量與垂直擴散所需的 2*radius+1 列
+func SimulateThermal(c *Canvas, s models.PrintSettings) *Thermal {
+	energy := ThermalEnergy(s)
+	speed := s.Speed
+	if speed <= 0 {
+		speed = parser.DefaultSpeed
+	}
+	carry := 0.12 + 0.015*math.Max(1, math.Min(14, speed))
+	sigma := 0.5 + 0.25*math.Max(energy-0.8, 0)
+	activation := directActivation
+	if s.Ribbon {
+		sigma *= 0.8
+		activation = transferActivation
+	}
+
+	// 以未正規化的高斯核擴散: 大面積區塊比細線得到更多鄰近熱量
+	const radius = 2
+	const window = radius*2 + 1
+	var kernel [window]float64
+	for d := -radius; d <= radius; d++ {
+		kernel[d+radius] = math.Exp(-float64(d*d) / (2 * sigma * sigma))
+	}
+
+	w, h := c.Width, c.Height
+	heat := make([]float64, w)
+	var spread [window][]float64
+	for i := range spread {
+		spread[i] = make([]float64, w)
+	}
+
+	// heatRow 累積第 y 列的熱量並做水平擴散, 結果放在 spread[y%window]
+	heatRow := func(y int) {
+		for x := 0; x < w; x++ {
+			v := carry * heat[x]
+			if c.Get(x, y) {
+				v += energy
+			}
+			heat[x] = v
+		}
+		row := spread[y%window]
+		for x := 0; x < w; x++ {
+			sum := 0.0
+			for d := -radius; d <= radius; d++ {
+				if xx := x + d; xx >= 0 && xx < w {
+					sum += kernel[d+radius] * heat[xx]
+				}
+			}
+			row[x] = sum
+		}
+	}
+
+	t := &Thermal{Width: w, Height: h, Energy: energy, Dark: make([]uint8, w*h)}
+	next := 0
+	for y := 0; y < h; y++ {
+		for ; next < h && next <= y+radius; next++ {
+			heatRow(next)
+		}
+		for x := 0; x < w; x++ {
+			sum := 0.0
+			for d := -radius; d <= radius; d++ {
+				if yy := y + d; yy >= 0 && yy < h {
+					sum += kernel[d+radius] * spread[yy%window][x]
+				}
+			}
+			dark := math.Max(0, math.Min(1, (sum-activation)/activationSpan))
+			t.Dark[y*w+x] = uint8(math.Round(255 * dark))
+		}
+	}
+	return t
+}
+
+// Printed 判斷 (x, y) 是否印出可辨識的黑點
+func (t *Thermal) Printed(x, y int) bool {
+	if x < 0 || y < 0 || x >= t.Width || y >= t.Height {
+		return false
+	}
+	return float64(t.Dark[y*t.Width+x]) >= 255*printedDarkness
+}
+
+// Canvas 以印出門檻轉換為 1 位元畫布, 供掃描驗證使用
+func (t *Thermal) Canvas() *Canvas {
+	c := NewCanvas(t.Width, t.Height)
+	for y := 0; y < t.Height; y++ {
+		for x := 0; x < t.Width; x++ {
+			if t.Printed(x, y) {
+				c.Set(x, y, true)
+			}
+		}
+	}
+	return c
+}
+
+// Image 轉換為灰階影像
+func (t *Thermal) Image() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, t.Width, t.Height))
+	for i, d := range t.Dark {
+		img.Pix[i] = 255 - d
+	}
+	return img
+}
+
+// Quality 熱感列印品質模擬結果
+type Quality struct {
+	Thermal  *Thermal
+	DotGain  float64
+	Dropouts int
+	Barcodes []models.BarcodeQuality
+	Warnings []string
+}
+
+// SimulateQuality 繪製標籤並模擬熱感列印, 量測每個一維條碼的窄條與窄空
+func SimulateQuality(data *models.RenderData) *Quality {
+	canvas, warnings := Render(data)
+	t := SimulateThermal(canvas, data.Print)
+	q := &Quality{Thermal: t, Warnings: warnings}
+
+	designed, printed := 0, 0
+	for y := 0; y < canvas.Height; y++ {
+		for x := 0; x < canvas.Width; x++ {
+			on := canvas.Get(x, y)
+			if on {
+				designed++
+			}
+			if t.Printed(x, y) {
+				printed++
+			} else if on {
+				q.Dropouts++
+			}
+		}
+	}
+	if designed > 0 {
+		q.DotGain = float64(printed-designed) / float64(designed)
+	}
+
+	for i, el := range data.Elements {
+		if el.Type != "barcode" {
+			continue
+		}
+		bq, err := measureBarcode(data, el, t)
+		if err != nil {
+			continue
+		}
+		bq.Element = i + 1
+		switch bq.Status {
+		case "dropout":
+			q.Warnings = append(q.Warnings, fmt.Sprintf("元素 %d (%s 條碼): 窄條 %d 點在目前濃度/速度下可能斷條或消失",
+				bq.Element, bq.Type, bq.Narrow))
+		case "bleed":
+			q.Warnings = append(q.Warnings, fmt.Sprintf("元素 %d (%s 條碼): 窄空白在目前濃度/速度下可能被暈開填滿",
+				bq.Element, bq.Type))
+		}
+		q.Barcodes = append(q.Barcodes, bq)
+	}
+	return q
+}
+
+// measureBarcode 沿條碼中線量測模擬列印後的最窄條與最窄空寬度
+func measureBarcode(data *models.RenderData, el models.Element, t *Thermal) (models.BarcodeQuality, error) {
+	props := el.Properties
+	pattern, err := barcode.Encode(StringProp(props, "type"), StringProp(props, "code"))
+	if err != nil {
+		return models.BarcodeQuality{}, err
+	}
+	widths := pattern.ModuleWidths(IntProp(props, "narrow"), IntProp(props, "wide"))
+	height := IntProp(props, "height")
+
+	orientation := "picket"
+	if rotation := IntProp(props, "rotation"); rotation == 90 || rotation == 270 {
+		orientation = "ladder"
+	}
+
+	// printedAt 查詢元素座標 (u, v) 的列印結果
+	point := ElementPoint(data, el)
+	printedAt := func(u, v int) bool {
+		pt := point(u, v)
+		return t.Printed(pt.X, pt.Y)
+	}
+
+	narrowBar, narrowSpace := 0, 0
+	for i, w := range widths {
+		if w == 0 {
+			continue
+		}
+		if i%2 == 0 && (narrowBar == 0 || w < narrowBar) {
+			narrowBar = w
+		}
+		if i%2 == 1 && i < len(widths)-1 && (narrowSpace == 0 || w < narrowSpace) {
+			narrowSpace = w
+		}
+	}
+
+	bq := models.BarcodeQuality{
+		Type:        StringProp(props, "type"),
+		Orientation: orientation,
+		Narrow:      narrowBar,
+	}
+
+	var barSum, spaceSum, bars, spaces int
+	v := height / 2
+	start := 0
+	for i, w := range widths {
+		end := start + w
+		dark := i%2 == 0
+		isNarrow := (dark && w == narrowBar) || (!dark && w == narrowSpace && i < len(widths)-1)
+		if w > 0 && isNarrow {
+			// 向兩側延伸至相鄰元素的一半, 量測暈開或縮小後的寬度
+			left, right := start, end
+			if i > 0 {
+				left -= widths[i-1] / 2
+			}
+			if i < len(widths)-1 {
+				right += widths[i+1] / 2
+			}
+			n := 0
+			center := printedAt((start+end-1)/2, v) == dark
+			if center {
+				for u := (start + end - 1) / 2; u >= left && printedAt(u, v) == dark; u-- {
+					n++
+				}
+				for u := (start+end-1)/2 + 1; u < right && printedAt(u, v) == dark; u++ {
+					n++
+				}
+			}
+			if dark {
+				barSum += n
+				bars++
+				if n == 0 {
+					bq.LostBars++
+				}
+			} else {
+				spaceSum += n
+				spaces++
+				if n == 0 {
+					bq.FilledGaps++
+				}
+			}
+		}
+		start = end
+	}
+
+	if bars > 0 {
+		bq.NarrowBar = math.Round(float64(barSum)/float64(bars)*100) / 100
+	}
+	if spaces > 0 {
+		bq.NarrowSpace = math.Round(float64(spaceSum)/float64(spaces)*100) / 100
+	}
+
+	switch {
+	case bq.LostBars > 0 || bq.NarrowBar < float64(narrowBar)/2:
+		bq.Status = "dropout"
+	case bq.FilledGaps > 0 || (spaces > 0 && bq.NarrowSpace < float64(narrowSpace)/2):
+		bq.Status = "bleed"
+	default:
+		bq.Status = "ok"
+	}
+	return bq, nil
+}
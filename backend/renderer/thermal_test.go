@@ -0,0 +1,50 @@
+package renderer
+
+import (
+	"testing"
+
+	"tspl-simulator/models"
+)
+
+func TestSimulateThermal(t *testing.T) {
+	c := NewCanvas(60, 40)
+	for y := 5; y < 35; y++ {
+		for x := 5; x < 25; x++ {
+			c.Set(x, y, true) // 實心區塊
+		}
+	}
+	for x := 30; x < 50; x++ {
+		c.Set(x, 20, true) // 1 點高的橫線, 沒有走紙方向累積的熱
+	}
+
+	tests := []struct {
+		name      string
+		settings  models.PrintSettings
+		block     bool
+		thinLine  bool
+		whiteEdge bool
+	}{
+		{"default", models.PrintSettings{Density: 8, Speed: 4}, true, true, true},
+		{"low energy drops thin lines", models.PrintSettings{Density: 1, Speed: 4}, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := SimulateThermal(c, tt.settings)
+			if th.Width != c.Width || th.Height != c.Height || len(th.Dark) != c.Width*c.Height {
+				t.Fatalf("尺寸 %dx%d (%d 點), want %dx%d", th.Width, th.Height, len(th.Dark), c.Width, c.Height)
+			}
+			if got := th.Printed(15, 20); got != tt.block {
+				t.Errorf("區塊中心 Printed = %v, want %v", got, tt.block)
+			}
+			if got := th.Printed(40, 20); got != tt.thinLine {
+				t.Errorf("細線 Printed = %v, want %v", got, tt.thinLine)
+			}
+			if got := !th.Printed(55, 20) && !th.Printed(15, 0); got != tt.whiteEdge {
+				t.Errorf("遠離墨點的白紙 = %v, want %v", got, tt.whiteEdge)
+			}
+			if img := th.Image(); img.GrayAt(55, 20).Y != 255 {
+				t.Errorf("白紙的灰階 = %d, want 255", img.GrayAt(55, 20).Y)
+			}
+		})
+	}
+}
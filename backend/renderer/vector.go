@@ -0,0 +1,218 @@
+package renderer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"tspl-simulator/models"
+)
+
+// maxPDFPages 多頁 PDF 最多輸出的頁數 (每張列印的標籤一頁)
+const maxPDFPages = 500
+
+// VectorOptions SVG/PDF 輸出選項
+type VectorOptions struct {
+	Grid int // 點格線間距 (點), 0 代表不顯示; 1 為每一點
+}
+
+// physicalSize 依 LabelSize 取得標籤實際寬高與單位 ("mm" 或 "in");
+// 沒有 SIZE 時依點數與 DPI 換算為毫米
+func physicalSize(data *models.RenderData) (float64, float64, string) {
+	size := data.LabelSize
+	if size.Width > 0 && size.Height > 0 {
+		if size.Unit == "inch" {
+			return size.Width, size.Height, "in"
+		}
+		return size.Width, size.Height, "mm"
+	}
+	dpi := data.DPI
+	if dpi <= 0 {
+		dpi = 203
+	}
+	return float64(data.Width) * 25.4 / float64(dpi), float64(data.Height) * 25.4 / float64(dpi), "mm"
+}
+
+// points 將實際尺寸換算為 PDF 的點 (1/72 吋)
+func points(v float64, unit string) float64 {
+	if unit == "in" {
+		return v * 72
+	}
+	return v * 72 / 25.4
+}
+
+// rects 將點陣合併為矩形: 每列連續的黑點為一段, 上下相鄰且範圍相同的段合併
+func (c *Canvas) rects() []image.Rectangle {
+	var out []image.Rectangle
+	open := map[[2]int]int{} // [起點, 終點] → out 中的索引
+	for y := 0; y < c.Height; y++ {
+		for x := 0; x < c.Width; {
+			if !c.Get(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < c.Width && c.Get(x, y) {
+				x++
+			}
+			key := [2]int{start, x}
+			if i, ok := open[key]; ok && out[i].Max.Y == y {
+				out[i].Max.Y = y + 1
+				continue
+			}
+			open[key] = len(out)
+			out = append(out, image.Rect(start, y, x, y+1))
+		}
+	}
+	return out
+}
+
+// RenderSVG 以 SVG 輸出標籤 (所有元素, 與 Render 相同), 每個點為向量矩形, 放大時保持銳利
+// width/height 為 LabelSize 的實際尺寸, viewBox 以點為單位
+func RenderSVG(w io.Writer, data *models.RenderData, opts VectorOptions) ([]string, error) {
+	canvas, warnings := Render(data)
+	width, height, unit := physicalSize(data)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%s%s" height="%s%s" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		number(width), unit, number(height), unit, canvas.Width, canvas.Height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", canvas.Width, canvas.Height)
+	bw.WriteString(`<path fill="#000" d="`)
+	for _, r := range canvas.rects() {
+		fmt.Fprintf(bw, "M%d %dh%dv%dh-%dz", r.Min.X, r.Min.Y, r.Dx(), r.Dy(), r.Dx())
+	}
+	bw.WriteString("\"/>\n")
+	if opts.Grid > 0 {
+		fmt.Fprintf(bw, `<defs><pattern id="grid" width="%d" height="%d" patternUnits="userSpaceOnUse">`, opts.Grid, opts.Grid)
+		fmt.Fprintf(bw, `<path d="M%d 0H0V%d" fill="none" stroke="#4a90d9" stroke-opacity="0.5" stroke-width="0.1"/></pattern></defs>`+"\n", opts.Grid, opts.Grid)
+		fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="url(#grid)"/>`+"\n", canvas.Width, canvas.Height)
+	}
+	bw.WriteString("</svg>\n")
+	return warnings, bw.Flush()
+}
+
+// RenderPDF 以 PDF 輸出列印工作: 每張列印的標籤 (PRINT 的組數 × 份數) 一頁, 頁面為 LabelSize 的實際尺寸
+// 沒有 PRINT 時輸出所有元素的單頁; 內容相同的頁面共用同一個內容串流
+func RenderPDF(w io.Writer, data *models.RenderData, opts VectorOptions) ([]string, error) {
+	width, height, unit := physicalSize(data)
+	pageW, pageH := points(width, unit), points(height, unit)
+
+	type page struct{ content int }
+	var (
+		pages    []page
+		streams  [][]byte
+		warnings []string
+	)
+	addLabel := func(elements []models.Element, count int) error {
+		canvas, w := RenderElements(data, elements)
+		warnings = append(warnings, w...)
+		stream, err := pdfContent(canvas, pageW, pageH, opts)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, stream)
+		for i := 0; i < count; i++ {
+			if len(pages) >= maxPDFPages {
+				warnings = append(warnings, fmt.Sprintf("PDF 最多輸出 %d 頁, 其餘已省略", maxPDFPages))
+				return nil
+			}
+			pages = append(pages, page{content: len(streams) - 1})
+		}
+		return nil
+	}
+
+	printed := false
+	for _, step := range data.Steps {
+		if step.Command != "PRINT" || len(pages) >= maxPDFPages {
+			continue
+		}
+		printed = true
+		if err := addLabel(data.Elements[step.ElementStart:step.ElementEnd], step.Sets*max(step.Copies, 1)); err != nil {
+			return warnings, err
+		}
+	}
+	if !printed {
+		if err := addLabel(data.Elements, 1); err != nil {
+			return warnings, err
+		}
+	}
+
+	// 物件編號: 1 Catalog, 2 Pages, 3.. 內容串流, 之後為各頁
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", ""}
+	for _, s := range streams {
+		objects = append(objects, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(s), s))
+	}
+	firstPage := len(objects) + 1
+	var kids bytes.Buffer
+	for i, p := range pages {
+		fmt.Fprintf(&kids, "%d 0 R ", firstPage+i)
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R >>",
+			number(pageW), number(pageH), 3+p.content))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(pages))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return warnings, err
+}
+
+// pdfContent 產生單頁的壓縮內容串流: 以點為單位繪製黑點矩形 (PDF 原點在左下, 先翻轉 y 軸)
+func pdfContent(canvas *Canvas, pageW, pageH float64, opts VectorOptions) ([]byte, error) {
+	var content bytes.Buffer
+	if canvas.Width > 0 && canvas.Height > 0 {
+		fmt.Fprintf(&content, "%s 0 0 %s 0 %s cm\n",
+			number(pageW/float64(canvas.Width)), number(-pageH/float64(canvas.Height)), number(pageH))
+	}
+	if rects := canvas.rects(); len(rects) > 0 {
+		content.WriteString("0 g\n")
+		for _, r := range rects {
+			fmt.Fprintf(&content, "%d %d %d %d re\n", r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+		}
+		content.WriteString("f\n")
+	}
+	if opts.Grid > 0 {
+		content.WriteString("0.29 0.56 0.85 RG 0.1 w\n")
+		for x := 0; x <= canvas.Width; x += opts.Grid {
+			fmt.Fprintf(&content, "%d 0 m %d %d l\n", x, x, canvas.Height)
+		}
+		for y := 0; y <= canvas.Height; y += opts.Grid {
+			fmt.Fprintf(&content, "0 %d m %d %d l\n", y, canvas.Width, y)
+		}
+		content.WriteString("S\n")
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(content.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// number 輸出最多六位小數的數值 (點與毫米/吋的換算比例需要足夠精度才能對齊頁面邊緣)
+func number(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
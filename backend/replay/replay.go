@@ -0,0 +1,328 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/renderer"
+	"tspl-simulator/storage"
+	"tspl-simulator/validator"
+	"tspl-simulator/verifier"
+)
+
+// 重送目標
+const (
+	TargetRender  = "render"  // 以目前的驗證、解析與渲染流程重新處理, 並與原本的結果比較
+	TargetMQTT    = "mqtt"    // 以 render_request 訊息發布到 MQTT 主題
+	TargetPrinter = "printer" // 將原始位元組送到打印機的 RAW 埠
+)
+
+// 重送的預設值與限制
+const (
+	DefaultPrinterPort = "9100"
+	DefaultTimeout     = 10 * time.Second
+	MaxRangeJobs       = 1000            // 批次重送的工作數上限
+	MaxRangeDuration   = 2 * time.Minute // 批次重送的總時間上限, 超過後其餘工作不重送
+)
+
+// ErrUnknownTarget 不支援的重送目標
+var ErrUnknownTarget = errors.New("未知的重送目標")
+
+// ErrPrinterNotAllowed 打印機位址不在允許清單中
+var ErrPrinterNotAllowed = errors.New("打印機位址不在允許清單中")
+
+// ErrRejectedNotIncluded 批次重送指定 status=rejected 但未明確要求包含驗證失敗的工作
+var ErrRejectedNotIncluded = errors.New("重送驗證失敗的工作須明確指定包含驗證失敗的工作")
+
+// Target 重送目標與其參數
+type Target struct {
+	Kind    string // render / mqtt / printer
+	Topic   string // mqtt: 發布的主題, 空字串時使用 Replayer.Topic
+	Printer string // printer: host[:port]
+}
+
+// Validate 檢查目標是否可用
+func (t Target) Validate() error {
+	switch t.Kind {
+	case TargetRender, TargetMQTT:
+		return nil
+	case TargetPrinter:
+		if t.Printer == "" {
+			return errors.New("未指定打印機位址")
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownTarget, t.Kind)
+	}
+}
+
+// Publisher 發布 MQTT 訊息 (由 mqtt.Client 實作)
+type Publisher interface {
+	Publish(topic string, message interface{}) error
+}
+
+// Replayer 由儲存服務讀取工作並重送
+type Replayer struct {
+	Storage  *storage.StorageService
+	MQTT     Publisher     // 為 nil 時無法重送到 MQTT
+	Topic    string        // MQTT 的預設主題
+	Timeout  time.Duration // 連線與傳送到打印機的逾時, 0 代表 DefaultTimeout
+	Printers []string      // 允許的打印機位址 host[:port] (未指定埠時為 DefaultPrinterPort), 空清單時不允許 printer 目標
+}
+
+// Check 檢查目標是否可用, printer 目標的位址須在 Printers 清單中 (否則回傳 ErrPrinterNotAllowed)
+func (r *Replayer) Check(target Target) error {
+	if err := target.Validate(); err != nil {
+		return err
+	}
+	if target.Kind == TargetPrinter && !r.printerAllowed(printerAddress(target.Printer)) {
+		return fmt.Errorf("%w: %s", ErrPrinterNotAllowed, printerAddress(target.Printer))
+	}
+	return nil
+}
+
+// printerAllowed 位址是否在允許清單中 (主機名稱不分大小寫)
+func (r *Replayer) printerAllowed(address string) bool {
+	for _, allowed := range r.Printers {
+		if strings.EqualFold(printerAddress(strings.TrimSpace(allowed)), address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replay 重送單一工作; 工作不存在時回傳 storage.ErrJobNotFound, 重送本身的失敗記錄在結果中
+func (r *Replayer) Replay(id string, target Target) (models.ReplayResult, error) {
+	return r.replay(id, target, time.Time{})
+}
+
+// replay 重送單一工作, deadline 不為零值時打印機的連線與傳送不超過該時間
+func (r *Replayer) replay(id string, target Target, deadline time.Time) (models.ReplayResult, error) {
+	job, code, err := r.Storage.GetJob(id)
+	if err != nil {
+		return models.ReplayResult{}, err
+	}
+	return r.replayJob(job, code, target, deadline), nil
+}
+
+// RangeStatus 批次重送選取的工作狀態: 預設只選取驗證通過的工作 (accepted),
+// 驗證失敗的工作 (status 為空字串或 rejected) 須明確指定 includeRejected 才會重送
+func RangeStatus(status string, includeRejected bool) (string, error) {
+	switch status {
+	case "":
+		if includeRejected {
+			return "", nil
+		}
+		return "accepted", nil
+	case "accepted":
+		return status, nil
+	case "rejected":
+		if !includeRejected {
+			return "", ErrRejectedNotIncluded
+		}
+		return status, nil
+	}
+	return "", errors.New("status 必須為 accepted 或 rejected")
+}
+
+// ReplayRange 依建立時間由舊到新重送符合條件的工作, 最多 limit 筆 (0 或超過上限時為 MaxRangeJobs)
+// 回傳的 truncated 表示符合的工作超過上限, 只重送了最早的部分;
+// 總時間超過 MaxRangeDuration 後其餘的工作不重送, 以失敗記錄在結果中
+func (r *Replayer) ReplayRange(q storage.JobQuery, limit int, target Target) (results []models.ReplayResult, total int, truncated bool, err error) {
+	if limit <= 0 || limit > MaxRangeJobs {
+		limit = MaxRangeJobs
+	}
+	// 以時間上限固定範圍, 避免重送到 MQTT 後新儲存的工作被再次選取
+	if q.To.IsZero() {
+		q.To = time.Now().Add(time.Millisecond)
+	}
+	q.Offset, q.Limit = 0, 1
+	if _, total, err = r.Storage.ListJobs(q); err != nil {
+		return nil, 0, false, err
+	}
+	// 列表由新到舊, 取最舊的 limit 筆
+	q.Offset, q.Limit = max(total-limit, 0), limit
+	jobs, _, err := r.Storage.ListJobs(q)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return storage.JobNewer(jobs[j], jobs[i])
+	})
+
+	deadline := time.Now().Add(MaxRangeDuration)
+	results = make([]models.ReplayResult, 0, len(jobs))
+	for _, job := range jobs {
+		if !time.Now().Before(deadline) {
+			results = append(results, models.ReplayResult{
+				JobID:  job.ID,
+				Target: target.Kind,
+				Error:  fmt.Sprintf("超過批次重送的時間上限 %s, 未重送", MaxRangeDuration),
+			})
+			continue
+		}
+		result, err := r.replay(job.ID, target, deadline)
+		if errors.Is(err, storage.ErrJobNotFound) {
+			continue
+		}
+		if err != nil {
+			result = models.ReplayResult{JobID: job.ID, Target: target.Kind, Error: err.Error()}
+		}
+		results = append(results, result)
+	}
+	return results, total, total > limit, nil
+}
+
+// replayJob 依目標重送工作
+func (r *Replayer) replayJob(job *models.Job, code string, target Target, deadline time.Time) models.ReplayResult {
+	result := models.ReplayResult{JobID: job.ID, Target: target.Kind}
+	start := time.Now()
+	err := r.Check(target)
+	switch {
+	case err != nil:
+	case target.Kind == TargetRender:
+		result.Render = render(job, code)
+	case target.Kind == TargetMQTT:
+		result.Topic = target.Topic
+		if result.Topic == "" {
+			result.Topic = r.Topic
+		}
+		err = r.publish(job, code, result.Topic)
+	case target.Kind == TargetPrinter:
+		result.Printer = printerAddress(target.Printer)
+		result.BytesSent, err = r.send(result.Printer, code, deadline)
+	}
+	result.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+	}
+	return result
+}
+
+// render 以目前的流程重新驗證、解析、渲染並掃描驗證, 不另存新的工作
+func render(job *models.Job, code string) *models.ReplayRender {
+	validationResult := validator.ValidateTSPL(code)
+	rr := &models.ReplayRender{}
+	if !validationResult.Valid {
+		rr.Metadata = storage.NewJobMetadata(job.Source, validationResult, nil, 0, nil)
+		rr.Changes = changes(job.Metadata, rr.Metadata)
+		return rr
+	}
+
+	start := time.Now()
+	renderData, err := parser.ParseTSPLWithStore(code, parser.NewScratchStore())
+	rr.Metadata = storage.NewJobMetadata(job.Source, validationResult, renderData, time.Since(start), err)
+	if err == nil {
+		canvas, warnings := renderer.Render(renderData)
+		rr.RenderWarnings = warnings
+		for _, scan := range verifier.Verify(renderData, canvas) {
+			if !scan.Match {
+				rr.ScanFailures++
+			}
+		}
+	}
+	rr.Changes = changes(job.Metadata, rr.Metadata)
+	return rr
+}
+
+// changes 比較原本儲存與重新處理的中繼資料; 舊版工作沒有中繼資料時不比較
+func changes(before, after *models.JobMetadata) []string {
+	if before == nil {
+		return nil
+	}
+	var out []string
+	if before.Valid != after.Valid {
+		out = append(out, fmt.Sprintf("驗證結果: %s → %s", validLabel(before.Valid), validLabel(after.Valid)))
+	}
+	if len(before.ValidationErrors) != len(after.ValidationErrors) {
+		out = append(out, fmt.Sprintf("驗證錯誤: %d → %d 個", len(before.ValidationErrors), len(after.ValidationErrors)))
+	}
+	if len(before.ValidationWarnings) != len(after.ValidationWarnings) {
+		out = append(out, fmt.Sprintf("驗證警告: %d → %d 個", len(before.ValidationWarnings), len(after.ValidationWarnings)))
+	}
+	if before.ParseError != after.ParseError {
+		out = append(out, fmt.Sprintf("解析錯誤: %q → %q", before.ParseError, after.ParseError))
+	}
+	types := map[string]bool{}
+	for t := range before.ElementCounts {
+		types[t] = true
+	}
+	for t := range after.ElementCounts {
+		types[t] = true
+	}
+	names := make([]string, 0, len(types))
+	for t := range types {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	for _, t := range names {
+		if b, a := before.ElementCounts[t], after.ElementCounts[t]; b != a {
+			out = append(out, fmt.Sprintf("%s 元素: %d → %d 個", t, b, a))
+		}
+	}
+	return out
+}
+
+// validLabel 驗證結果的說明
+func validLabel(valid bool) string {
+	if valid {
+		return "通過"
+	}
+	return "失敗"
+}
+
+// publish 以 render_request 訊息發布工作
+func (r *Replayer) publish(job *models.Job, code, topic string) error {
+	if r.MQTT == nil {
+		return errors.New("MQTT 未連接")
+	}
+	if topic == "" {
+		return errors.New("未指定 MQTT 主題")
+	}
+	return r.MQTT.Publish(topic, models.MQTTMessage{
+		Type:      "render_request",
+		TSPLCode:  code,
+		Timestamp: time.Now().Unix(),
+		RequestID: "replay-" + job.ID,
+	})
+}
+
+// send 將 TSPL 原始位元組送到打印機; deadline 不為零值時逾時不超過該時間
+func (r *Replayer) send(address, code string, deadline time.Time) (int, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if !deadline.IsZero() {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("連接打印機失敗: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+	n, err := conn.Write([]byte(code))
+	if err != nil {
+		return n, fmt.Errorf("傳送到打印機失敗: %v", err)
+	}
+	return n, nil
+}
+
+// printerAddress 補上預設的 RAW 埠
+func printerAddress(address string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(address, DefaultPrinterPort)
+}
@@ -0,0 +1,82 @@
+package replay
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tspl-simulator/storage"
+)
+
+func TestRangeStatus(t *testing.T) {
+	tests := []struct {
+		status          string
+		includeRejected bool
+		want            string
+		err             error
+	}{
+		{"", false, "accepted", nil},
+		{"", true, "", nil},
+		{"accepted", false, "accepted", nil},
+		{"accepted", true, "accepted", nil},
+		{"rejected", true, "rejected", nil},
+		{"rejected", false, "", ErrRejectedNotIncluded},
+	}
+	for _, tt := range tests {
+		got, err := RangeStatus(tt.status, tt.includeRejected)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("RangeStatus(%q, %v) = %q, %v; want %q, %v", tt.status, tt.includeRejected, got, err, tt.want, tt.err)
+		}
+	}
+	if _, err := RangeStatus("pending", true); err == nil {
+		t.Error("未知的 status 應回傳錯誤")
+	}
+}
+
+func TestReplayRangeSkipsRejectedByDefault(t *testing.T) {
+	s := storage.NewStorageServiceWithBackend(storage.NewMemoryBackend())
+	if _, err := s.SaveAPIData("SIZE 50 mm, 30 mm\nCLS\nPRINT 1\n", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SaveRejectedAPIData("SIZE 50 mm, 30 mm\nBOGUS\n", nil); err != nil {
+		t.Fatal(err)
+	}
+	jobs, _, err := s.ListJobs(storage.JobQuery{})
+	if err != nil || len(jobs) != 2 {
+		t.Fatalf("ListJobs = %v, %v", jobs, err)
+	}
+	rejected, accepted := jobs[0].ID, jobs[1].ID
+	r := &Replayer{Storage: s}
+	from := time.Now().Add(-time.Hour)
+
+	for _, tt := range []struct {
+		name            string
+		includeRejected bool
+		want            []string
+	}{
+		{"default", false, []string{accepted}},
+		{"include rejected", true, []string{accepted, rejected}},
+	} {
+		status, err := RangeStatus("", tt.includeRejected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, total, _, err := r.ReplayRange(storage.JobQuery{Status: status, From: from}, 0, Target{Kind: TargetRender})
+		if err != nil {
+			t.Fatalf("%s: ReplayRange: %v", tt.name, err)
+		}
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.JobID)
+		}
+		if total != len(tt.want) || len(ids) != len(tt.want) {
+			t.Fatalf("%s: 重送 %v (total %d), want %v", tt.name, ids, total, tt.want)
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("%s: 重送 %v, want %v", tt.name, ids, tt.want)
+				break
+			}
+		}
+	}
+}
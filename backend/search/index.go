@@ -0,0 +1,373 @@
+package search
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/storage"
+)
+
+// 索引的限制
+const (
+	maxLineLength  = 4096 // 超過此長度的行 (多半是 BITMAP 等二進位資料) 不索引原始行
+	maxValueLength = 256  // 搜尋結果中每個命中值的最大長度
+	compactMinDead = 1024 // 已刪除的文件超過此數量且多於有效文件時重建倒排表
+	rebuildPage    = 500
+)
+
+// Field 工作中可搜尋的一個值: 原始行或由該行解析出的元素屬性
+type Field struct {
+	Line    int    // 原始 TSPL 行號
+	Command string // TSPL 指令 (大寫), 註解行為空字串
+	Name    string // text / code / data (元素屬性) 或 line (原始行)
+	Value   string
+}
+
+// Query 搜尋條件
+type Query struct {
+	Text    string    // 以空白分隔的關鍵字, 每個關鍵字不分大小寫地比對子字串, 全部命中的工作才列出
+	Command string    // 只比對此指令的行 (例如 BARCODE), 空字串代表全部
+	Source  string    // api / mqtt, 空字串代表全部
+	From    time.Time // 建立時間下限 (含), 零值代表不限
+	To      time.Time // 建立時間上限 (不含), 零值代表不限
+	Offset  int
+	Limit   int // 0 代表不限
+}
+
+// Result 一筆命中的工作
+type Result struct {
+	Job     models.Job
+	Matches []Field
+}
+
+// document 索引中的一筆工作
+type document struct {
+	job    models.Job
+	fields []Field
+}
+
+// Index 儲存工作的倒排索引: 以小寫值的三字元組 (trigram) 找出候選工作, 再逐欄位比對子字串,
+// 因此 SKU 或追蹤號碼的一部分也能找到
+type Index struct {
+	mu    sync.RWMutex
+	docs  []*document      // 依加入順序, 刪除後為 nil
+	ids   map[string]int   // 工作 ID → docs 的位置
+	grams map[string][]int // trigram → 含有的文件位置 (遞增)
+	dead  int
+
+	rebuilding bool
+	removed    map[string]bool // 重建期間刪除的工作, 重建不再加入
+}
+
+// NewIndex 建立空的索引
+func NewIndex() *Index {
+	return &Index{ids: map[string]int{}, grams: map[string][]int{}}
+}
+
+// JobSaved 實作 storage.Listener: 索引新儲存的工作
+func (x *Index) JobSaved(job models.Job, code string) {
+	x.Add(job, code)
+}
+
+// JobDeleted 實作 storage.Listener: 從索引移除工作
+func (x *Index) JobDeleted(id string) {
+	x.Remove(id)
+}
+
+// Add 索引工作; 已存在時取代
+func (x *Index) Add(job models.Job, code string) {
+	job.Metadata = nil
+	doc := &document{job: job, fields: Fields(code)}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(job.ID)
+	x.addLocked(doc)
+}
+
+// Remove 從索引移除工作
+func (x *Index) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.rebuilding {
+		x.removed[id] = true
+	}
+	x.removeLocked(id)
+}
+
+// addLocked 加入文件並更新倒排表
+func (x *Index) addLocked(doc *document) {
+	n := len(x.docs)
+	x.docs = append(x.docs, doc)
+	x.ids[doc.job.ID] = n
+	for gram := range docGrams(doc) {
+		x.grams[gram] = append(x.grams[gram], n)
+	}
+}
+
+// removeLocked 將文件標記為已刪除, 已刪除的文件過多時重建倒排表
+func (x *Index) removeLocked(id string) {
+	n, ok := x.ids[id]
+	if !ok {
+		return
+	}
+	x.docs[n] = nil
+	delete(x.ids, id)
+	x.dead++
+	if x.dead > compactMinDead && x.dead > len(x.ids) {
+		docs := x.docs
+		x.docs, x.ids, x.grams, x.dead = nil, map[string]int{}, map[string][]int{}, 0
+		for _, doc := range docs {
+			if doc != nil {
+				x.addLocked(doc)
+			}
+		}
+	}
+}
+
+// Len 索引中的工作數
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.ids)
+}
+
+// Rebuilding 是否正在由儲存服務重建索引 (搜尋結果可能不完整)
+func (x *Index) Rebuilding() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.rebuilding
+}
+
+// Rebuild 由儲存服務讀取所有工作重建索引; 重建期間新儲存的工作照常加入
+func (x *Index) Rebuild(s *storage.StorageService) error {
+	x.mu.Lock()
+	x.rebuilding, x.removed = true, map[string]bool{}
+	x.mu.Unlock()
+	defer func() {
+		x.mu.Lock()
+		x.rebuilding, x.removed = false, nil
+		x.mu.Unlock()
+	}()
+
+	// 以時間上限固定範圍, 避免重建期間新增的工作加入分頁; 以上一頁最後一筆為游標取下一頁,
+	// 重建期間刪除的工作不會使後面的工作前移而漏掉
+	to := time.Now().Add(time.Millisecond)
+	var after *storage.Cursor
+	for {
+		jobs, _, err := s.ListJobs(storage.JobQuery{To: to, After: after, Limit: rebuildPage})
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			_, code, err := s.GetJob(job.ID)
+			if errors.Is(err, storage.ErrJobNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			job.Metadata = nil
+			doc := &document{job: job, fields: Fields(code)}
+
+			x.mu.Lock()
+			if _, ok := x.ids[job.ID]; !ok && !x.removed[job.ID] {
+				x.addLocked(doc)
+			}
+			x.mu.Unlock()
+		}
+		if len(jobs) < rebuildPage {
+			return nil
+		}
+		last := jobs[len(jobs)-1]
+		after = &storage.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// Search 依條件搜尋工作 (最新的在前), 並回傳分頁前符合條件的總數
+func (x *Index) Search(q Query) ([]Result, int) {
+	terms := strings.Fields(strings.ToLower(q.Text))
+	command := strings.ToUpper(q.Command)
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// 由各關鍵字的 trigram 交集出候選文件; 少於 3 個字元的關鍵字無法縮小範圍
+	var candidates []int
+	narrowed := false
+	for _, term := range terms {
+		for i := 0; i+3 <= len(term); i++ {
+			postings := x.grams[term[i:i+3]]
+			if !narrowed {
+				candidates, narrowed = postings, true
+			} else {
+				candidates = intersect(candidates, postings)
+			}
+		}
+	}
+	if !narrowed {
+		candidates = make([]int, len(x.docs))
+		for i := range candidates {
+			candidates[i] = i
+		}
+	}
+
+	var results []Result
+	for _, n := range candidates {
+		doc := x.docs[n]
+		if doc == nil || !q.matchesJob(doc.job) {
+			continue
+		}
+		if matches, ok := matchFields(doc.fields, terms, command); ok {
+			results = append(results, Result{Job: doc.job, Matches: matches})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return storage.JobNewer(results[i].Job, results[j].Job)
+	})
+	total := len(results)
+	if q.Offset > 0 {
+		results = results[min(q.Offset, total):]
+	}
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results, total
+}
+
+// matchesJob 判斷工作是否符合來源與時間條件
+func (q *Query) matchesJob(job models.Job) bool {
+	if q.Source != "" && q.Source != job.Source {
+		return false
+	}
+	if !q.From.IsZero() && job.CreatedAt.Before(q.From) {
+		return false
+	}
+	return q.To.IsZero() || job.CreatedAt.Before(q.To)
+}
+
+// matchFields 每個關鍵字都須命中至少一個欄位; 回傳命中的欄位
+// 同一行已由元素屬性命中時, 不再列出該行的原始內容
+func matchFields(fields []Field, terms []string, command string) ([]Field, bool) {
+	hit := make([]bool, len(terms))
+	var matches []Field
+	matchedLine := map[int]bool{}
+	for _, f := range fields {
+		if command != "" && f.Command != command {
+			continue
+		}
+		value := strings.ToLower(f.Value)
+		found := false
+		for i, term := range terms {
+			if strings.Contains(value, term) {
+				hit[i], found = true, true
+			}
+		}
+		if !found || (f.Name == "line" && matchedLine[f.Line]) {
+			continue
+		}
+		matchedLine[f.Line] = true
+		matches = append(matches, Field{Line: f.Line, Command: f.Command, Name: f.Name, Value: truncate(f.Value, maxValueLength)})
+	}
+	for _, ok := range hit {
+		if !ok {
+			return nil, false
+		}
+	}
+	return matches, len(terms) > 0
+}
+
+// Fields 取出 TSPL 中可搜尋的值: 每一行的原始內容 (DOWNLOAD 不含檔案資料), 以及
+// TEXT/BARCODE/QRCODE 解析出的 text、code、data 屬性 (逐行解析, 其他行的錯誤不影響)
+func Fields(code string) []Field {
+	var fields []Field
+	for _, sl := range parser.SplitSource(code) {
+		text := strings.TrimSpace(sl.Text)
+		if text == "" {
+			continue
+		}
+		command := ""
+		if !strings.HasPrefix(text, ";") {
+			command = strings.ToUpper(strings.Fields(text)[0])
+			command = strings.TrimSuffix(command, ",")
+		}
+
+		switch command {
+		case "TEXT", "BARCODE", "QRCODE":
+			if data, err := parser.ParseTSPLWithStore(text, parser.NewScratchStore()); err == nil {
+				for _, el := range data.Elements {
+					for _, name := range []string{"text", "code", "data"} {
+						if v, ok := el.Properties[name].(string); ok && v != "" {
+							fields = append(fields, Field{Line: sl.Line, Command: command, Name: name, Value: strings.Clone(v)})
+						}
+					}
+				}
+			}
+		}
+		if len(text) <= maxLineLength && printable(text) {
+			fields = append(fields, Field{Line: sl.Line, Command: command, Name: "line", Value: strings.Clone(text)})
+		}
+	}
+	return fields
+}
+
+// docGrams 文件所有欄位的小寫 trigram
+func docGrams(doc *document) map[string]struct{} {
+	grams := map[string]struct{}{}
+	for _, f := range doc.fields {
+		value := strings.ToLower(f.Value)
+		for i := 0; i+3 <= len(value); i++ {
+			grams[value[i:i+3]] = struct{}{}
+		}
+	}
+	return grams
+}
+
+// intersect 兩個遞增序列的交集
+func intersect(a, b []int) []int {
+	var out []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// printable 判斷是否為不含控制字元的有效 UTF-8 文字
+func printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// truncate 截斷過長的值 (不切斷 UTF-8 字元)
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"sync"
+	"testing"
+
+	"tspl-simulator/storage"
+)
+
+// deletingBackend 取第二頁前刪除第一頁的工作, 模擬重建期間同時刪除工作
+type deletingBackend struct {
+	storage.Backend
+	once    sync.Once
+	deleted int
+}
+
+func (b *deletingBackend) List(q storage.Query) ([]storage.Record, int, error) {
+	recs, total, err := b.Backend.List(q)
+	if err != nil || q.Limit != rebuildPage {
+		return recs, total, err
+	}
+	b.once.Do(func() {
+		for _, rec := range recs[:rebuildPage/2] {
+			if err := b.Backend.Delete(rec.ID); err == nil {
+				b.deleted++
+			}
+		}
+	})
+	return recs, total, nil
+}
+
+func TestRebuildWithConcurrentDeletes(t *testing.T) {
+	backend := &deletingBackend{Backend: storage.NewMemoryBackend()}
+	s := storage.NewStorageServiceWithBackend(backend)
+	const jobs = rebuildPage*2 + 10
+	for i := 0; i < jobs; i++ {
+		if _, err := s.SaveAPIData("SIZE 50 mm, 30 mm\nPRINT 1\n", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	x := NewIndex()
+	if err := x.Rebuild(s); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if backend.deleted != rebuildPage/2 {
+		t.Fatalf("刪除了 %d 筆, want %d", backend.deleted, rebuildPage/2)
+	}
+	// 第一頁的工作在取得原始碼前已刪除, 其餘工作都必須建立索引
+	if got, want := x.Len(), jobs-rebuildPage/2; got != want {
+		t.Errorf("索引工作數 = %d, want %d", got, want)
+	}
+	if x.Rebuilding() {
+		t.Error("重建結束後 Rebuilding 仍為 true")
+	}
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"tspl-simulator/models"
+)
+
+// ErrJobNotFound 找不到指定的列印工作 (或工作 ID 格式錯誤)
+var ErrJobNotFound = errors.New("找不到列印工作")
+
+// ErrJobExists 工作 ID 已存在 (儲存後端不覆蓋既有的工作)
+var ErrJobExists = errors.New("列印工作已存在")
+
+// ErrReadOnly 以唯讀方式開啟的儲存後端不可建立或刪除工作
+var ErrReadOnly = errors.New("儲存後端為唯讀")
+
+// jobBuckets 工作 ID 前綴 (來源[-rejected]) 與檔案/物件資料夾的對應; 驗證失敗的工作另存於 *_rejected
+var jobBuckets = map[string]string{
+	"api":           "API_print",
+	"mqtt":          "MQTT_print",
+	"api-rejected":  "API_rejected",
+	"mqtt-rejected": "MQTT_rejected",
+}
+
+// jobIDPattern 工作 ID: 來源[-rejected]-ULID
+var jobIDPattern = regexp.MustCompile(`^((?:api|mqtt)(?:-rejected)?)-([0-7][0-9A-HJKMNP-TV-Z]{25})$`)
+
+// Record 儲存後端中的一筆列印工作
+type Record struct {
+	ID        string              `json:"id"`
+	Bucket    string              `json:"bucket"` // api / mqtt / api-rejected / mqtt-rejected
+	CreatedAt time.Time           `json:"created_at"`
+	Size      int64               `json:"size"`
+	Hash      string              `json:"hash,omitempty"` // TSPL 內容的 SHA-256 (十六進位)
+	Location  string              `json:"-"`              // 後端中的位置 (檔案路徑、物件鍵), 供記錄使用
+	Data      []byte              `json:"-"`              // TSPL 原始碼, 僅 Put/Get 使用
+	Metadata  *models.JobMetadata `json:"metadata,omitempty"`
+}
+
+// Query 儲存後端的查詢條件, 結果依建立時間由新到舊排序
+type Query struct {
+	Buckets []string  // 空代表全部
+	From    time.Time // 建立時間下限 (含), 零值代表不限
+	To      time.Time // 建立時間上限 (不含), 零值代表不限
+	Hash    string    // 內容雜湊, 空字串代表不限
+	After   *Cursor   // 只列出排序在游標之後 (較舊) 的工作, nil 代表不限
+	Offset  int
+	Limit   int  // 0 代表不限
+	Meta    bool // 是否載入中繼資料
+}
+
+// Cursor 分頁游標: 上一頁最後一筆工作的建立時間與 ID
+// 以游標取下一頁不受其間刪除或新增的工作影響, 不會像位移一樣漏掉或重複
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Backend 列印工作的儲存後端
+type Backend interface {
+	// Put 建立工作 (含 Data 與 Metadata); ID 已存在時回傳 ErrJobExists, 成功時填入 Location
+	Put(rec *Record) error
+	// Get 取得工作, 含 Data 與 Metadata
+	Get(id string) (*Record, error)
+	// Delete 刪除工作與中繼資料
+	Delete(id string) error
+	// List 依條件查詢工作 (不含 Data), 並回傳分頁前符合條件的總數
+	List(q Query) ([]Record, int, error)
+	// Close 釋放後端資源
+	Close() error
+}
+
+// Job 轉換為 API 模型
+func (r *Record) Job() models.Job {
+	source, rejected := strings.CutSuffix(r.Bucket, "-rejected")
+	return models.Job{
+		ID:        r.ID,
+		Source:    source,
+		Rejected:  rejected,
+		CreatedAt: r.CreatedAt,
+		Size:      r.Size,
+		Hash:      r.Hash,
+		Metadata:  r.Metadata,
+	}
+}
+
+// ContentHash 計算 TSPL 內容的 SHA-256
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// parseJobID 拆解 來源[-rejected]-ULID 格式的工作 ID
+func parseJobID(id string) (bucket, ulid string, ok bool) {
+	m := jobIDPattern.FindStringSubmatch(id)
+	if m == nil {
+		return "", "", false
+	}
+	if _, ok := ulidTime(m[2]); !ok {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// matches 判斷工作是否符合查詢條件
+func (q *Query) matches(rec *Record) bool {
+	if len(q.Buckets) > 0 && !q.hasBucket(rec.Bucket) {
+		return false
+	}
+	if !q.From.IsZero() && rec.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !rec.CreatedAt.Before(q.To) {
+		return false
+	}
+	if !q.afterCursor(rec) {
+		return false
+	}
+	return q.Hash == "" || q.Hash == rec.Hash
+}
+
+// afterCursor 工作是否排序在游標之後 (較舊); 沒有游標時一律成立
+func (q *Query) afterCursor(rec *Record) bool {
+	return q.After == nil || newer(q.After.CreatedAt, q.After.ID, rec.CreatedAt, rec.ID)
+}
+
+// hasBucket 查詢是否包含指定的工作類別
+func (q *Query) hasBucket(bucket string) bool {
+	if len(q.Buckets) == 0 {
+		return true
+	}
+	for _, b := range q.Buckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// JobNewer 判斷工作 a 是否比 b 新: 依建立時間, 同一毫秒時依 ID 結尾的 ULID
+// (跨來源單調遞增, 不可直接比較整個 ID, 否則 api-rejected-... 一律排在 api-... 之前)
+func JobNewer(a, b models.Job) bool {
+	return newer(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+}
+
+// newer 依 (建立時間, ULID) 比較兩個工作
+func newer(at time.Time, aID string, bt time.Time, bID string) bool {
+	if !at.Equal(bt) {
+		return at.After(bt)
+	}
+	return idSortKey(aID) > idSortKey(bID)
+}
+
+// idSortKey 工作 ID 的排序鍵: ULID 格式取結尾的 ULID, 舊版 ID 使用整個 ID
+func idSortKey(id string) string {
+	if n := len(id) - ulidLength; n > 0 && id[n-1] == '-' {
+		return id[n:]
+	}
+	return id
+}
+
+// paginate 將符合條件的工作由新到舊排序後分頁, 回傳該頁與總數
+func paginate(recs []Record, q Query) ([]Record, int) {
+	sort.Slice(recs, func(i, j int) bool {
+		return newer(recs[i].CreatedAt, recs[i].ID, recs[j].CreatedAt, recs[j].ID)
+	})
+	total := len(recs)
+	if q.Offset > 0 {
+		recs = recs[min(q.Offset, total):]
+	}
+	if q.Limit > 0 && len(recs) > q.Limit {
+		recs = recs[:q.Limit]
+	}
+	return recs, total
+}
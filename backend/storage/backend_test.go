@@ -0,0 +1,376 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"tspl-simulator/models"
+)
+
+// conformanceRecord 建立指定類別與時間的工作
+func conformanceRecord(t *testing.T, bucket string, at time.Time, data string, meta *models.JobMetadata) *Record {
+	t.Helper()
+	ulid, createdAt := ulidAt(at)
+	return &Record{
+		ID:        bucket + "-" + ulid,
+		Bucket:    bucket,
+		CreatedAt: createdAt,
+		Size:      int64(len(data)),
+		Hash:      ContentHash([]byte(data)),
+		Data:      []byte(data),
+		Metadata:  meta,
+	}
+}
+
+// listIDs 依序列出查詢結果的工作 ID
+func listIDs(t *testing.T, b Backend, q Query) ([]string, int) {
+	t.Helper()
+	recs, total, err := b.List(q)
+	if err != nil {
+		t.Fatalf("List(%+v): %v", q, err)
+	}
+	ids := []string{}
+	for _, rec := range recs {
+		if rec.Data != nil {
+			t.Errorf("List 不應回傳 Data: %s", rec.ID)
+		}
+		ids = append(ids, rec.ID)
+	}
+	return ids, total
+}
+
+// testBackendConformance 所有儲存後端都必須符合的行為
+func testBackendConformance(t *testing.T, b Backend) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	meta := &models.JobMetadata{Source: "api", Valid: true, RequestID: "req-1", ElementCounts: map[string]int{"text": 2}}
+	oldest := conformanceRecord(t, "api", base, "SIZE 50 mm, 30 mm\n", meta)
+	rejected := conformanceRecord(t, "mqtt-rejected", base.Add(24*time.Hour), "SIZE 40 mm\n", nil)
+	newest := conformanceRecord(t, "api", base.Add(48*time.Hour), "SIZE 50 mm, 30 mm\n", nil)
+
+	for _, rec := range []*Record{oldest, rejected, newest} {
+		if err := b.Put(rec); err != nil {
+			t.Fatalf("Put(%s): %v", rec.ID, err)
+		}
+		if rec.Location == "" {
+			t.Errorf("Put(%s) 沒有填入 Location", rec.ID)
+		}
+	}
+
+	t.Run("put existing", func(t *testing.T) {
+		dup := *oldest
+		dup.Data = []byte("SIZE 1,1\n")
+		if err := b.Put(&dup); !errors.Is(err, ErrJobExists) {
+			t.Fatalf("重複的 ID: got %v, want ErrJobExists", err)
+		}
+		got, err := b.Get(oldest.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got.Data) != string(oldest.Data) {
+			t.Errorf("既有的工作被覆蓋: %q", got.Data)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		got, err := b.Get(oldest.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != oldest.ID || got.Bucket != "api" || got.Size != oldest.Size || got.Hash != oldest.Hash {
+			t.Errorf("Get = %+v, want %+v", got, oldest)
+		}
+		if !got.CreatedAt.Equal(oldest.CreatedAt) {
+			t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, oldest.CreatedAt)
+		}
+		if string(got.Data) != string(oldest.Data) {
+			t.Errorf("Data = %q, want %q", got.Data, oldest.Data)
+		}
+		if !reflect.DeepEqual(got.Metadata, meta) {
+			t.Errorf("Metadata = %+v, want %+v", got.Metadata, meta)
+		}
+
+		got, err = b.Get(rejected.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Metadata != nil {
+			t.Errorf("沒有中繼資料的工作: Metadata = %+v", got.Metadata)
+		}
+	})
+
+	t.Run("get missing", func(t *testing.T) {
+		missing, _ := ulidAt(base)
+		for _, id := range []string{"api-" + missing, "api-not-a-ulid", "printer-" + missing, ""} {
+			if _, err := b.Get(id); !errors.Is(err, ErrJobNotFound) {
+				t.Errorf("Get(%q): got %v, want ErrJobNotFound", id, err)
+			}
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			query Query
+			want  []string
+			total int
+		}{
+			{"all newest first", Query{}, []string{newest.ID, rejected.ID, oldest.ID}, 3},
+			{"bucket", Query{Buckets: []string{"api"}}, []string{newest.ID, oldest.ID}, 2},
+			{"rejected bucket", Query{Buckets: []string{"api-rejected", "mqtt-rejected"}}, []string{rejected.ID}, 1},
+			{"from", Query{From: base.Add(24 * time.Hour)}, []string{newest.ID, rejected.ID}, 2},
+			{"to exclusive", Query{To: base.Add(48 * time.Hour)}, []string{rejected.ID, oldest.ID}, 2},
+			{"hash", Query{Hash: oldest.Hash}, []string{newest.ID, oldest.ID}, 2},
+			{"offset and limit", Query{Offset: 1, Limit: 1}, []string{rejected.ID}, 3},
+			{"offset past end", Query{Offset: 5}, []string{}, 3},
+			{"after cursor", Query{After: &Cursor{CreatedAt: newest.CreatedAt, ID: newest.ID}}, []string{rejected.ID, oldest.ID}, 2},
+			{"after cursor and limit", Query{After: &Cursor{CreatedAt: rejected.CreatedAt, ID: rejected.ID}, Limit: 1}, []string{oldest.ID}, 1},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ids, total := listIDs(t, b, tt.query)
+				if !reflect.DeepEqual(ids, tt.want) || total != tt.total {
+					t.Errorf("List = %v (total %d), want %v (total %d)", ids, total, tt.want, tt.total)
+				}
+			})
+		}
+
+		recs, _, err := b.List(Query{Buckets: []string{"api"}, Meta: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, rec := range recs {
+			want := (*models.JobMetadata)(nil)
+			if rec.ID == oldest.ID {
+				want = meta
+			}
+			if !reflect.DeepEqual(rec.Metadata, want) {
+				t.Errorf("List Meta %s: Metadata = %+v, want %+v", rec.ID, rec.Metadata, want)
+			}
+			if rec.Size != int64(len(oldest.Data)) {
+				t.Errorf("List %s: Size = %d", rec.ID, rec.Size)
+			}
+		}
+	})
+
+	t.Run("same millisecond order", func(t *testing.T) {
+		at := base.Add(72 * time.Hour)
+		first := conformanceRecord(t, "api-rejected", at, "A\n", nil)
+		second := conformanceRecord(t, "api", at, "B\n", nil)
+		if first.ID[len(first.ID)-ulidLength:] > second.ID[len(second.ID)-ulidLength:] {
+			first, second = second, first
+		}
+		for _, rec := range []*Record{first, second} {
+			if err := b.Put(rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids, _ := listIDs(t, b, Query{From: at})
+		if want := []string{second.ID, first.ID}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("同一毫秒的工作: List = %v, want %v (依 ULID)", ids, want)
+		}
+		ids, _ = listIDs(t, b, Query{From: at, After: &Cursor{CreatedAt: second.CreatedAt, ID: second.ID}})
+		if want := []string{first.ID}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("同一毫秒的游標: List = %v, want %v", ids, want)
+		}
+		for _, rec := range []*Record{first, second} {
+			if err := b.Delete(rec.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := b.Delete(rejected.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.Get(rejected.ID); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("刪除後 Get: got %v, want ErrJobNotFound", err)
+		}
+		if err := b.Delete(rejected.ID); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("重複刪除: got %v, want ErrJobNotFound", err)
+		}
+		ids, total := listIDs(t, b, Query{})
+		if want := []string{newest.ID, oldest.ID}; !reflect.DeepEqual(ids, want) || total != 2 {
+			t.Errorf("刪除後 List = %v (total %d), want %v", ids, total, want)
+		}
+
+		if err := b.Delete(oldest.ID); err != nil {
+			t.Fatal(err)
+		}
+		if ids, _ := listIDs(t, b, Query{Hash: oldest.Hash}); !reflect.DeepEqual(ids, []string{newest.ID}) {
+			t.Errorf("刪除後依雜湊 List = %v, want [%s]", ids, newest.ID)
+		}
+
+		// 刪除後可以再建立同一天的工作
+		again := conformanceRecord(t, "mqtt-rejected", base.Add(24*time.Hour), "SIZE 40 mm\n", nil)
+		if err := b.Put(again); err != nil {
+			t.Fatalf("刪除後 Put: %v", err)
+		}
+		if _, err := b.Get(again.ID); err != nil {
+			t.Errorf("刪除後 Put 再 Get: %v", err)
+		}
+	})
+
+	if err := b.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestFSBackendConformance(t *testing.T) {
+	testBackendConformance(t, NewFSBackend(t.TempDir()))
+}
+
+func TestMemoryBackendConformance(t *testing.T) {
+	testBackendConformance(t, NewMemoryBackend())
+}
+
+func TestKVBackendConformance(t *testing.T) {
+	b, err := OpenKVBackend(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBackendConformance(t, b)
+}
+
+func TestKVBackendReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	b, err := OpenKVBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := conformanceRecord(t, "api", time.Now(), "SIZE 50 mm, 30 mm\n", &models.JobMetadata{Source: "api", Valid: true})
+	deleted := conformanceRecord(t, "mqtt", time.Now(), "SIZE 40 mm\n", nil)
+	for _, rec := range []*Record{kept, deleted} {
+		if err := b.Put(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = OpenKVBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	got, err := b.Get(kept.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Data) != string(kept.Data) || !reflect.DeepEqual(got.Metadata, kept.Metadata) {
+		t.Errorf("重新開啟後 Get = %+v", got)
+	}
+	if _, err := b.Get(deleted.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("重新開啟後已刪除的工作: got %v, want ErrJobNotFound", err)
+	}
+}
+
+// TestKVBackendReadOnlyWhileWriting 命令列工具在伺服器寫入時開啟同一份記錄檔:
+// 唯讀開啟不可截斷寫到一半的記錄, 也不可壓縮記錄檔使伺服器之後的寫入遺失
+func TestKVBackendReadOnlyWhileWriting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.db")
+	w, err := OpenKVBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { w.Close() }()
+
+	// 已刪除的記錄足以在讀寫開啟時觸發壓縮
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	const initial, deleted, writes = 100, 80, 200
+	for i := 0; i < initial; i++ {
+		rec := conformanceRecord(t, "api", base.Add(time.Duration(i)*time.Second), "SIZE 50 mm, 30 mm\n", nil)
+		if err := w.Put(rec); err != nil {
+			t.Fatal(err)
+		}
+		if i < deleted {
+			if err := w.Delete(rec.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < writes; i++ {
+			rec := conformanceRecord(t, "mqtt", base.Add(time.Hour+time.Duration(i)*time.Second), "SIZE 40 mm\n", nil)
+			if err := w.Put(rec); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for running := true; running; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			running = false
+		default:
+		}
+		s, err := OpenReadOnly("kv", dir, S3Config{})
+		if err != nil {
+			t.Fatalf("唯讀開啟: %v", err)
+		}
+		jobs, total, err := s.ListJobs(JobQuery{Source: "api"})
+		if err != nil || total != initial-deleted {
+			t.Fatalf("唯讀 ListJobs: total %d, %v; want %d", total, err, initial-deleted)
+		}
+		if _, _, err := s.GetJob(jobs[0].ID); err != nil {
+			t.Errorf("唯讀 GetJob: %v", err)
+		}
+		if err := s.DeleteJob(jobs[0].ID); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("唯讀 DeleteJob: got %v, want ErrReadOnly", err)
+		}
+		s.Close()
+	}
+
+	// 尾端寫到一半的記錄: 唯讀開啟只略過, 不截斷
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1, 0, 1, 2, 3, 4, '{'})
+	f.Close()
+	before, _ := os.Stat(path)
+	r, err := OpenKVBackendReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := r.List(Query{}); total != initial-deleted+writes {
+		t.Errorf("唯讀開啟: total %d, want %d", total, initial-deleted+writes)
+	}
+	r.Close()
+	if after, _ := os.Stat(path); after.Size() != before.Size() {
+		t.Errorf("唯讀開啟改變了記錄檔大小: %d → %d", before.Size(), after.Size())
+	}
+
+	// 寫入端的所有工作都保留
+	w, err = OpenKVBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := w.List(Query{}); total != initial-deleted+writes {
+		t.Errorf("重新開啟: total %d, want %d (寫入的工作遺失)", total, initial-deleted+writes)
+	}
+}
+
+func TestOpenReadOnlyMissingKV(t *testing.T) {
+	if _, err := OpenReadOnly("kv", t.TempDir(), S3Config{}); err == nil {
+		t.Error("唯讀開啟不存在的記錄檔應回傳錯誤")
+	}
+}
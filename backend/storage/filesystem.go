@@ -0,0 +1,409 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tspl-simulator/models"
+)
+
+// legacyJobIDPattern 舊版以時間命名的工作 ID: 來源[-rejected]-年月日-時分秒[-毫秒],
+// 對應 資料夾/年_月_日/時_分_秒[_毫秒].tspl
+var legacyJobIDPattern = regexp.MustCompile(`^((?:api|mqtt)(?:-rejected)?)-(\d{8})-(\d{6})(?:-(\d{3}))?$`)
+
+// compressedExt 壓縮後的工作檔附加的副檔名 (ULID.tspl.gz); 索引中仍記錄原本的 .tspl 檔名
+const compressedExt = ".gz"
+
+// FSBackend 以本機檔案系統儲存工作: 資料夾/年_月_日/ULID.tspl, 中繼資料為同名 .json,
+// 每個日期資料夾另有 index.jsonl 索引
+type FSBackend struct {
+	basePath string
+	indexMu  sync.Mutex // 保護索引的附加寫入、核對與清空資料夾的移除
+}
+
+// NewFSBackend 建立檔案系統儲存後端
+func NewFSBackend(basePath string) *FSBackend {
+	return &FSBackend{basePath: basePath}
+}
+
+// Put 以暫存檔寫入後排他地移入正式檔名, 再寫入中繼資料並記入當天的索引
+func (b *FSBackend) Put(rec *Record) error {
+	folderPath := filepath.Join(b.basePath, jobBuckets[rec.Bucket], rec.CreatedAt.Format("2006_01_02"))
+	if err := os.MkdirAll(folderPath, 0755); err != nil {
+		return fmt.Errorf("建立資料夾失敗: %v", err)
+	}
+
+	_, id, ok := parseJobID(rec.ID)
+	if !ok {
+		return fmt.Errorf("工作 ID 格式錯誤: %s", rec.ID)
+	}
+	filePath := filepath.Join(folderPath, id+".tspl")
+	err := writeExclusive(filePath, rec.Data)
+	if errors.Is(err, os.ErrNotExist) {
+		// 同一天的最後一筆工作剛被刪除, 資料夾已移除
+		if err = os.MkdirAll(folderPath, 0755); err == nil {
+			err = writeExclusive(filePath, rec.Data)
+		}
+	}
+	if errors.Is(err, os.ErrExist) {
+		return ErrJobExists
+	} else if err != nil {
+		return fmt.Errorf("寫入檔案失敗: %v", err)
+	}
+	rec.Location = filePath
+
+	if rec.Metadata != nil {
+		if err := writeMetadata(filePath, rec.Metadata); err != nil {
+			return err
+		}
+	}
+	return b.appendIndex(folderPath, indexEntry{ID: rec.ID, File: id + ".tspl", CreatedAt: rec.CreatedAt, Size: rec.Size, Hash: rec.Hash})
+}
+
+// Get 讀取工作檔與中繼資料
+func (b *FSBackend) Get(id string) (*Record, error) {
+	path, bucket, ok := b.jobPath(id)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	data, err := readJobFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取工作失敗: %v", err)
+	}
+	meta, err := readMetadata(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Record{
+		ID:        id,
+		Bucket:    bucket,
+		CreatedAt: jobTime(path),
+		Size:      int64(len(data)),
+		Hash:      ContentHash(data),
+		Location:  path,
+		Data:      data,
+		Metadata:  meta,
+	}, nil
+}
+
+// Delete 刪除工作與中繼資料, 並於索引記錄刪除; 日期資料夾清空後一併移除
+func (b *FSBackend) Delete(id string) error {
+	path, _, ok := b.jobPath(id)
+	if !ok {
+		return ErrJobNotFound
+	}
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.Remove(path + compressedExt)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrJobNotFound
+	} else if err != nil {
+		return fmt.Errorf("刪除工作失敗: %v", err)
+	}
+	if err := os.Remove(metadataPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("刪除中繼資料失敗: %v", err)
+	}
+
+	// 記錄刪除與清空資料夾在同一個鎖內, 並於鎖內重新確認資料夾只剩索引,
+	// 避免移除同時寫入的工作的索引
+	folderPath := filepath.Dir(path)
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+	if err := writeIndexEntries(folderPath, indexEntry{ID: id, File: filepath.Base(path), Deleted: true}); err != nil {
+		return err
+	}
+	if dayFolderEmpty(folderPath) {
+		os.Remove(filepath.Join(folderPath, indexFile))
+		os.Remove(filepath.Join(folderPath, indexMarker))
+		os.Remove(folderPath)
+	}
+	return nil
+}
+
+// dayFolderEmpty 日期資料夾中是否只剩索引與核對標記
+func dayFolderEmpty(folderPath string) bool {
+	files, err := os.ReadDir(folderPath)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if name := file.Name(); name != indexFile && name != indexMarker {
+			return false
+		}
+	}
+	return true
+}
+
+// List 以日期資料夾縮小範圍, 再由各資料夾的索引列出工作, 不需逐一讀取檔案
+func (b *FSBackend) List(q Query) ([]Record, int, error) {
+	var recs []Record
+	for bucket, folder := range jobBuckets {
+		if !q.hasBucket(bucket) {
+			continue
+		}
+		days, err := os.ReadDir(filepath.Join(b.basePath, folder))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("讀取工作列表失敗: %v", err)
+		}
+		for _, day := range days {
+			if !day.IsDir() || !dayInRange(day.Name(), q.From, q.To, time.Local) {
+				continue
+			}
+			folderPath := filepath.Join(b.basePath, folder, day.Name())
+			entries, err := b.readIndex(bucket, folderPath)
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, entry := range entries {
+				rec := Record{ID: entry.ID, Bucket: bucket, CreatedAt: entry.CreatedAt, Size: entry.Size, Hash: entry.Hash, Location: filepath.Join(folderPath, entry.File)}
+				if rec.Hash == "" && q.Hash != "" {
+					// 舊版索引沒有雜湊
+					if data, err := readJobFile(rec.Location); err == nil {
+						rec.Hash = ContentHash(data)
+					}
+				}
+				if q.matches(&rec) {
+					recs = append(recs, rec)
+				}
+			}
+		}
+	}
+
+	page, total := paginate(recs, q)
+	if q.Meta {
+		for i := range page {
+			// 列表中略過無法讀取的中繼資料, 錯誤由 Get 回報
+			page[i].Metadata, _ = readMetadata(page[i].Location)
+		}
+	}
+	return page, total, nil
+}
+
+// Close 檔案系統後端沒有需要釋放的資源
+func (b *FSBackend) Close() error {
+	return nil
+}
+
+// jobPath 將工作 ID 換算為檔案路徑與工作類別
+func (b *FSBackend) jobPath(id string) (string, string, bool) {
+	if bucket, ulid, ok := parseJobID(id); ok {
+		t, _ := ulidTime(ulid)
+		return filepath.Join(b.basePath, jobBuckets[bucket], t.Format("2006_01_02"), ulid+".tspl"), bucket, true
+	}
+
+	m := legacyJobIDPattern.FindStringSubmatch(id)
+	if m == nil {
+		return "", "", false
+	}
+	date, clock := m[2], m[3]
+	name := clock[0:2] + "_" + clock[2:4] + "_" + clock[4:6]
+	if m[4] != "" {
+		name += "_" + m[4]
+	}
+	day := date[0:4] + "_" + date[4:6] + "_" + date[6:8]
+	return filepath.Join(b.basePath, jobBuckets[m[1]], day, name+".tspl"), m[1], true
+}
+
+// jobFromFile 由日期資料夾中的檔案建立索引記錄 (重建索引時使用); 不是工作檔時回傳 false
+func jobFromFile(bucket, folderPath string, file os.DirEntry) (indexEntry, bool) {
+	plain, compressed := strings.CutSuffix(file.Name(), compressedExt)
+	name := strings.TrimSuffix(plain, ".tspl")
+	if file.IsDir() || name == plain {
+		return indexEntry{}, false
+	}
+	if compressed {
+		// 壓縮到一半中斷時兩個檔案並存, 以未壓縮的為準
+		if _, err := os.Stat(filepath.Join(folderPath, plain)); err == nil {
+			return indexEntry{}, false
+		}
+	}
+	id := bucket + "-" + name
+	if !jobIDPattern.MatchString(id) {
+		// 舊版: 時_分_秒[_毫秒].tspl
+		day := filepath.Base(folderPath)
+		id = bucket + "-" + strings.ReplaceAll(day, "_", "") + "-" + strings.Replace(strings.Replace(name, "_", "", 2), "_", "-", 1)
+		if !legacyJobIDPattern.MatchString(id) {
+			return indexEntry{}, false
+		}
+	}
+	path := filepath.Join(folderPath, plain)
+	entry := indexEntry{ID: id, File: plain, CreatedAt: jobTime(path)}
+	if data, err := readJobFile(path); err == nil {
+		entry.Size = int64(len(data))
+		entry.Hash = ContentHash(data)
+	}
+	return entry, true
+}
+
+// jobTime 由工作檔路徑取得建立時間: ULID 檔名取其時間, 舊版 年_月_日/時_分_秒[_毫秒].tspl 依本地時區換算
+func jobTime(path string) time.Time {
+	name := strings.TrimSuffix(filepath.Base(path), ".tspl")
+	if t, ok := ulidTime(name); ok {
+		return t
+	}
+	day := filepath.Base(filepath.Dir(path))
+	ms := 0
+	if len(name) == len("15_04_05_000") {
+		fmt.Sscanf(name[9:], "%d", &ms)
+		name = name[:8]
+	}
+	t, err := time.ParseInLocation("2006_01_02 15_04_05", day+" "+name, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// dayInRange 判斷日期資料夾 (年_月_日, 依 loc 時區) 是否可能包含區間內的工作
+func dayInRange(day string, from, to time.Time, loc *time.Location) bool {
+	start, err := time.ParseInLocation("2006_01_02", day, loc)
+	if err != nil {
+		return false
+	}
+	if !to.IsZero() && !start.Before(to) {
+		return false
+	}
+	return from.IsZero() || start.AddDate(0, 0, 1).After(from)
+}
+
+// metadataPath 工作檔對應的中繼資料附檔路徑
+func metadataPath(jobPath string) string {
+	return strings.TrimSuffix(jobPath, ".tspl") + ".json"
+}
+
+// writeMetadata 寫入工作的中繼資料附檔
+func writeMetadata(jobPath string, meta *models.JobMetadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化中繼資料失敗: %v", err)
+	}
+	if err := writeAtomic(metadataPath(jobPath), data); err != nil {
+		return fmt.Errorf("寫入中繼資料失敗: %v", err)
+	}
+	return nil
+}
+
+// readMetadata 讀取工作的中繼資料附檔; 舊工作沒有附檔時回傳 nil
+func readMetadata(jobPath string) (*models.JobMetadata, error) {
+	data, err := os.ReadFile(metadataPath(jobPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取中繼資料失敗: %v", err)
+	}
+	var meta models.JobMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("解析中繼資料失敗: %v", err)
+	}
+	return &meta, nil
+}
+
+// readJobFile 讀取工作檔; 已壓縮的工作改讀 .gz 並解壓縮
+func readJobFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		return data, err
+	}
+	f, err := os.Open(path + compressedExt)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// CompressBefore 以 gzip 壓縮整天都早於 before 的日期資料夾中的工作檔 (ULID.tspl → ULID.tspl.gz),
+// 中繼資料與索引保持不變, 讀取時自動解壓縮; dryRun 時只統計不壓縮
+func (b *FSBackend) CompressBefore(before time.Time, dryRun bool) (*models.CompressionReport, error) {
+	report := &models.CompressionReport{Before: before, Folders: []string{}}
+	for _, folder := range jobBuckets {
+		days, err := os.ReadDir(filepath.Join(b.basePath, folder))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("讀取工作列表失敗: %v", err)
+		}
+		for _, day := range days {
+			start, err := time.ParseInLocation("2006_01_02", day.Name(), time.Local)
+			if !day.IsDir() || err != nil || start.AddDate(0, 0, 1).After(before) {
+				continue
+			}
+			folderPath := filepath.Join(b.basePath, folder, day.Name())
+			files, err := os.ReadDir(folderPath)
+			if err != nil {
+				return report, fmt.Errorf("讀取工作列表失敗: %v", err)
+			}
+			count := 0
+			for _, file := range files {
+				info, err := file.Info()
+				if file.IsDir() || filepath.Ext(file.Name()) != ".tspl" || err != nil {
+					continue
+				}
+				if !dryRun {
+					size, err := compressFile(filepath.Join(folderPath, file.Name()))
+					if err != nil {
+						return report, err
+					}
+					report.CompressedBytes += size
+				}
+				count++
+				report.Files++
+				report.Bytes += info.Size()
+			}
+			if count > 0 {
+				report.Folders = append(report.Folders, filepath.Join(folder, day.Name()))
+			}
+		}
+	}
+	sort.Strings(report.Folders)
+	return report, nil
+}
+
+// compressFile 將工作檔壓縮為 .gz 後移除原檔, 回傳壓縮後的大小
+func compressFile(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil // 已被刪除
+	}
+	if err != nil {
+		return 0, fmt.Errorf("讀取工作失敗: %v", err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("壓縮工作失敗: %v", err)
+	}
+	if err := writeAtomic(path+compressedExt, buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("寫入壓縮檔失敗: %v", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("移除已壓縮的工作失敗: %v", err)
+	}
+	return int64(buf.Len()), nil
+}
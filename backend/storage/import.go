@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"tspl-simulator/models"
+)
+
+// importAttempts 匯入時 ULID 碰撞的重試次數
+const importAttempts = 3
+
+// ImportJob 匯入由其他環境匯出的工作: 保留來源、狀態、建立時間與中繼資料, 以新的工作 ID 儲存
+// TSPL 內容 (SHA-256) 已存在時不重複儲存, 回傳既有的工作與 duplicate = true
+// 建立時間為零值或晚於現在時以現在的時間儲存; 早於 1970-01-01 (UTC) 時無法編入 ULID 的 48 位元毫秒時間, 回傳錯誤
+func (s *StorageService) ImportJob(job models.Job, code string) (imported models.Job, duplicate bool, err error) {
+	if job.Source != "api" && job.Source != "mqtt" {
+		return models.Job{}, false, fmt.Errorf("未知的工作來源: %s", job.Source)
+	}
+	if !job.CreatedAt.IsZero() && job.CreatedAt.UnixMilli() < 0 {
+		return models.Job{}, false, fmt.Errorf("建立時間 %s 早於 1970-01-01, 無法匯入", job.CreatedAt.Format(time.RFC3339))
+	}
+	bucket := job.Source
+	if job.Rejected {
+		bucket += "-rejected"
+	}
+	hash := ContentHash([]byte(code))
+
+	// 同一時間只匯入一個工作, 避免同時匯入相同內容時都判斷為不重複
+	s.importMu.Lock()
+	defer s.importMu.Unlock()
+
+	existing, _, err := s.ListJobs(JobQuery{Hash: hash, Limit: 1})
+	if err != nil {
+		return models.Job{}, false, err
+	}
+	if len(existing) > 0 {
+		return existing[0], true, nil
+	}
+
+	now := time.Now()
+	for attempt := 0; attempt < importAttempts; attempt++ {
+		var id string
+		var createdAt time.Time
+		if job.CreatedAt.IsZero() || job.CreatedAt.After(now) {
+			id, createdAt = s.ids.next(now)
+		} else {
+			id, createdAt = ulidAt(job.CreatedAt)
+		}
+		rec := &Record{
+			ID:        bucket + "-" + id,
+			Bucket:    bucket,
+			CreatedAt: createdAt,
+			Size:      int64(len(code)),
+			Hash:      hash,
+			Data:      []byte(code),
+			Metadata:  job.Metadata,
+		}
+		err = s.backend.Put(rec)
+		if errors.Is(err, ErrJobExists) {
+			continue
+		}
+		if err != nil {
+			return models.Job{}, false, err
+		}
+		for _, l := range s.listeners {
+			l.JobSaved(rec.Job(), code)
+		}
+		return rec.Job(), false, nil
+	}
+	return models.Job{}, false, err
+}
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// indexFile 每個日期資料夾中的工作索引, 每行一筆 JSON
+const indexFile = "index.jsonl"
+
+// indexMarker 日期資料夾的內容已與索引核對過的標記 (見 reconcileIndex)
+const indexMarker = ".indexed"
+
+// indexEntry 工作索引的一筆記錄; 刪除時附加 Deleted 為 true 的記錄
+type indexEntry struct {
+	ID        string    `json:"id"`
+	File      string    `json:"file"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+	Hash      string    `json:"hash,omitempty"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+// writeTemp 將資料寫入同資料夾的暫存檔並同步到磁碟, 回傳暫存檔路徑
+func writeTemp(path string, data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeAtomic 以暫存檔加 rename 寫入, 讀取端不會看到寫到一半的檔案
+func writeAtomic(path string, data []byte) error {
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeExclusive 與 writeAtomic 相同, 但目標已存在時失敗而不覆蓋
+// (以 hard link 移入正式檔名, 等同不覆蓋的 rename)
+func writeExclusive(path string, data []byte) error {
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Link(tmp, path)
+}
+
+// appendIndex 附加一筆記錄到日期資料夾的索引
+func (b *FSBackend) appendIndex(folderPath string, entry indexEntry) error {
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+	return writeIndexEntries(folderPath, entry)
+}
+
+// writeIndexEntries 附加記錄到索引 (不存在時建立); 呼叫端須持有 indexMu
+func writeIndexEntries(folderPath string, entries ...indexEntry) error {
+	var content bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("序列化索引失敗: %v", err)
+		}
+		content.Write(line)
+		content.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(filepath.Join(folderPath, indexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("開啟索引失敗: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(content.Bytes()); err != nil {
+		return fmt.Errorf("寫入索引失敗: %v", err)
+	}
+	return nil
+}
+
+// readIndex 讀取日期資料夾的索引 (依寫入順序, 已刪除的工作不列出)
+// 尚未核對過的資料夾 (舊版儲存, 或舊版程式寫入過的當天資料夾) 先將索引沒有的工作檔補入索引
+func (b *FSBackend) readIndex(bucket, folderPath string) ([]indexEntry, error) {
+	if _, err := os.Stat(filepath.Join(folderPath, indexMarker)); errors.Is(err, os.ErrNotExist) {
+		if err := b.reconcileIndex(bucket, folderPath); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := loadIndex(folderPath)
+	if err != nil {
+		return nil, err
+	}
+	live := entries[:0]
+	for _, entry := range entries {
+		if !entry.Deleted {
+			live = append(live, entry)
+		}
+	}
+	return live, nil
+}
+
+// loadIndex 讀取索引的所有記錄, 同一工作以最後一筆為準; 沒有索引時回傳空
+func loadIndex(folderPath string) ([]indexEntry, error) {
+	f, err := os.Open(filepath.Join(folderPath, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取索引失敗: %v", err)
+	}
+	defer f.Close()
+
+	var entries []indexEntry
+	position := map[string]int{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry indexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // 略過寫到一半的記錄
+		}
+		if i, ok := position[entry.ID]; ok {
+			entries[i] = entry
+			continue
+		}
+		position[entry.ID] = len(entries)
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("讀取索引失敗: %v", err)
+	}
+	return entries, nil
+}
+
+// reconcileIndex 將資料夾中索引沒有記錄的工作檔 (例如舊版的 時_分_秒.tspl) 補入索引, 並建立核對標記
+// 索引中已有記錄 (含已刪除) 的工作不重複加入
+func (b *FSBackend) reconcileIndex(bucket, folderPath string) error {
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+	marker := filepath.Join(folderPath, indexMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil // 其他請求已核對
+	}
+
+	files, err := os.ReadDir(folderPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // 資料夾已清空移除
+	}
+	if err != nil {
+		return fmt.Errorf("讀取工作列表失敗: %v", err)
+	}
+	indexed, err := loadIndex(folderPath)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(indexed))
+	for _, entry := range indexed {
+		known[entry.ID] = true
+	}
+
+	var missing []indexEntry
+	for _, file := range files {
+		if entry, ok := jobFromFile(bucket, folderPath, file); ok && !known[entry.ID] {
+			missing = append(missing, entry)
+		}
+	}
+	if len(missing) > 0 {
+		if err := writeIndexEntries(folderPath, missing...); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		return fmt.Errorf("寫入索引標記失敗: %v", err)
+	}
+	return nil
+}
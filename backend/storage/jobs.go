@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"time"
+
+	"tspl-simulator/models"
+)
+
+// JobQuery 列印工作查詢條件
+type JobQuery struct {
+	Source string    // api / mqtt, 空字串代表全部
+	Status string    // accepted (驗證通過) / rejected (驗證失敗), 空字串代表全部
+	From   time.Time // 建立時間下限 (含), 零值代表不限
+	To     time.Time // 建立時間上限 (不含), 零值代表不限
+	Hash   string    // TSPL 內容的 SHA-256, 空字串代表不限
+	After  *Cursor   // 只列出排序在游標之後 (較舊) 的工作, nil 代表不限
+	Offset int
+	Limit  int // 0 代表不限
+}
+
+// buckets 將來源與狀態條件換算為工作類別
+func (q JobQuery) buckets() []string {
+	var buckets []string
+	for _, source := range []string{"api", "mqtt"} {
+		if q.Source != "" && q.Source != source {
+			continue
+		}
+		if q.Status != "rejected" {
+			buckets = append(buckets, source)
+		}
+		if q.Status != "accepted" {
+			buckets = append(buckets, source+"-rejected")
+		}
+	}
+	return buckets
+}
+
+// ListJobs 依條件列出列印工作 (最新的在前), 並回傳分頁前符合條件的總數
+func (s *StorageService) ListJobs(q JobQuery) ([]models.Job, int, error) {
+	buckets := q.buckets()
+	if len(buckets) == 0 {
+		return []models.Job{}, 0, nil
+	}
+	recs, total, err := s.backend.List(Query{
+		Buckets: buckets,
+		From:    q.From,
+		To:      q.To,
+		Hash:    q.Hash,
+		After:   q.After,
+		Offset:  q.Offset,
+		Limit:   q.Limit,
+		Meta:    true,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	jobs := make([]models.Job, 0, len(recs))
+	for i := range recs {
+		jobs = append(jobs, recs[i].Job())
+	}
+	return jobs, total, nil
+}
+
+// GetJob 取得列印工作 (含中繼資料) 與其 TSPL 原始碼
+func (s *StorageService) GetJob(id string) (*models.Job, string, error) {
+	rec, err := s.backend.Get(id)
+	if err != nil {
+		return nil, "", err
+	}
+	job := rec.Job()
+	return &job, string(rec.Data), nil
+}
+
+// DeleteJob 刪除列印工作與中繼資料
+func (s *StorageService) DeleteJob(id string) error {
+	if err := s.backend.Delete(id); err != nil {
+		return err
+	}
+	for _, l := range s.listeners {
+		l.JobDeleted(id)
+	}
+	return nil
+}
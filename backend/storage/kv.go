@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// kvMaxEntry 單筆記錄的長度上限, 超過視為損毀
+const kvMaxEntry = 64 << 20
+
+// kvCompactThreshold 開啟時已失效的記錄數超過此數量且多於有效記錄時壓縮記錄檔
+const kvCompactThreshold = 64
+
+// kvEntry 記錄檔中的一筆記錄; 檔案格式為連續的 [4 位元組長度][4 位元組 CRC-32][JSON]
+type kvEntry struct {
+	Op     string  `json:"op"` // put / delete
+	ID     string  `json:"id"`
+	Record *Record `json:"record,omitempty"`
+	Data   []byte  `json:"data,omitempty"`
+}
+
+// KVBackend 嵌入式鍵值儲存: 所有工作附加寫入單一記錄檔, 開啟時重播記錄建立記憶體索引
+// (時間、來源與內容雜湊), 寫到一半的尾端記錄會被截斷
+type KVBackend struct {
+	mu       sync.RWMutex
+	path     string
+	readOnly bool
+	file     *os.File
+	size     int64
+	index    *memIndex
+	offsets  map[string]int64 // ID → put 記錄的位置
+	dead     int              // 已刪除的記錄數
+}
+
+// OpenKVBackend 開啟 (或建立) 記錄檔
+func OpenKVBackend(path string) (*KVBackend, error) {
+	b := &KVBackend{path: path}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	if b.dead > kvCompactThreshold && b.dead > len(b.offsets) {
+		if err := b.compact(); err != nil {
+			b.file.Close()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// OpenKVBackendReadOnly 以唯讀方式開啟既有的記錄檔, 供伺服器執行中時命令列工具讀取:
+// 不截斷尾端寫到一半的記錄、不壓縮記錄檔, Put/Delete 回傳 ErrReadOnly;
+// 索引為開啟當下的內容, 之後其他程序寫入的工作不會出現
+func OpenKVBackendReadOnly(path string) (*KVBackend, error) {
+	b := &KVBackend{path: path, readOnly: true}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// load 重播記錄檔建立索引
+func (b *KVBackend) load() error {
+	flag := os.O_RDWR | os.O_CREATE
+	if b.readOnly {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(b.path, flag, 0644)
+	if err != nil {
+		return fmt.Errorf("開啟記錄檔失敗: %v", err)
+	}
+	b.file, b.size = f, 0
+	b.index, b.offsets, b.dead = newMemIndex(), map[string]int64{}, 0
+
+	r := bufio.NewReader(f)
+	for {
+		entry, n, err := readKVEntry(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !b.readOnly {
+				// 尾端的記錄不完整或損毀: 截斷 (唯讀時可能是其他程序正在寫入, 只略過)
+				if err := f.Truncate(b.size); err != nil {
+					f.Close()
+					return fmt.Errorf("截斷記錄檔失敗: %v", err)
+				}
+			}
+			break
+		}
+		b.apply(entry, b.size)
+		b.size += n
+	}
+	if _, err := f.Seek(b.size, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("開啟記錄檔失敗: %v", err)
+	}
+	return nil
+}
+
+// apply 將記錄套用到索引
+func (b *KVBackend) apply(entry *kvEntry, offset int64) {
+	switch entry.Op {
+	case "put":
+		if entry.Record == nil {
+			return
+		}
+		rec := *entry.Record
+		rec.Location = b.path + "#" + rec.ID
+		if b.index.add(&rec) {
+			b.offsets[rec.ID] = offset
+		}
+	case "delete":
+		if b.index.remove(entry.ID) != nil {
+			delete(b.offsets, entry.ID)
+			b.dead += 2
+		}
+	}
+}
+
+// readKVEntry 讀取一筆記錄, 回傳記錄與佔用的位元組數
+func readKVEntry(r io.Reader) (*kvEntry, int64, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, 0, err // 檔案結尾為 io.EOF, 不完整的標頭為 io.ErrUnexpectedEOF
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > kvMaxEntry {
+		return nil, 0, errors.New("記錄長度錯誤")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, 0, errors.New("記錄檢查碼錯誤")
+	}
+	var entry kvEntry
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		return nil, 0, err
+	}
+	return &entry, int64(8 + length), nil
+}
+
+// encodeKVEntry 編碼一筆記錄
+func encodeKVEntry(entry *kvEntry) ([]byte, error) {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[8:], payload)
+	return buf, nil
+}
+
+// append 附加記錄並同步到磁碟, 回傳記錄的位置
+func (b *KVBackend) append(entry *kvEntry) (int64, error) {
+	buf, err := encodeKVEntry(entry)
+	if err != nil {
+		return 0, fmt.Errorf("序列化記錄失敗: %v", err)
+	}
+	offset := b.size
+	if _, err := b.file.Write(buf); err != nil {
+		b.file.Truncate(offset)
+		b.file.Seek(offset, io.SeekStart)
+		return 0, fmt.Errorf("寫入記錄檔失敗: %v", err)
+	}
+	if err := b.file.Sync(); err != nil {
+		return 0, fmt.Errorf("寫入記錄檔失敗: %v", err)
+	}
+	b.size += int64(len(buf))
+	return offset, nil
+}
+
+// Put 建立工作
+func (b *KVBackend) Put(rec *Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.readOnly {
+		return ErrReadOnly
+	}
+	if _, ok := b.offsets[rec.ID]; ok {
+		return ErrJobExists
+	}
+	stored := *rec
+	stored.Data = nil
+	offset, err := b.append(&kvEntry{Op: "put", ID: rec.ID, Record: &stored, Data: rec.Data})
+	if err != nil {
+		return err
+	}
+	stored.Location = b.path + "#" + rec.ID
+	b.index.add(&stored)
+	b.offsets[rec.ID] = offset
+	rec.Location = stored.Location
+	return nil
+}
+
+// Get 由記錄檔讀取工作內容
+func (b *KVBackend) Get(id string) (*Record, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	offset, ok := b.offsets[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	entry, _, err := readKVEntry(io.NewSectionReader(b.file, offset, b.size-offset))
+	if err != nil {
+		return nil, fmt.Errorf("讀取工作失敗: %v", err)
+	}
+	rec := *b.index.records[id]
+	rec.Data = entry.Data
+	return &rec, nil
+}
+
+// Delete 附加刪除記錄
+func (b *KVBackend) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.readOnly {
+		return ErrReadOnly
+	}
+	if _, ok := b.offsets[id]; !ok {
+		return ErrJobNotFound
+	}
+	if _, err := b.append(&kvEntry{Op: "delete", ID: id}); err != nil {
+		return err
+	}
+	b.index.remove(id)
+	delete(b.offsets, id)
+	b.dead += 2
+	return nil
+}
+
+// List 由記憶體索引查詢工作
+func (b *KVBackend) List(q Query) ([]Record, int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	recs, total := b.index.list(q)
+	if !q.Meta {
+		for i := range recs {
+			recs[i].Metadata = nil
+		}
+	}
+	return recs, total, nil
+}
+
+// Close 關閉記錄檔
+func (b *KVBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.file.Close()
+}
+
+// compact 只保留有效的工作重寫記錄檔 (寫入暫存檔後 rename)
+func (b *KVBackend) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("壓縮記錄檔失敗: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, rec := range b.index.records {
+		entry, _, err := readKVEntry(io.NewSectionReader(b.file, b.offsets[rec.ID], b.size-b.offsets[rec.ID]))
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("壓縮記錄檔失敗: %v", err)
+		}
+		buf, err := encodeKVEntry(entry)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("壓縮記錄檔失敗: %v", err)
+		}
+		w.Write(buf)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("壓縮記錄檔失敗: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("壓縮記錄檔失敗: %v", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("壓縮記錄檔失敗: %v", err)
+	}
+
+	b.file.Close()
+	return b.load()
+}
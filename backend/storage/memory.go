@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+// memIndex 記憶體中的工作索引: 各工作類別依建立時間排序, 另以內容雜湊索引,
+// 供記憶體與 KV 後端以二分搜尋查詢時間區間
+type memIndex struct {
+	records map[string]*Record            // ID → 工作 (不含 Data)
+	byTime  map[string][]*Record          // 工作類別 → 依 (建立時間, ULID) 排序
+	byHash  map[string]map[string]*Record // 內容雜湊 → ID → 工作
+}
+
+// newMemIndex 建立空的索引
+func newMemIndex() *memIndex {
+	return &memIndex{
+		records: map[string]*Record{},
+		byTime:  map[string][]*Record{},
+		byHash:  map[string]map[string]*Record{},
+	}
+}
+
+// recordBefore 依 (建立時間, ULID) 由舊到新排序
+func recordBefore(a, b *Record) bool {
+	return newer(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+}
+
+// add 加入工作; ID 已存在時回傳 false
+func (m *memIndex) add(rec *Record) bool {
+	if _, ok := m.records[rec.ID]; ok {
+		return false
+	}
+	m.records[rec.ID] = rec
+
+	list := m.byTime[rec.Bucket]
+	i := sort.Search(len(list), func(i int) bool { return recordBefore(rec, list[i]) })
+	list = append(list, nil)
+	copy(list[i+1:], list[i:])
+	list[i] = rec
+	m.byTime[rec.Bucket] = list
+
+	if rec.Hash != "" {
+		if m.byHash[rec.Hash] == nil {
+			m.byHash[rec.Hash] = map[string]*Record{}
+		}
+		m.byHash[rec.Hash][rec.ID] = rec
+	}
+	return true
+}
+
+// remove 移除工作; 不存在時回傳 nil
+func (m *memIndex) remove(id string) *Record {
+	rec, ok := m.records[id]
+	if !ok {
+		return nil
+	}
+	delete(m.records, id)
+
+	list := m.byTime[rec.Bucket]
+	i := sort.Search(len(list), func(i int) bool { return !recordBefore(list[i], rec) })
+	if i < len(list) && list[i] == rec {
+		m.byTime[rec.Bucket] = append(list[:i], list[i+1:]...)
+	}
+
+	if ids := m.byHash[rec.Hash]; ids != nil {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(m.byHash, rec.Hash)
+		}
+	}
+	return rec
+}
+
+// list 查詢工作: 指定雜湊時由雜湊索引取出, 否則在各工作類別的時間排序中以二分搜尋取出區間
+func (m *memIndex) list(q Query) ([]Record, int) {
+	var recs []Record
+	if q.Hash != "" {
+		for _, rec := range m.byHash[q.Hash] {
+			if q.matches(rec) {
+				recs = append(recs, *rec)
+			}
+		}
+		return paginate(recs, q)
+	}
+
+	for bucket, list := range m.byTime {
+		if !q.hasBucket(bucket) {
+			continue
+		}
+		start, end := 0, len(list)
+		if !q.From.IsZero() {
+			start = sort.Search(len(list), func(i int) bool { return !list[i].CreatedAt.Before(q.From) })
+		}
+		if !q.To.IsZero() {
+			end = sort.Search(len(list), func(i int) bool { return !list[i].CreatedAt.Before(q.To) })
+		}
+		if q.After != nil {
+			end = min(end, sort.Search(len(list), func(i int) bool { return list[i].CreatedAt.After(q.After.CreatedAt) }))
+		}
+		for _, rec := range list[start:max(start, end)] {
+			if q.afterCursor(rec) {
+				recs = append(recs, *rec)
+			}
+		}
+	}
+	return paginate(recs, q)
+}
+
+// MemoryBackend 只存在記憶體中的儲存後端 (測試或不需保留工作時使用), 重新啟動後清空
+type MemoryBackend struct {
+	mu    sync.RWMutex
+	index *memIndex
+	data  map[string][]byte
+}
+
+// NewMemoryBackend 建立記憶體儲存後端
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{index: newMemIndex(), data: map[string][]byte{}}
+}
+
+// Put 建立工作
+func (b *MemoryBackend) Put(rec *Record) error {
+	stored := *rec
+	stored.Data = nil
+	stored.Location = "memory:" + rec.ID
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.index.add(&stored) {
+		return ErrJobExists
+	}
+	b.data[rec.ID] = append([]byte(nil), rec.Data...)
+	rec.Location = stored.Location
+	return nil
+}
+
+// Get 取得工作
+func (b *MemoryBackend) Get(id string) (*Record, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	rec, ok := b.index.records[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	out := *rec
+	out.Data = append([]byte(nil), b.data[id]...)
+	return &out, nil
+}
+
+// Delete 刪除工作
+func (b *MemoryBackend) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.index.remove(id) == nil {
+		return ErrJobNotFound
+	}
+	delete(b.data, id)
+	return nil
+}
+
+// List 查詢工作
+func (b *MemoryBackend) List(q Query) ([]Record, int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	recs, total := b.index.list(q)
+	if !q.Meta {
+		for i := range recs {
+			recs[i].Metadata = nil
+		}
+	}
+	return recs, total, nil
+}
+
+// Close 記憶體後端沒有需要釋放的資源
+func (b *MemoryBackend) Close() error {
+	return nil
+}
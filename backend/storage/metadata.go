@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"time"
+
+	"tspl-simulator/models"
+	"tspl-simulator/validator"
+)
+
+// NewJobMetadata 依驗證結果與解析結果建立工作中繼資料
+// data 為解析結果 (未解析或解析失敗時為 nil); 來源位址、MQTT 主題與請求 ID 由呼叫端填入
+func NewJobMetadata(source string, result *validator.ValidationResult, data *models.RenderData, parseDuration time.Duration, parseErr error) *models.JobMetadata {
+	meta := &models.JobMetadata{
+		Source:             source,
+		Valid:              result.Valid,
+		ValidationErrors:   modelErrors(result.Errors),
+		ValidationWarnings: modelErrors(result.Warnings),
+		ParseDurationMS:    float64(parseDuration.Microseconds()) / 1000,
+	}
+	if parseErr != nil {
+		meta.ParseError = parseErr.Error()
+	}
+	if data != nil {
+		meta.ElementCounts = map[string]int{}
+		for _, el := range data.Elements {
+			meta.ElementCounts[el.Type]++
+		}
+		meta.Printer = &models.PrinterProfile{
+			DPI:       data.DPI,
+			Width:     data.Width,
+			Height:    data.Height,
+			LabelSize: data.LabelSize,
+			Media:     data.Media,
+			Direction: data.Direction,
+			Reference: data.Reference,
+			Print:     data.Print,
+		}
+	}
+	return meta
+}
+
+// modelErrors 將驗證器的錯誤轉為 API 模型
+func modelErrors(errs []validator.ValidationError) []models.ValidationError {
+	var out []models.ValidationError
+	for _, err := range errs {
+		out = append(out, models.ValidationError{
+			Line:    err.Line,
+			Column:  err.Column,
+			Command: err.Command,
+			AI:      err.AI,
+			Message: err.Message,
+		})
+	}
+	return out
+}
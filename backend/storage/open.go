@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Open 依後端名稱建立儲存服務: fs (path 下的資料夾)、memory、kv (path/jobs.db) 或 s3
+func Open(backend, path string, s3 S3Config) (*StorageService, error) {
+	switch backend {
+	case "fs":
+		return NewStorageService(path), nil
+	case "memory":
+		return NewStorageServiceWithBackend(NewMemoryBackend()), nil
+	case "kv":
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, fmt.Errorf("建立資料夾失敗: %v", err)
+		}
+		b, err := OpenKVBackend(filepath.Join(path, "jobs.db"))
+		if err != nil {
+			return nil, err
+		}
+		return NewStorageServiceWithBackend(b), nil
+	case "s3":
+		b, err := NewS3Backend(s3)
+		if err != nil {
+			return nil, err
+		}
+		return NewStorageServiceWithBackend(b), nil
+	default:
+		return nil, fmt.Errorf("未知的儲存後端: %s", backend)
+	}
+}
+
+// OpenReadOnly 與 Open 相同, 但 kv 後端以唯讀方式開啟既有的 path/jobs.db,
+// 供命令列工具在伺服器執行中時讀取, 不會截斷或壓縮伺服器正在寫入的記錄檔
+func OpenReadOnly(backend, path string, s3 S3Config) (*StorageService, error) {
+	if backend != "kv" {
+		return Open(backend, path, s3)
+	}
+	b, err := OpenKVBackendReadOnly(filepath.Join(path, "jobs.db"))
+	if err != nil {
+		return nil, err
+	}
+	return NewStorageServiceWithBackend(b), nil
+}
+
+// ParseJobTime 解析查詢用的日期 (本地時區) 或 RFC 3339 時間, 空字串為零值;
+// endOfDay 為 true 時日期換算為隔天 0 點, 作為不含的上限
+func ParseJobTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
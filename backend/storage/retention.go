@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"tspl-simulator/models"
+)
+
+// maxReportedJobs 報告中每個來源最多列出的過期工作數
+const maxReportedJobs = 1000
+
+// RetentionPolicy 單一來源的保留規則, 零值代表不限
+// 驗證失敗的工作與同來源的工作一起計算
+type RetentionPolicy struct {
+	MaxAge   time.Duration // 超過此時間的工作刪除
+	MaxJobs  int           // 只保留最新的 N 筆
+	MaxBytes int64         // 只保留最新且 TSPL 總位元組數不超過此值的工作
+}
+
+// limited 是否設定了任何限制
+func (p RetentionPolicy) limited() bool {
+	return p.MaxAge > 0 || p.MaxJobs > 0 || p.MaxBytes > 0
+}
+
+// RetentionConfig 背景清理的設定
+type RetentionConfig struct {
+	Policies      map[string]RetentionPolicy // 來源 (api / mqtt) → 保留規則
+	CompressAfter time.Duration              // 日期資料夾整天超過此時間後壓縮, 0 代表不壓縮
+	Interval      time.Duration              // 執行間隔, 預設每小時
+}
+
+// Enabled 是否需要啟動背景清理
+func (c RetentionConfig) Enabled() bool {
+	for _, p := range c.Policies {
+		if p.limited() {
+			return true
+		}
+	}
+	return c.CompressAfter > 0
+}
+
+// Compressor 可壓縮舊工作的儲存後端 (目前只有檔案系統後端)
+type Compressor interface {
+	// CompressBefore 壓縮整天都早於 before 的日期資料夾; dryRun 時只統計不壓縮
+	CompressBefore(before time.Time, dryRun bool) (*models.CompressionReport, error)
+}
+
+// Janitor 依保留規則定期刪除過期工作並壓縮舊的日期資料夾
+type Janitor struct {
+	service *StorageService
+	config  RetentionConfig
+
+	mu      sync.Mutex // 同一時間只執行一次
+	last    *models.RetentionReport
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewJanitor 建立背景清理
+func NewJanitor(service *StorageService, config RetentionConfig) *Janitor {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	return &Janitor{service: service, config: config}
+}
+
+// Enabled 是否設定了任何保留規則或壓縮
+func (j *Janitor) Enabled() bool {
+	return j.config.Enabled()
+}
+
+// Start 啟動背景清理: 立即執行一次, 之後每隔 Interval 執行
+func (j *Janitor) Start() {
+	j.stop, j.stopped = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(j.stopped)
+		ticker := time.NewTicker(j.config.Interval)
+		defer ticker.Stop()
+		for {
+			if report, err := j.Run(false); err != nil {
+				log.Printf("保留規則執行失敗: %v", err)
+			} else {
+				logRetention(report)
+			}
+			select {
+			case <-ticker.C:
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止背景清理並等待執行中的清理結束
+func (j *Janitor) Stop() {
+	if j.stop == nil {
+		return
+	}
+	close(j.stop)
+	<-j.stopped
+	j.stop = nil
+}
+
+// LastReport 最近一次實際執行的結果; 尚未執行時為 nil
+func (j *Janitor) LastReport() *models.RetentionReport {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last
+}
+
+// Run 套用保留規則; dryRun 時只回報會刪除與壓縮的內容
+// 個別工作刪除失敗時記入報告並繼續, 無法列出工作時回傳錯誤
+func (j *Janitor) Run(dryRun bool) (*models.RetentionReport, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	report := &models.RetentionReport{DryRun: dryRun, StartedAt: now, Sources: []models.RetentionSourceReport{}}
+	for _, source := range []string{"api", "mqtt"} {
+		policy := j.config.Policies[source]
+		recs, _, err := j.service.backend.List(Query{Buckets: []string{source, source + "-rejected"}})
+		if err != nil {
+			return nil, err
+		}
+
+		sr := models.RetentionSourceReport{Source: source, MaxJobs: policy.MaxJobs, MaxBytes: policy.MaxBytes}
+		if policy.MaxAge > 0 {
+			sr.MaxAge = policy.MaxAge.String()
+		}
+		var full string // 由新到舊累計, 超過數量或容量後較舊的工作全部過期
+		for _, rec := range recs {
+			sr.Jobs++
+			sr.Bytes += rec.Size
+			if full == "" {
+				if policy.MaxJobs > 0 && sr.Jobs > policy.MaxJobs {
+					full = "max_jobs"
+				} else if policy.MaxBytes > 0 && sr.Bytes > policy.MaxBytes {
+					full = "max_bytes"
+				}
+			}
+			reason := full
+			if policy.MaxAge > 0 && now.Sub(rec.CreatedAt) > policy.MaxAge {
+				reason = "max_age"
+			}
+			if reason == "" {
+				continue
+			}
+
+			if !dryRun {
+				if err := j.service.DeleteJob(rec.ID); err != nil && !errors.Is(err, ErrJobNotFound) {
+					report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", rec.ID, err))
+					continue
+				}
+			}
+			sr.ExpiredJobs++
+			sr.ExpiredBytes += rec.Size
+			if len(sr.Expired) < maxReportedJobs {
+				sr.Expired = append(sr.Expired, models.ExpiredJob{ID: rec.ID, CreatedAt: rec.CreatedAt, Size: rec.Size, Reason: reason})
+			}
+		}
+		report.Sources = append(report.Sources, sr)
+	}
+
+	if compressor, ok := j.service.backend.(Compressor); ok && j.config.CompressAfter > 0 {
+		compression, err := compressor.CompressBefore(now.Add(-j.config.CompressAfter), dryRun)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		report.Compression = compression
+	}
+
+	if !dryRun {
+		j.last = report
+	}
+	return report, nil
+}
+
+// logRetention 記錄背景清理的結果
+func logRetention(report *models.RetentionReport) {
+	for _, sr := range report.Sources {
+		if sr.ExpiredJobs > 0 {
+			log.Printf("保留規則: 刪除 %s 的 %d 筆工作 (%d 位元組), 剩餘 %d 筆", sr.Source, sr.ExpiredJobs, sr.ExpiredBytes, sr.Jobs-sr.ExpiredJobs)
+		}
+	}
+	if c := report.Compression; c != nil && c.Files > 0 {
+		log.Printf("保留規則: 壓縮 %d 個資料夾的 %d 個工作檔 (%d → %d 位元組)", len(c.Folders), c.Files, c.Bytes, c.CompressedBytes)
+	}
+	for _, e := range report.Errors {
+		log.Printf("保留規則錯誤: %s", e)
+	}
+}
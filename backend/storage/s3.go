@@ -0,0 +1,401 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"tspl-simulator/models"
+)
+
+// S3Config S3 相容物件儲存的連線設定
+type S3Config struct {
+	Endpoint  string // 例如 https://s3.ap-northeast-1.amazonaws.com 或本機的 http://localhost:9000
+	Region    string
+	Bucket    string
+	Prefix    string // 物件鍵的前綴, 例如 "tspl/"
+	AccessKey string // 空字串時不簽章 (本機測試用的替身服務)
+	SecretKey string
+}
+
+// S3Backend 以 S3 相容物件儲存 (AWS S3、MinIO 等) 保存工作, 使用 path-style 位址與 SigV4 簽章
+// 物件鍵: 前綴/資料夾/年_月_日 (UTC)/ULID.tspl 與 .json; 內容雜湊另以 前綴/hash/雜湊/工作 ID 空物件索引
+type S3Backend struct {
+	config S3Config
+	client *http.Client
+}
+
+// NewS3Backend 建立 S3 儲存後端
+func NewS3Backend(config S3Config) (*S3Backend, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("S3 需要設定 endpoint 與 bucket")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &S3Backend{config: config, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Put 建立工作; 以 If-None-Match: * 條件寫入, 物件已存在時回傳 ErrJobExists
+func (b *S3Backend) Put(rec *Record) error {
+	key, ok := b.jobKey(rec.ID)
+	if !ok {
+		return fmt.Errorf("工作 ID 格式錯誤: %s", rec.ID)
+	}
+	headers := map[string]string{
+		"If-None-Match":     "*",
+		"Content-Type":      "text/plain; charset=utf-8",
+		"X-Amz-Meta-Sha256": rec.Hash,
+	}
+	status, _, err := b.do(http.MethodPut, key+".tspl", nil, rec.Data, headers)
+	if err != nil {
+		if status == http.StatusPreconditionFailed {
+			return ErrJobExists
+		}
+		return err
+	}
+	rec.Location = "s3://" + b.config.Bucket + "/" + key + ".tspl"
+
+	if rec.Metadata != nil {
+		data, err := json.Marshal(rec.Metadata)
+		if err != nil {
+			return fmt.Errorf("序列化中繼資料失敗: %v", err)
+		}
+		if _, _, err := b.do(http.MethodPut, key+".json", nil, data, map[string]string{"Content-Type": "application/json"}); err != nil {
+			return err
+		}
+	}
+	if rec.Hash != "" {
+		if _, _, err := b.do(http.MethodPut, b.hashKey(rec.Hash, rec.ID), nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get 讀取工作與中繼資料
+func (b *S3Backend) Get(id string) (*Record, error) {
+	key, ok := b.jobKey(id)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	status, data, err := b.do(http.MethodGet, key+".tspl", nil, nil, nil)
+	if status == http.StatusNotFound {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	bucket, ulid, _ := parseJobID(id)
+	createdAt, _ := ulidTime(ulid)
+	rec := &Record{
+		ID:        id,
+		Bucket:    bucket,
+		CreatedAt: createdAt,
+		Size:      int64(len(data)),
+		Hash:      ContentHash(data),
+		Location:  "s3://" + b.config.Bucket + "/" + key + ".tspl",
+		Data:      data,
+	}
+	if rec.Metadata, err = b.metadata(key); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Delete 刪除工作、中繼資料與雜湊索引
+func (b *S3Backend) Delete(id string) error {
+	rec, err := b.Get(id)
+	if err != nil {
+		return err
+	}
+	key, _ := b.jobKey(id)
+	for _, k := range []string{key + ".tspl", key + ".json", b.hashKey(rec.Hash, id)} {
+		if status, _, err := b.do(http.MethodDelete, k, nil, nil, nil); err != nil && status != http.StatusNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// List 指定雜湊時列出雜湊索引, 否則依日期前綴縮小範圍後列出物件
+func (b *S3Backend) List(q Query) ([]Record, int, error) {
+	var recs []Record
+	if q.Hash != "" {
+		keys, _, err := b.listObjects(b.config.Prefix+"hash/"+q.Hash+"/", "")
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, obj := range keys {
+			rec, err := b.Get(obj.Key[strings.LastIndex(obj.Key, "/")+1:])
+			if err != nil {
+				continue // 索引殘留但工作已刪除
+			}
+			rec.Data, rec.Metadata = nil, nil
+			if q.matches(rec) {
+				recs = append(recs, *rec)
+			}
+		}
+	} else {
+		for bucket, folder := range jobBuckets {
+			if !q.hasBucket(bucket) {
+				continue
+			}
+			_, days, err := b.listObjects(b.config.Prefix+folder+"/", "/")
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, dayPrefix := range days {
+				day := strings.TrimSuffix(dayPrefix[strings.LastIndex(strings.TrimSuffix(dayPrefix, "/"), "/")+1:], "/")
+				if !dayInRange(day, q.From, q.To, time.UTC) {
+					continue
+				}
+				objects, _, err := b.listObjects(dayPrefix, "")
+				if err != nil {
+					return nil, 0, err
+				}
+				for _, obj := range objects {
+					name, ok := strings.CutSuffix(obj.Key[strings.LastIndex(obj.Key, "/")+1:], ".tspl")
+					if !ok {
+						continue
+					}
+					createdAt, ok := ulidTime(name)
+					if !ok {
+						continue
+					}
+					rec := Record{ID: bucket + "-" + name, Bucket: bucket, CreatedAt: createdAt, Size: obj.Size, Location: "s3://" + b.config.Bucket + "/" + obj.Key}
+					if q.matches(&rec) {
+						recs = append(recs, rec)
+					}
+				}
+			}
+		}
+	}
+
+	page, total := paginate(recs, q)
+	if q.Meta {
+		for i := range page {
+			// 列表中略過無法讀取的中繼資料, 錯誤由 Get 回報
+			if key, ok := b.jobKey(page[i].ID); ok {
+				page[i].Metadata, _ = b.metadata(key)
+			}
+		}
+	}
+	return page, total, nil
+}
+
+// Close S3 後端沒有需要釋放的資源
+func (b *S3Backend) Close() error {
+	return nil
+}
+
+// jobKey 工作的物件鍵 (不含副檔名)
+func (b *S3Backend) jobKey(id string) (string, bool) {
+	bucket, ulid, ok := parseJobID(id)
+	if !ok {
+		return "", false
+	}
+	t, _ := ulidTime(ulid)
+	return b.config.Prefix + jobBuckets[bucket] + "/" + t.UTC().Format("2006_01_02") + "/" + ulid, true
+}
+
+// hashKey 內容雜湊索引的物件鍵
+func (b *S3Backend) hashKey(hash, id string) string {
+	return b.config.Prefix + "hash/" + hash + "/" + id
+}
+
+// metadata 讀取中繼資料物件; 不存在時回傳 nil
+func (b *S3Backend) metadata(key string) (*models.JobMetadata, error) {
+	status, data, err := b.do(http.MethodGet, key+".json", nil, nil, nil)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta models.JobMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("解析中繼資料失敗: %v", err)
+	}
+	return &meta, nil
+}
+
+// s3Object ListObjectsV2 回應中的物件
+type s3Object struct {
+	Key  string `xml:"Key"`
+	Size int64  `xml:"Size"`
+}
+
+// listObjects 以 ListObjectsV2 列出前綴下的物件與 (指定 delimiter 時的) 子前綴, 自動處理分頁
+func (b *S3Backend) listObjects(prefix, delimiter string) ([]s3Object, []string, error) {
+	var objects []s3Object
+	var prefixes []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		_, body, err := b.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var result struct {
+			Contents       []s3Object `xml:"Contents"`
+			CommonPrefixes []struct {
+				Prefix string `xml:"Prefix"`
+			} `xml:"CommonPrefixes"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, nil, fmt.Errorf("解析 S3 物件列表失敗: %v", err)
+		}
+		objects = append(objects, result.Contents...)
+		for _, p := range result.CommonPrefixes {
+			prefixes = append(prefixes, p.Prefix)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, prefixes, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do 發送簽章後的請求, 回傳狀態碼與內容; 非 2xx 時回傳錯誤 (仍回傳狀態碼)
+func (b *S3Backend) do(method, key string, query url.Values, body []byte, headers map[string]string) (int, []byte, error) {
+	path := "/" + s3Escape(b.config.Bucket, false)
+	if key != "" {
+		path += "/" + s3Escape(key, false)
+	}
+	rawQuery := canonicalQuery(query)
+	target := b.config.Endpoint + path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("建立 S3 請求失敗: %v", err)
+	}
+	for k, v := range headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	b.sign(req, path, rawQuery, body, time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("S3 請求失敗: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("讀取 S3 回應失敗: %v", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		xml.Unmarshal(data, &s3Err)
+		return resp.StatusCode, data, fmt.Errorf("S3 %s %s 失敗: %s %s %s", method, path, resp.Status, s3Err.Code, s3Err.Message)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// sign 以 AWS Signature Version 4 簽署請求; 未設定金鑰時不簽章
+func (b *S3Backend) sign(req *http.Request, path, rawQuery string, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if b.config.AccessKey == "" {
+		return
+	}
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	// 簽署 host 與所有 x-amz-* 標頭
+	signed := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			signed[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(signed))
+	for k := range signed {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + signed[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, rawQuery, canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := amzDate[:8] + "/" + b.config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.config.SecretKey), amzDate[:8])
+	key = hmacSHA256(key, b.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.config.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 計算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery 依 SigV4 規則排序並編碼查詢參數
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape 依 SigV4 規則編碼 (保留 A-Z a-z 0-9 - _ . ~), encodeSlash 為 false 時保留 /
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 最小的 S3 替身: path-style 的 PUT (含 If-None-Match: *)、GET、DELETE 與 ListObjectsV2
+// 列表每頁最多 pageSize 筆, 以驗證分頁處理
+type fakeS3 struct {
+	bucket   string
+	pageSize int
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string) *httptest.Server {
+	f := &fakeS3{bucket: bucket, pageSize: 2, objects: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	if bucket != f.bucket {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r)
+	case r.Method == http.MethodPut:
+		if _, ok := f.objects[key]; ok && r.Header.Get("If-None-Match") == "*" {
+			f.error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// list ListObjectsV2: prefix、delimiter 與以起始位置為 continuation-token 的分頁
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	prefix, delimiter := q.Get("prefix"), q.Get("delimiter")
+
+	type entry struct {
+		key, prefix string
+	}
+	var entries []entry
+	seen := map[string]bool{}
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				p := key[:len(prefix)+i+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					entries = append(entries, entry{prefix: p})
+				}
+				continue
+			}
+		}
+		entries = append(entries, entry{key: key})
+	}
+
+	start, _ := strconv.Atoi(q.Get("continuation-token"))
+	end := min(start+f.pageSize, len(entries))
+	type object struct {
+		Key  string
+		Size int64
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName               xml.Name       `xml:"ListBucketResult"`
+		Contents              []object       `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{IsTruncated: end < len(entries)}
+	for _, e := range entries[min(start, end):end] {
+		if e.prefix != "" {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{e.prefix})
+		} else {
+			result.Contents = append(result.Contents, object{e.key, int64(len(f.objects[e.key]))})
+		}
+	}
+	if result.IsTruncated {
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
+
+func TestS3BackendConformance(t *testing.T) {
+	srv := newFakeS3(t, "labels")
+	b, err := NewS3Backend(S3Config{Endpoint: srv.URL, Bucket: "labels", Prefix: "tspl/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBackendConformance(t, b)
+}
+
+func TestS3BackendSignsRequests(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	b, err := NewS3Backend(S3Config{Endpoint: srv.URL, Bucket: "labels", AccessKey: "AKID", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := ulidAt(time.Now())
+	b.Get("api-" + id)
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/us-east-1/s3/aws4_request") {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
@@ -2,106 +2,117 @@ package storage
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+	"sync"
 	"time"
+
+	"tspl-simulator/models"
 )
 
-// StorageService 儲存服務
+// StorageService 儲存服務, 實際的讀寫交由儲存後端 (檔案系統、記憶體、KV 或 S3)
 type StorageService struct {
-	basePath string
+	backend   Backend
+	ids       ulidGenerator
+	listeners []Listener
+	importMu  sync.Mutex
+}
+
+// Listener 接收工作建立與刪除的通知 (例如搜尋索引); 在儲存或刪除的 goroutine 中同步呼叫
+type Listener interface {
+	JobSaved(job models.Job, code string)
+	JobDeleted(id string)
 }
 
-// NewStorageService 建立新的儲存服務
+// NewStorageService 建立以本機檔案系統儲存的服務
 func NewStorageService(basePath string) *StorageService {
+	return NewStorageServiceWithBackend(NewFSBackend(basePath))
+}
+
+// NewStorageServiceWithBackend 建立使用指定儲存後端的服務
+func NewStorageServiceWithBackend(backend Backend) *StorageService {
 	return &StorageService{
-		basePath: basePath,
+		backend: backend,
 	}
 }
 
-// SaveAPIData 儲存 API 接收的資料
-func (s *StorageService) SaveAPIData(data string) (string, error) {
-	return s.saveData("API_print", data)
+// AddListener 註冊工作建立與刪除的通知, 須在開始儲存前呼叫
+func (s *StorageService) AddListener(l Listener) {
+	s.listeners = append(s.listeners, l)
 }
 
-// SaveMQTTData 儲存 MQTT 接收的資料
-func (s *StorageService) SaveMQTTData(data string) (string, error) {
-	return s.saveData("MQTT_print", data)
+// Close 關閉儲存後端
+func (s *StorageService) Close() error {
+	return s.backend.Close()
 }
 
-// saveData 儲存資料到指定類型的資料夾
-func (s *StorageService) saveData(dataType string, data string) (string, error) {
-	now := time.Now()
+// SaveAPIData 儲存 API 接收的資料與中繼資料 (meta 可為 nil)
+func (s *StorageService) SaveAPIData(data string, meta *models.JobMetadata) (string, error) {
+	return s.saveData("api", data, meta)
+}
 
-	// 建立資料夾路徑: basePath/dataType/年_月_日
-	dateFolder := now.Format("2006_01_02")
-	folderPath := filepath.Join(s.basePath, dataType, dateFolder)
+// SaveMQTTData 儲存 MQTT 接收的資料與中繼資料 (meta 可為 nil)
+func (s *StorageService) SaveMQTTData(data string, meta *models.JobMetadata) (string, error) {
+	return s.saveData("mqtt", data, meta)
+}
 
-	// 確保資料夾存在
-	if err := os.MkdirAll(folderPath, 0755); err != nil {
-		return "", fmt.Errorf("建立資料夾失敗: %v", err)
-	}
+// SaveRejectedAPIData 儲存驗證失敗的 API 資料與中繼資料 (含驗證錯誤)
+func (s *StorageService) SaveRejectedAPIData(data string, meta *models.JobMetadata) (string, error) {
+	return s.saveData("api-rejected", data, meta)
+}
 
-	// 檔案名稱: 時_分_秒.tspl
-	fileName := now.Format("15_04_05") + ".tspl"
-	filePath := filepath.Join(folderPath, fileName)
+// SaveRejectedMQTTData 儲存驗證失敗的 MQTT 資料與中繼資料 (含驗證錯誤)
+func (s *StorageService) SaveRejectedMQTTData(data string, meta *models.JobMetadata) (string, error) {
+	return s.saveData("mqtt-rejected", data, meta)
+}
 
-	// 如果檔案已存在,加上毫秒避免衝突
-	if _, err := os.Stat(filePath); err == nil {
-		fileName = now.Format("15_04_05") + "_" + fmt.Sprintf("%03d", now.Nanosecond()/1000000) + ".tspl"
-		filePath = filepath.Join(folderPath, fileName)
+// saveData 儲存資料到指定類型的工作類別, 回傳工作在後端中的位置
+// 工作 ID 為單調遞增的 ULID, 後端不覆蓋既有的工作, 因此同時到達的 API 與 MQTT 請求不會互相覆蓋
+func (s *StorageService) saveData(bucket string, data string, meta *models.JobMetadata) (string, error) {
+	id, createdAt := s.ids.next(time.Now())
+	rec := &Record{
+		ID:        bucket + "-" + id,
+		Bucket:    bucket,
+		CreatedAt: createdAt,
+		Size:      int64(len(data)),
+		Hash:      ContentHash([]byte(data)),
+		Data:      []byte(data),
+		Metadata:  meta,
 	}
-
-	// 寫入檔案
-	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
-		return "", fmt.Errorf("寫入檔案失敗: %v", err)
+	if err := s.backend.Put(rec); err != nil {
+		return rec.Location, err
 	}
-
-	return filePath, nil
+	for _, l := range s.listeners {
+		l.JobSaved(rec.Job(), data)
+	}
+	return rec.Location, nil
 }
 
-// GetRecentFiles 取得最近的檔案列表
+// GetRecentFiles 取得最近的檔案列表, dataType 為資料夾名稱 (API_print、MQTT_print...)
 func (s *StorageService) GetRecentFiles(dataType string, limit int) ([]FileInfo, error) {
-	basePath := filepath.Join(s.basePath, dataType)
-
-	var files []FileInfo
-
-	// 遍歷日期資料夾
-	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	var buckets []string
+	for bucket, folder := range jobBuckets {
+		if folder == dataType {
+			buckets = append(buckets, bucket)
 		}
+	}
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("未知的資料類型: %s", dataType)
+	}
 
-		if !info.IsDir() && filepath.Ext(path) == ".tspl" {
-			files = append(files, FileInfo{
-				Path:     path,
-				Name:     info.Name(),
-				Size:     info.Size(),
-				ModTime:  info.ModTime(),
-			})
-		}
-
-		return nil
-	})
-
+	recs, _, err := s.backend.List(Query{Buckets: buckets, Limit: limit})
 	if err != nil {
 		return nil, fmt.Errorf("讀取檔案列表失敗: %v", err)
 	}
 
-	// 按修改時間排序（最新的在前）
-	for i := 0; i < len(files)-1; i++ {
-		for j := i + 1; j < len(files); j++ {
-			if files[i].ModTime.Before(files[j].ModTime) {
-				files[i], files[j] = files[j], files[i]
-			}
-		}
-	}
-
-	// 限制數量
-	if limit > 0 && len(files) > limit {
-		files = files[:limit]
+	files := make([]FileInfo, 0, len(recs))
+	for _, rec := range recs {
+		files = append(files, FileInfo{
+			Path:    rec.Location,
+			Name:    filepath.Base(rec.Location),
+			Size:    rec.Size,
+			ModTime: rec.CreatedAt,
+		})
 	}
-
 	return files, nil
 }
 
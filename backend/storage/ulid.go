@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// crockford ULID 使用的 Crockford Base32 字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLength ULID 字串長度 (48 位元毫秒時間 + 80 位元亂數, 共 26 字元)
+const ulidLength = 26
+
+// ulidGenerator 產生單調遞增的 ULID: 同一毫秒 (或時鐘倒退) 時沿用上一個時間並將亂數部分加一,
+// 因此依字串排序即為建立順序
+type ulidGenerator struct {
+	mu     sync.Mutex
+	lastMS uint64
+	random [10]byte
+}
+
+// next 產生新的 ULID, 並回傳其代表的時間 (毫秒精度)
+func (g *ulidGenerator) next(now time.Time) (string, time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(now.UnixMilli())
+	if ms <= g.lastMS {
+		ms = g.lastMS
+		for i := len(g.random) - 1; i >= 0; i-- {
+			g.random[i]++
+			if g.random[i] != 0 {
+				break
+			}
+		}
+	} else {
+		g.lastMS = ms
+		rand.Read(g.random[:])
+	}
+
+	return buildULID(ms, g.random), time.UnixMilli(int64(ms))
+}
+
+// ulidAt 產生指定時間的 ULID (亂數部分不延續產生器), 用於保留原始建立時間的匯入
+func ulidAt(t time.Time) (string, time.Time) {
+	var random [10]byte
+	rand.Read(random[:])
+	ms := uint64(t.UnixMilli())
+	return buildULID(ms, random), time.UnixMilli(int64(ms))
+}
+
+// buildULID 組合 48 位元毫秒時間與 80 位元亂數並編碼
+func buildULID(ms uint64, random [10]byte) string {
+	var b [16]byte
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(b[6:], random[:])
+	return encodeULID(b)
+}
+
+// encodeULID 將 128 位元以 Crockford Base32 編碼 (最前面補 2 個 0 位元, 共 130 位元)
+func encodeULID(b [16]byte) string {
+	out := make([]byte, ulidLength)
+	for i := range out {
+		v := 0
+		for bit := 0; bit < 5; bit++ {
+			pos := i*5 + bit - 2 // 在 128 位元中的位置
+			v <<= 1
+			if pos >= 0 && b[pos/8]&(0x80>>(pos%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out)
+}
+
+// ulidTime 取得 ULID 的時間; 格式錯誤時回傳 false
+func ulidTime(id string) (time.Time, bool) {
+	if len(id) != ulidLength || id[0] > '7' {
+		return time.Time{}, false
+	}
+	var ms int64
+	for i := 0; i < ulidLength; i++ {
+		v := -1
+		for n := 0; n < len(crockford); n++ {
+			if crockford[n] == id[i] {
+				v = n
+				break
+			}
+		}
+		if v < 0 {
+			return time.Time{}, false
+		}
+		if i < 10 {
+			ms = ms<<5 | int64(v)
+		}
+	}
+	return time.UnixMilli(ms), true
+}
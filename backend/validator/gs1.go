@@ -0,0 +1,47 @@
+package validator
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"tspl-simulator/gs1"
+)
+
+// validateGS1 檢查 EAN128 條碼與以 (AI) 開頭的 QR Code 資料是否符合 GS1 規範
+// indent 為原始行首的空白長度, 用於換算錯誤所在的欄位
+func validateGS1(command, line string, lineNum, indent int) []ValidationError {
+	var match []int
+	var data string
+	switch command {
+	case "BARCODE":
+		match = barcodePattern.FindStringSubmatchIndex(line)
+		if match == nil || strings.ToUpper(line[match[6]:match[7]]) != "EAN128" {
+			return nil
+		}
+		data = line[match[18]:match[19]]
+	case "QRCODE":
+		match = qrcodePattern.FindStringSubmatchIndex(line)
+		if match == nil {
+			return nil
+		}
+		data = line[match[20]:match[21]]
+		if !gs1.IsElementString(data) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	start := match[len(match)-2]
+
+	var errs []ValidationError
+	for _, err := range gs1.Validate(data) {
+		errs = append(errs, ValidationError{
+			Line:    lineNum,
+			Column:  indent + utf8.RuneCountInString(line[:start+err.Offset]) + 1,
+			Command: command,
+			AI:      err.AI,
+			Message: "GS1 " + err.Error(),
+		})
+	}
+	return errs
+}
@@ -0,0 +1,163 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+	"tspl-simulator/qrcode"
+)
+
+// labelArea 目前的標籤尺寸 (點) 與 REFERENCE 參考點
+type labelArea struct {
+	width, height int
+	refX, refY    int
+	known         bool // 是否已設定 SIZE
+}
+
+// setSize 依 SIZE 參數設定標籤尺寸, 格式錯誤時保持不變 (由 validateSize 回報)
+func (a *labelArea) setSize(parts []string) {
+	if len(parts) < 2 {
+		return
+	}
+	width, unit1, err1 := parseValueWithUnit(parts[0])
+	height, unit2, err2 := parseValueWithUnit(parts[1])
+	if err1 != nil || err2 != nil {
+		return
+	}
+	a.width = parser.ToDots(width, unit1)
+	a.height = parser.ToDots(height, unit2)
+	a.known = true
+}
+
+// setReference 依 REFERENCE 參數設定參考點
+func (a *labelArea) setReference(parts []string) {
+	if len(parts) < 2 {
+		return
+	}
+	x, err1 := strconv.Atoi(strings.TrimSuffix(parts[0], ","))
+	y, err2 := strconv.Atoi(parts[1])
+	if err1 == nil && err2 == nil {
+		a.refX, a.refY = x, y
+	}
+}
+
+// remaining 元素從 (x, y) 往旋轉方向延伸時, 到標籤邊緣的剩餘寬高 (點)
+func (a *labelArea) remaining(x, y, rotation int) (int, int) {
+	x += a.refX
+	y += a.refY
+	switch rotation {
+	case 90:
+		return x + 1, a.height - y
+	case 180:
+		return x + 1, y + 1
+	case 270:
+		return a.width - x, y + 1
+	default:
+		return a.width - x, a.height - y
+	}
+}
+
+// manualMode 解析 QRCODE 手動模式 (M) 資料開頭的模式字元: N 數字、A 英數、B 位元組 (後接 4 位數長度)、K 漢字
+func manualMode(data string) (qrcode.Mode, string, error) {
+	if data == "" {
+		return qrcode.ModeByte, "", fmt.Errorf("手動模式的資料需以模式字元 N/A/B/K 開頭")
+	}
+	switch data[0] {
+	case 'N':
+		return qrcode.ModeNumeric, data[1:], nil
+	case 'A':
+		return qrcode.ModeAlphanumeric, data[1:], nil
+	case 'B':
+		if len(data) < 5 || !isDigits(data[1:5]) {
+			return qrcode.ModeByte, "", fmt.Errorf("手動模式 B 需要 4 位數的資料長度")
+		}
+		n, _ := strconv.Atoi(data[1:5])
+		if n != len(data)-5 {
+			return qrcode.ModeByte, "", fmt.Errorf("手動模式 B 指定長度 %d 與實際資料長度 %d 不符", n, len(data)-5)
+		}
+		return qrcode.ModeByte, data[5:], nil
+	case 'K':
+		return qrcode.ModeByte, data[1:], nil
+	default:
+		return qrcode.ModeByte, "", fmt.Errorf("未知的手動模式字元 %q, 應為 N/A/B/K", data[0])
+	}
+}
+
+// isDigits 判斷字串是否全為數字
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validateQRCapacity 計算 QR Code 在指定糾錯等級與編碼模式下所需的最小版本與實際尺寸,
+// 超出版本 40 或超出 x/y 到標籤邊緣的剩餘空間時回報警告
+func validateQRCapacity(line string, lineNum, indent int, area labelArea) (*models.QRCapacity, []ValidationError, []ValidationError) {
+	match := qrcodePattern.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, nil, nil
+	}
+	group := func(i int) string { return line[match[2*i]:match[2*i+1]] }
+	column := func(i int) int { return indent + utf8.RuneCountInString(line[:match[2*i]]) + 1 }
+	issue := func(i int, format string, args ...interface{}) ValidationError {
+		return ValidationError{Line: lineNum, Column: column(i), Command: "QRCODE", Message: fmt.Sprintf(format, args...)}
+	}
+
+	x, _ := strconv.Atoi(group(1))
+	y, _ := strconv.Atoi(group(2))
+	ecc := qrcode.ParseECC(group(3))
+	cell, _ := strconv.Atoi(group(4))
+	rotation, _ := strconv.Atoi(group(6))
+	data := group(10)
+
+	mode := qrcode.DetectMode(data)
+	content := data
+	if group(5) == "M" {
+		var err error
+		if mode, content, err = manualMode(data); err != nil {
+			return nil, []ValidationError{issue(10, "QRCODE %v", err)}, nil
+		}
+		if mode != qrcode.ModeByte && content != "" && qrcode.DetectMode(content) > mode {
+			return nil, []ValidationError{issue(10, "QRCODE 手動模式資料含有 %s 模式無法編碼的字元", mode)}, nil
+		}
+	}
+
+	report := &models.QRCapacity{Line: lineNum, ECC: ecc.String(), Mode: mode.String(), CellSize: cell, Fits: true}
+	version, err := qrcode.MinVersionMode(content, mode, ecc)
+	if err != nil {
+		report.Fits = false
+		return report, nil, []ValidationError{issue(10, "QRCODE %v, 請降低糾錯等級或縮短資料", err)}
+	}
+	report.Version = version
+	report.Modules = 17 + 4*version
+	report.SizeDots = report.Modules * cell
+	report.SizeMM = dotsToMM(report.SizeDots)
+
+	if !area.known {
+		return report, nil, nil
+	}
+	availW, availH := area.remaining(x, y, rotation)
+	report.AvailableWidth, report.AvailableHeight = availW, availH
+	report.AvailableWidthMM, report.AvailableHeightMM = dotsToMM(availW), dotsToMM(availH)
+	if report.SizeDots > availW || report.SizeDots > availH {
+		report.Fits = false
+		return report, nil, []ValidationError{issue(1,
+			"QRCODE 版本 %d (%s) 尺寸 %d 點 (%.1f mm) 超出 x/y 到標籤邊緣的剩餘空間 %d×%d 點 (%.1f×%.1f mm)",
+			version, ecc, report.SizeDots, report.SizeMM, max(availW, 0), max(availH, 0),
+			math.Max(report.AvailableWidthMM, 0), math.Max(report.AvailableHeightMM, 0))}
+	}
+	return report, nil, nil
+}
+
+// dotsToMM 以印表機 DPI 將點數換算為毫米 (小數一位)
+func dotsToMM(dots int) float64 {
+	return math.Round(float64(dots)*25.4/float64(parser.DPI)*10) / 10
+}
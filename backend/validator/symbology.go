@@ -0,0 +1,181 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"tspl-simulator/barcode"
+)
+
+// checkDigitLengths EAN/UPC/ITF-14 的完整長度 (含檢查碼), 少一位時由印表機自動補上檢查碼
+var checkDigitLengths = map[string]int{
+	"EAN13": 13,
+	"EAN8":  8,
+	"UPCA":  12,
+	"ITF14": 14,
+}
+
+// symbologyIssue 條碼內容的問題, offset 為資料中的位置 (從 0 開始)
+type symbologyIssue struct {
+	offset  int
+	message string
+	warning bool
+}
+
+// validateSymbology 依條碼類型檢查資料的字元集、長度與檢查碼; 未知的類型只回傳警告
+// 回傳的錯誤與警告皆指向該行資料中的欄位
+func validateSymbology(line string, lineNum, indent int) (errs, warnings []ValidationError) {
+	match := barcodePattern.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, nil
+	}
+	codeType := strings.ToUpper(line[match[6]:match[7]])
+	data := line[match[18]:match[19]]
+
+	var issues []symbologyIssue
+	switch codeType {
+	case "128", "128M":
+		issues = checkCharset("Code 128", data, func(ch byte) bool { return ch >= 32 && ch <= 126 })
+	case "EAN128":
+		// GS1 內容由 validateGS1 檢查
+	case "39", "39S", "39C":
+		issues = checkCode39(codeType, data)
+	case "EAN13", "EAN8", "UPCA", "ITF14":
+		issues = checkMod10(codeType, data, checkDigitLengths[codeType])
+	case "25", "25C":
+		issues = checkITF(codeType, data)
+	case "CODA":
+		issues = checkCodabar(data)
+	default:
+		// 其他類型 (例如 93、UPCE、MSI、EAN13+2) 印表機仍可列印, 只提醒未檢查內容
+		issues = []symbologyIssue{{offset: -1, message: fmt.Sprintf("未檢查 %s 條碼的字元集與檢查碼", codeType), warning: true}}
+	}
+
+	for _, issue := range issues {
+		start := match[18] + issue.offset
+		if issue.offset < 0 {
+			start = match[6]
+		}
+		e := ValidationError{
+			Line:    lineNum,
+			Column:  indent + utf8.RuneCountInString(line[:start]) + 1,
+			Command: "BARCODE",
+			Message: issue.message,
+		}
+		if issue.warning {
+			warnings = append(warnings, e)
+		} else {
+			errs = append(errs, e)
+		}
+	}
+	return errs, warnings
+}
+
+// checkCharset 回報第一個不允許的字元
+func checkCharset(name, data string, allowed func(ch byte) bool) []symbologyIssue {
+	if data == "" {
+		return []symbologyIssue{{message: name + " 資料不可為空"}}
+	}
+	for i := 0; i < len(data); i++ {
+		if !allowed(data[i]) {
+			return []symbologyIssue{{offset: i, message: fmt.Sprintf("%s 不支援的字元: %q (第 %d 個字元)", name, data[i], i+1)}}
+		}
+	}
+	return nil
+}
+
+// checkCode39 檢查 Code 39 字元集 (僅大寫字母、數字與 -. $/+%), 39C 回報自動加入的檢查字元
+func checkCode39(codeType, data string) []symbologyIssue {
+	issues := checkCharset("Code 39", data, func(ch byte) bool {
+		return ch != '*' && strings.IndexByte(barcode.Code39Charset, ch) >= 0
+	})
+	if len(issues) > 0 {
+		if ch := data[issues[0].offset]; ch >= 'a' && ch <= 'z' {
+			issues[0].message += ", Code 39 只支援大寫字母"
+		}
+		return issues
+	}
+	if codeType == "39C" {
+		issues = append(issues, symbologyIssue{
+			offset:  len(data),
+			message: fmt.Sprintf("將自動加入 Mod 43 檢查字元 %q", barcode.Code39CheckChar(data)),
+			warning: true,
+		})
+	}
+	return issues
+}
+
+// checkMod10 檢查 EAN/UPC/ITF-14 的位數與 Mod 10 檢查碼
+func checkMod10(codeType, data string, length int) []symbologyIssue {
+	if issues := checkCharset(codeType, data, isDigit); len(issues) > 0 {
+		issues[0].message = fmt.Sprintf("%s 只能包含數字: %q (第 %d 個字元)", codeType, data[issues[0].offset], issues[0].offset+1)
+		return issues
+	}
+	switch len(data) {
+	case length - 1:
+		return []symbologyIssue{{
+			offset:  len(data),
+			message: fmt.Sprintf("%s 將自動加入檢查碼 %d", codeType, barcode.Mod10CheckDigit(data)),
+			warning: true,
+		}}
+	case length:
+		expected := barcode.Mod10CheckDigit(data[:length-1])
+		if int(data[length-1]-'0') != expected {
+			return []symbologyIssue{{offset: length - 1, message: fmt.Sprintf("%s 檢查碼錯誤: %c 應為 %d", codeType, data[length-1], expected)}}
+		}
+		return nil
+	default:
+		return []symbologyIssue{{message: fmt.Sprintf("%s 需要 %d 或 %d 位數字, 目前為 %d 位", codeType, length-1, length, len(data))}}
+	}
+}
+
+// checkITF 檢查 Interleaved 2 of 5, 回報自動加入的檢查碼與補齊偶數位的前導 0
+func checkITF(codeType, data string) []symbologyIssue {
+	if issues := checkCharset("Interleaved 2 of 5", data, isDigit); len(issues) > 0 {
+		return issues
+	}
+	var issues []symbologyIssue
+	digits := len(data)
+	if codeType == "25C" {
+		issues = append(issues, symbologyIssue{
+			offset:  len(data),
+			message: fmt.Sprintf("將自動加入 Mod 10 檢查碼 %d", barcode.Mod10CheckDigit(data)),
+			warning: true,
+		})
+		digits++
+	}
+	if digits%2 == 1 {
+		issues = append(issues, symbologyIssue{message: "Interleaved 2 of 5 需要偶數位數, 將自動在前方補 0", warning: true})
+	}
+	return issues
+}
+
+// checkCodabar 檢查 Codabar 字元集與起始/終止字元 (A-D)
+func checkCodabar(data string) []symbologyIssue {
+	content := strings.ToUpper(data)
+	isGuard := func(ch byte) bool { return ch >= 'A' && ch <= 'D' }
+	guarded := len(content) >= 2 && isGuard(content[0]) && isGuard(content[len(content)-1])
+	body, offset := content, 0
+	if guarded {
+		body, offset = content[1:len(content)-1], 1
+	}
+	issues := checkCharset("Codabar", body, func(ch byte) bool {
+		_, ok := barcode.CodabarPattern(ch)
+		return ok && !isGuard(ch)
+	})
+	if len(issues) > 0 && body != "" {
+		i := issues[0].offset
+		issues[0].offset += offset
+		issues[0].message = fmt.Sprintf("Codabar 不支援的字元: %q (第 %d 個字元)", body[i], i+offset+1)
+	}
+	if len(issues) == 0 && !guarded {
+		issues = append(issues, symbologyIssue{message: "未指定起始/終止字元, 將自動使用 A", warning: true})
+	}
+	return issues
+}
+
+// isDigit 判斷是否為數字字元
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// barcodeLine 建立 BARCODE 指令行
+func barcodeLine(codeType, data string) string {
+	return fmt.Sprintf(`BARCODE 10,10,"%s",50,1,0,2,2,"%s"`, codeType, data)
+}
+
+func TestValidateSymbology(t *testing.T) {
+	tests := []struct {
+		name     string
+		codeType string
+		data     string
+		err      string // 錯誤應包含的字串, 空字串代表沒有錯誤
+		warning  string // 警告應包含的字串, 空字串代表沒有警告
+	}{
+		{"EAN13 valid", "EAN13", "4006381333931", "", ""},
+		{"EAN13 wrong check digit", "EAN13", "4006381333932", "EAN13 檢查碼錯誤: 2 應為 1", ""},
+		{"EAN13 missing check digit", "EAN13", "400638133393", "", "將自動加入檢查碼 1"},
+		{"EAN13 wrong length", "EAN13", "40063813339", "需要 12 或 13 位數字, 目前為 11 位", ""},
+		{"EAN13 letter", "EAN13", "40063813339A", "只能包含數字", ""},
+		{"UPCA valid", "UPCA", "036000291452", "", ""},
+		{"UPCA wrong check digit", "UPCA", "036000291453", "UPCA 檢查碼錯誤: 3 應為 2", ""},
+		{"UPCA missing check digit", "UPCA", "03600029145", "", "將自動加入檢查碼 2"},
+		{"EAN8 valid", "EAN8", "96385074", "", ""},
+		{"ITF even", "25", "123456", "", ""},
+		{"ITF odd length", "25", "12345", "", "需要偶數位數"},
+		{"ITF check digit makes even", "25C", "12345", "", "將自動加入 Mod 10 檢查碼"},
+		{"ITF letter", "25", "12A4", "Interleaved 2 of 5 不支援的字元", ""},
+		{"Code 39 valid", "39", "ABC-123", "", ""},
+		{"39C lowercase", "39C", "abc", "Code 39 只支援大寫字母", ""},
+		{"39C check character", "39C", "ABC", "", "Mod 43 檢查字元"},
+		{"Code 39 asterisk", "39", "A*B", "Code 39 不支援的字元", ""},
+		{"Code 128 valid", "128", "Hello 128", "", ""},
+		{"Codabar with start/stop", "CODA", "A123456B", "", ""},
+		{"Codabar lowercase start/stop", "CODA", "a123456d", "", ""},
+		{"Codabar without start/stop", "CODA", "123456", "", "將自動使用 A"},
+		{"Codabar bad character", "CODA", "A12X4B", "Codabar 不支援的字元: 'X' (第 4 個字元)", ""},
+		{"Codabar guard inside", "CODA", "12A4", "Codabar 不支援的字元", ""},
+		{"unknown symbology passes", "93", "anything", "", "未檢查 93 條碼"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, warnings := validateSymbology(barcodeLine(tt.codeType, tt.data), 1, 0)
+			check := func(kind string, got []ValidationError, want string) {
+				if want == "" {
+					for _, e := range got {
+						t.Errorf("不應有%s: %s", kind, e.Message)
+					}
+					return
+				}
+				for _, e := range got {
+					if strings.Contains(e.Message, want) {
+						return
+					}
+				}
+				t.Errorf("%s = %v, want 包含 %q", kind, got, want)
+			}
+			check("錯誤", errs, tt.err)
+			check("警告", warnings, tt.warning)
+		})
+	}
+}
+
+func TestValidateSymbologyColumn(t *testing.T) {
+	line := barcodeLine("EAN13", "4006381333932")
+	errs, _ := validateSymbology(line, 3, 2)
+	if len(errs) != 1 {
+		t.Fatalf("errs = %v, want 1 個錯誤", errs)
+	}
+	// 錯誤指向資料中最後一位的檢查碼
+	want := 2 + strings.Index(line, "4006381333932") + 12 + 1
+	if errs[0].Line != 3 || errs[0].Column != want || errs[0].Command != "BARCODE" {
+		t.Errorf("錯誤位置 = 第 %d 行第 %d 欄 (%s), want 第 3 行第 %d 欄", errs[0].Line, errs[0].Column, errs[0].Command, want)
+	}
+}
+
+func TestValidateTSPLUnknownSymbology(t *testing.T) {
+	code := "SIZE 50 mm, 30 mm\nCLS\n" + barcodeLine("93", "ABC") + "\nPRINT 1\n"
+	result := ValidateTSPL(code)
+	if !result.Valid {
+		t.Fatalf("未知的條碼類型不應驗證失敗: %v", result.Errors)
+	}
+	if len(result.Warnings) == 0 {
+		t.Error("未知的條碼類型應有警告")
+	}
+}
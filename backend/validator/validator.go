@@ -5,19 +5,37 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+
+	"tspl-simulator/models"
+	"tspl-simulator/parser"
+)
+
+// 標籤與媒體長度的上限 (毫米), 避免繪製時配置過大的點陣
+const (
+	maxLabelWidth  = 216  // 8.5 英吋, 寬幅打印機的最大列印寬度
+	maxLabelHeight = 1000 // 單張標籤的最大長度
+	maxGapLength   = 100  // GAP/BLINE 的間距、黑標與偏移
 )
 
+// maxShapeDots 圖形命令座標與尺寸的上限 (點), 約為最大標籤長度
+const maxShapeDots = 10000
+
 // ValidationError 驗證錯誤
 type ValidationError struct {
 	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"` // 錯誤在該行的位置 (從 1 開始), 0 代表整行
 	Command string `json:"command"`
+	AI      string `json:"ai,omitempty"` // GS1 應用識別碼 (僅 GS1 資料錯誤)
 	Message string `json:"message"`
 }
 
 // ValidationResult 驗證結果
 type ValidationResult struct {
-	Valid  bool               `json:"valid"`
-	Errors []ValidationError  `json:"errors,omitempty"`
+	Valid      bool                `json:"valid"`
+	Errors     []ValidationError   `json:"errors,omitempty"`
+	Warnings   []ValidationError   `json:"warnings,omitempty"`    // 不影響 Valid 的提醒 (例如自動加入的檢查碼)
+	QRCapacity []models.QRCapacity `json:"qr_capacity,omitempty"` // 每個 QRCODE 所需的版本與尺寸
 }
 
 // ValidateTSPL 驗證 TSPL2 語法
@@ -27,11 +45,16 @@ func ValidateTSPL(tsplCode string) *ValidationResult {
 		Errors: []ValidationError{},
 	}
 
+	// DOWNLOAD 的二進位資料不參與語法檢查
+	tsplCode, _ = parser.ExtractDownloads(tsplCode)
+
 	lines := strings.Split(tsplCode, "\n")
 	hasSize := false
 	hasPrint := false
+	var area labelArea
 
 	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
 		line = strings.TrimSpace(line)
 		lineNum := i + 1
 
@@ -46,6 +69,7 @@ func ValidateTSPL(tsplCode string) *ValidationResult {
 		}
 
 		command := strings.ToUpper(parts[0])
+		args := parser.SplitArgs(line)
 
 		// 檢查命令是否有效
 		if !isValidCommand(command) {
@@ -62,28 +86,42 @@ func ValidateTSPL(tsplCode string) *ValidationResult {
 		switch command {
 		case "SIZE":
 			hasSize = true
-			if err := validateSize(parts[1:], lineNum); err != nil {
+			if err := validateSize(args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
+			area.setSize(args)
 
 		case "GAP":
-			if err := validateGap(parts[1:], lineNum); err != nil {
+			if err := validateGap(args, lineNum); err != nil {
+				result.Valid = false
+				result.Errors = append(result.Errors, *err)
+			}
+
+		case "BLINE":
+			if err := validateBline(args, lineNum); err != nil {
+				result.Valid = false
+				result.Errors = append(result.Errors, *err)
+			}
+
+		case "BACKFEED", "LIMITFEED":
+			if err := validateFeed(command, args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
 
 		case "DIRECTION":
-			if err := validateDirection(parts[1:], lineNum); err != nil {
+			if err := validateDirection(args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
 
 		case "REFERENCE":
-			if err := validateReference(parts[1:], lineNum); err != nil {
+			if err := validateReference(args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
+			area.setReference(args)
 
 		case "TEXT":
 			if err := validateText(line, lineNum); err != nil {
@@ -95,41 +133,84 @@ func ValidateTSPL(tsplCode string) *ValidationResult {
 			if err := validateBarcode(line, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
+			} else {
+				errs, warnings := validateSymbology(line, lineNum, indent)
+				errs = append(errs, validateGS1(command, line, lineNum, indent)...)
+				if len(errs) > 0 {
+					result.Valid = false
+					result.Errors = append(result.Errors, errs...)
+				}
+				result.Warnings = append(result.Warnings, warnings...)
 			}
 
 		case "QRCODE":
 			if err := validateQRCode(line, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
+			} else {
+				report, errs, warnings := validateQRCapacity(line, lineNum, indent, area)
+				errs = append(errs, validateGS1(command, line, lineNum, indent)...)
+				if len(errs) > 0 {
+					result.Valid = false
+					result.Errors = append(result.Errors, errs...)
+				}
+				result.Warnings = append(result.Warnings, warnings...)
+				if report != nil {
+					result.QRCapacity = append(result.QRCapacity, *report)
+				}
 			}
 
 		case "BOX":
-			if err := validateBox(parts[1:], lineNum); err != nil {
+			if err := validateBox(args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
 
 		case "BAR":
-			if err := validateBar(parts[1:], lineNum); err != nil {
+			if err := validateBar(args, lineNum); err != nil {
+				result.Valid = false
+				result.Errors = append(result.Errors, *err)
+			}
+
+		case "CIRCLE", "ELLIPSE", "DIAGONAL", "TRIANGLE":
+			if err := validateShape(command, args, lineNum); err != nil {
+				result.Valid = false
+				result.Errors = append(result.Errors, *err)
+			}
+
+		case "PUTBMP", "PUTPCX":
+			if err := validatePutImage(command, args, lineNum); err != nil {
+				result.Valid = false
+				result.Errors = append(result.Errors, *err)
+			}
+
+		case "DOWNLOAD":
+			if err := validateDownload(args, lineNum); err != nil {
+				result.Valid = false
+				result.Errors = append(result.Errors, *err)
+			}
+
+		case "REVERSE", "ERASE":
+			if err := validateRegion(command, args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
 
 		case "PRINT":
 			hasPrint = true
-			if err := validatePrint(parts[1:], lineNum); err != nil {
+			if err := validatePrint(args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
 
 		case "DENSITY":
-			if err := validateDensity(parts[1:], lineNum); err != nil {
+			if err := validateDensity(args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
 
 		case "SPEED":
-			if err := validateSpeed(parts[1:], lineNum); err != nil {
+			if err := validateSpeed(args, lineNum); err != nil {
 				result.Valid = false
 				result.Errors = append(result.Errors, *err)
 			}
@@ -169,6 +250,8 @@ func isValidCommand(command string) bool {
 		"HOME": true, "SOUND": true, "LIMITFEED": true, "SELFTEST": true,
 		"EOP": true, "BLOCK": true, "CODEPAGE": true, "COUNTRY": true,
 		"PUTBMP": true, "PUTPCX": true, "DOWNLOAD": true, "ERASE": true,
+		"BLINE": true, "CIRCLE": true, "ELLIPSE": true, "DIAGONAL": true,
+		"TRIANGLE": true,
 	}
 	return validCommands[command]
 }
@@ -184,21 +267,13 @@ func validateSize(parts []string, lineNum int) *ValidationError {
 	}
 
 	// 檢查寬度
-	if _, _, err := parseValueWithUnit(parts[0]); err != nil {
-		return &ValidationError{
-			Line:    lineNum,
-			Command: "SIZE",
-			Message: fmt.Sprintf("寬度格式錯誤: %v", err),
-		}
+	if err := validateLength(parts[0], maxLabelWidth, "SIZE", "寬度", lineNum); err != nil {
+		return err
 	}
 
 	// 檢查高度
-	if _, _, err := parseValueWithUnit(parts[1]); err != nil {
-		return &ValidationError{
-			Line:    lineNum,
-			Command: "SIZE",
-			Message: fmt.Sprintf("高度格式錯誤: %v", err),
-		}
+	if err := validateLength(parts[1], maxLabelHeight, "SIZE", "高度", lineNum); err != nil {
+		return err
 	}
 
 	return nil
@@ -215,20 +290,90 @@ func validateGap(parts []string, lineNum int) *ValidationError {
 	}
 
 	// 檢查間距
-	if _, _, err := parseValueWithUnit(parts[0]); err != nil {
+	if err := validateLength(parts[0], maxGapLength, "GAP", "間距", lineNum); err != nil {
+		return err
+	}
+
+	// 檢查偏移
+	if err := validateLength(parts[1], maxGapLength, "GAP", "偏移", lineNum); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateLength 驗證帶單位的長度格式, 並限制不超過 maxMM 毫米
+func validateLength(value string, maxMM float64, command, name string, lineNum int) *ValidationError {
+	v, unit, err := parseValueWithUnit(value)
+	if err != nil {
 		return &ValidationError{
 			Line:    lineNum,
-			Command: "GAP",
-			Message: fmt.Sprintf("間距格式錯誤: %v", err),
+			Command: command,
+			Message: fmt.Sprintf("%s格式錯誤: %v", name, err),
 		}
 	}
+	if unit == "inch" {
+		v *= 25.4
+	}
+	if v > maxMM {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: fmt.Sprintf("%s超過上限 %g mm", name, maxMM),
+		}
+	}
+	return nil
+}
 
-	// 檢查偏移
-	if _, _, err := parseValueWithUnit(parts[1]); err != nil {
+// validateBline 驗證 BLINE 命令
+func validateBline(parts []string, lineNum int) *ValidationError {
+	if len(parts) < 2 {
 		return &ValidationError{
 			Line:    lineNum,
-			Command: "GAP",
-			Message: fmt.Sprintf("偏移格式錯誤: %v", err),
+			Command: "BLINE",
+			Message: "BLINE 命令需要 2 個參數 (黑標高度, 額外進紙長度)",
+		}
+	}
+
+	// 檢查黑標高度
+	if err := validateLength(parts[0], maxGapLength, "BLINE", "黑標高度", lineNum); err != nil {
+		return err
+	}
+
+	// 檢查額外進紙長度
+	if err := validateLength(parts[1], maxGapLength, "BLINE", "額外進紙長度", lineNum); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateFeed 驗證 BACKFEED (點數) 與 LIMITFEED (長度) 命令
+func validateFeed(command string, parts []string, lineNum int) *ValidationError {
+	if len(parts) < 1 {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: fmt.Sprintf("%s 命令需要 1 個參數", command),
+		}
+	}
+
+	if command == "BACKFEED" {
+		if n, err := strconv.Atoi(parts[0]); err != nil || n < 0 {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: "退紙點數必須是非負整數",
+			}
+		}
+		return nil
+	}
+
+	if _, _, err := parseValueWithUnit(parts[0]); err != nil {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: fmt.Sprintf("進紙上限格式錯誤: %v", err),
 		}
 	}
 
@@ -309,10 +454,14 @@ func validateText(line string, lineNum int) *ValidationError {
 	return nil
 }
 
+var (
+	barcodePattern = regexp.MustCompile(`BARCODE\s+(\d+),(\d+),"([^"]+)",(\d+),(\d+),(\d+),(\d+),(\d+),"([^"]*)"`)
+	qrcodePattern  = regexp.MustCompile(`QRCODE\s+(\d+),(\d+),([LMQH]),(\d+),([AM]),(\d+)(?:,(\d+),(\d+),(\d+))?,(?:"([^"]*)")`)
+)
+
 // validateBarcode 驗證 BARCODE 命令
 func validateBarcode(line string, lineNum int) *ValidationError {
-	re := regexp.MustCompile(`BARCODE\s+(\d+),(\d+),"([^"]+)",(\d+),(\d+),(\d+),(\d+),(\d+),"([^"]*)"`)
-	if !re.MatchString(line) {
+	if !barcodePattern.MatchString(line) {
 		return &ValidationError{
 			Line:    lineNum,
 			Command: "BARCODE",
@@ -324,8 +473,7 @@ func validateBarcode(line string, lineNum int) *ValidationError {
 
 // validateQRCode 驗證 QRCODE 命令
 func validateQRCode(line string, lineNum int) *ValidationError {
-	re := regexp.MustCompile(`QRCODE\s+(\d+),(\d+),([HML]),(\d+),([AM]),(\d+)(?:,(\d+),(\d+),(\d+))?,(?:"([^"]*)")`)
-	if !re.MatchString(line) {
+	if !qrcodePattern.MatchString(line) {
 		return &ValidationError{
 			Line:    lineNum,
 			Command: "QRCODE",
@@ -337,16 +485,16 @@ func validateQRCode(line string, lineNum int) *ValidationError {
 
 // validateBox 驗證 BOX 命令
 func validateBox(parts []string, lineNum int) *ValidationError {
-	if len(parts) < 5 {
+	if len(parts) < 5 || len(parts) > 6 {
 		return &ValidationError{
 			Line:    lineNum,
 			Command: "BOX",
-			Message: "BOX 命令需要 5 個參數 (x, y, x_end, y_end, thickness)",
+			Message: "BOX 命令需要 5 或 6 個參數 (x, y, x_end, y_end, thickness[, radius])",
 		}
 	}
 
 	// 驗證所有參數都是數字
-	for i, param := range parts[:5] {
+	for i, param := range parts {
 		if _, err := strconv.Atoi(strings.TrimSuffix(param, ",")); err != nil {
 			return &ValidationError{
 				Line:    lineNum,
@@ -356,6 +504,91 @@ func validateBox(parts []string, lineNum int) *ValidationError {
 		}
 	}
 
+	// 驗證圓角半徑
+	if len(parts) == 6 {
+		radius, _ := strconv.Atoi(parts[5])
+		if radius < 0 {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: "BOX",
+				Message: "圓角半徑不可為負數",
+			}
+		}
+	}
+
+	return nil
+}
+
+// shapeUsage 各圖形命令的參數說明
+var shapeUsage = map[string]string{
+	"CIRCLE":   "x, y, diameter, thickness",
+	"ELLIPSE":  "x, y, width, height, thickness",
+	"DIAGONAL": "x1, y1, x2, y2, thickness",
+	"TRIANGLE": "x1, y1, x2, y2, x3, y3, thickness",
+}
+
+// validateShape 驗證 CIRCLE、ELLIPSE、DIAGONAL 與 TRIANGLE 命令
+func validateShape(command string, parts []string, lineNum int) *ValidationError {
+	usage := shapeUsage[command]
+	count := len(strings.Split(usage, ","))
+	if len(parts) != count {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: fmt.Sprintf("%s 命令需要 %d 個參數 (%s)", command, count, usage),
+		}
+	}
+
+	// 驗證所有參數都是數字, 且座標與尺寸不超過上限
+	for i, param := range parts {
+		v, err := strconv.Atoi(param)
+		if err != nil {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: fmt.Sprintf("第 %d 個參數必須是數字", i+1),
+			}
+		}
+		if v < -maxShapeDots || v > maxShapeDots {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: fmt.Sprintf("第 %d 個參數超出範圍 (±%d 點)", i+1, maxShapeDots),
+			}
+		}
+	}
+
+	// 驗證線寬
+	if thickness, _ := strconv.Atoi(parts[count-1]); thickness < 1 {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: "線寬必須至少為 1 點",
+		}
+	}
+
+	// 驗證尺寸
+	switch command {
+	case "CIRCLE":
+		if d, _ := strconv.Atoi(parts[2]); d < 1 {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: "直徑必須大於 0",
+			}
+		}
+	case "ELLIPSE":
+		w, _ := strconv.Atoi(parts[2])
+		h, _ := strconv.Atoi(parts[3])
+		if w < 1 || h < 1 {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: "寬度與高度必須大於 0",
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -383,6 +616,121 @@ func validateBar(parts []string, lineNum int) *ValidationError {
 	return nil
 }
 
+// validatePutImage 驗證 PUTBMP 與 PUTPCX 命令
+func validatePutImage(command string, parts []string, lineNum int) *ValidationError {
+	if len(parts) < 3 || len(parts) > 5 {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: fmt.Sprintf("%s 命令格式錯誤。正確格式: %s x,y,\"filename\"[,bpp,contrast]", command, command),
+		}
+	}
+
+	// 檢查座標
+	for i, param := range parts[:2] {
+		if _, err := strconv.Atoi(param); err != nil {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: fmt.Sprintf("第 %d 個參數必須是數字", i+1),
+			}
+		}
+	}
+
+	// 檢查檔名
+	if len(parts[2]) < 3 || !strings.HasPrefix(parts[2], `"`) || !strings.HasSuffix(parts[2], `"`) {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: "檔名必須以雙引號包住",
+		}
+	}
+
+	// 檢查色彩深度
+	if len(parts) >= 4 {
+		if bpp, err := strconv.Atoi(parts[3]); err != nil || (bpp != 1 && bpp != 8) {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: "bpp 必須是 1 或 8",
+			}
+		}
+	}
+
+	// 檢查對比
+	if len(parts) == 5 {
+		if contrast, err := strconv.Atoi(parts[4]); err != nil || contrast < 0 || contrast > 100 {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: "對比必須在 0-100 之間",
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateDownload 驗證 DOWNLOAD 命令標頭
+func validateDownload(parts []string, lineNum int) *ValidationError {
+	if len(parts) > 0 && (strings.EqualFold(parts[0], "F") || strings.EqualFold(parts[0], "E")) {
+		parts = parts[1:]
+	}
+
+	if len(parts) < 1 || len(parts[0]) < 3 || !strings.HasPrefix(parts[0], `"`) || !strings.HasSuffix(parts[0], `"`) {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: "DOWNLOAD",
+			Message: "DOWNLOAD 命令格式錯誤。正確格式: DOWNLOAD [n,]\"filename\"[,size,data]",
+		}
+	}
+
+	// 檢查資料大小
+	if len(parts) >= 2 {
+		size, err := strconv.Atoi(parts[1])
+		if err != nil || size < 0 {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: "DOWNLOAD",
+				Message: "資料大小必須是非負整數",
+			}
+		}
+		if size > parser.MaxDownloadSize {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: "DOWNLOAD",
+				Message: fmt.Sprintf("資料大小超過上限 %d 位元組", parser.MaxDownloadSize),
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateRegion 驗證 REVERSE 與 ERASE 命令
+func validateRegion(command string, parts []string, lineNum int) *ValidationError {
+	if len(parts) < 4 {
+		return &ValidationError{
+			Line:    lineNum,
+			Command: command,
+			Message: fmt.Sprintf("%s 命令需要 4 個參數 (x, y, width, height)", command),
+		}
+	}
+
+	// 驗證所有參數都是非負整數
+	for i, param := range parts[:4] {
+		if n, err := strconv.Atoi(param); err != nil || n < 0 {
+			return &ValidationError{
+				Line:    lineNum,
+				Command: command,
+				Message: fmt.Sprintf("第 %d 個參數必須是非負整數", i+1),
+			}
+		}
+	}
+
+	return nil
+}
+
 // validatePrint 驗證 PRINT 命令
 func validatePrint(parts []string, lineNum int) *ValidationError {
 	if len(parts) > 2 {
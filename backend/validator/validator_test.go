@@ -0,0 +1,21 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTSPLRejectsUnsupportedControlFlow(t *testing.T) {
+	// 解析器不支援副程式與跳躍, 驗證器不可讓這些命令通過
+	for _, line := range []string{"SUB HEADER", "ENDSUB", "CALL HEADER", "GOSUB L1", "GOTO L1", "RETURN", ":L1"} {
+		t.Run(line, func(t *testing.T) {
+			result := ValidateTSPL("SIZE 50 mm, 30 mm\nCLS\n" + line + "\nPRINT 1\n")
+			if result.Valid {
+				t.Fatalf("%s 不應通過驗證", line)
+			}
+			if len(result.Errors) != 1 || result.Errors[0].Line != 3 || !strings.Contains(result.Errors[0].Message, "未知") {
+				t.Errorf("Errors = %v, want 第 3 行的未知命令錯誤", result.Errors)
+			}
+		})
+	}
+}
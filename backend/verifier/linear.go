@@ -0,0 +1,313 @@
+package verifier
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"tspl-simulator/barcode"
+	"tspl-simulator/gs1"
+	"tspl-simulator/models"
+	"tspl-simulator/renderer"
+)
+
+// scanLines ISO/IEC 15416 建議的掃描線數
+const scanLines = 10
+
+// quietZones 各條碼類型要求的左右靜區 (模組數), 未列出者為 10
+var quietZones = map[string][2]int{
+	"EAN13": {11, 7},
+	"UPCA":  {9, 9},
+	"EAN8":  {7, 7},
+}
+
+// eanMinX GS1 規範 EAN/UPC 最小模組寬度 (80% 倍率, mm)
+const eanMinX = 0.264
+
+// scan 單一掃描線的量測
+type scan struct {
+	runs  []int // 條空寬度 (點), 以條開始與結束
+	left  int   // 左側靜區 (點)
+	right int   // 右側靜區 (點)
+}
+
+// readScan 沿元素座標 v 由左至右取樣, 擷取從 u≈0 開始的條碼條空序列
+// 超出標籤範圍視為靜區中斷; 空白寬度達 gapLimit 時視為條碼結束
+func readScan(sample func(u, v int) (bool, bool), v, lo, hi, start, gapLimit int) (scan, bool) {
+	dark := func(u int) bool {
+		d, inside := sample(u, v)
+		return d && inside
+	}
+	light := func(u int) bool {
+		d, inside := sample(u, v)
+		return !d && inside
+	}
+
+	u := start
+	if dark(u) {
+		for u > lo && dark(u-1) {
+			u--
+		}
+	} else {
+		for u < hi && !dark(u) {
+			u++
+		}
+		if u >= hi {
+			return scan{}, false
+		}
+	}
+
+	var s scan
+	for q := u - 1; q >= lo && light(q); q-- {
+		s.left++
+	}
+
+	last := u
+	for u < hi {
+		end := u
+		for end < hi && dark(end) {
+			end++
+		}
+		s.runs = append(s.runs, end-u)
+		last = end
+		space := end
+		for space < hi && light(space) && space-end < gapLimit {
+			space++
+		}
+		if space >= hi || space-end >= gapLimit || !dark(space) {
+			break
+		}
+		s.runs = append(s.runs, space-end)
+		u = space
+	}
+	for q := last; q < hi && light(q); q++ {
+		s.right++
+	}
+	return s, true
+}
+
+// verifyBarcode 以多條掃描線解碼一維條碼並估計等級
+func verifyBarcode(data *models.RenderData, el models.Element, canvas *renderer.Canvas) models.ScanResult {
+	props := el.Properties
+	codeType := strings.ToUpper(renderer.StringProp(props, "type"))
+	code := renderer.StringProp(props, "code")
+	result := models.ScanResult{Type: "barcode", Symbology: codeType, Expected: code}
+
+	pattern, err := barcode.Encode(codeType, code)
+	if err != nil {
+		return failed(result, err.Error())
+	}
+
+	narrow, wide := renderer.IntProp(props, "narrow"), renderer.IntProp(props, "wide")
+	if narrow < 1 {
+		narrow = 1
+	}
+	if wide < narrow {
+		wide = narrow
+	}
+	nominal := pattern.ModuleWidths(narrow, wide)
+	total := pattern.TotalWidth(narrow, wide)
+	height := renderer.IntProp(props, "height")
+
+	result.XDots = float64(narrow)
+	result.XDimension = dotsToMM(float64(narrow), data.DPI)
+	if (codeType == "EAN13" || codeType == "EAN8" || codeType == "UPCA") && result.XDimension < eanMinX {
+		result.Messages = append(result.Messages,
+			fmt.Sprintf("模組寬度 %.3f mm 小於 GS1 規範最小值 %.3f mm", result.XDimension, eanMinX))
+	}
+
+	required, ok := quietZones[codeType]
+	if !ok {
+		required = [2]int{10, 10}
+	}
+	margin := 2*max(required[0], required[1])*narrow + narrow
+	gapLimit := max(5*narrow, 2*wide)
+
+	point := renderer.ElementPoint(data, el)
+	sample := func(u, v int) (bool, bool) {
+		pt := point(u, v)
+		inside := pt.X >= 0 && pt.Y >= 0 && pt.X < canvas.Width && pt.Y < canvas.Height
+		return canvas.Get(pt.X, pt.Y), inside
+	}
+
+	lines := scanLines
+	if height < lines {
+		lines = height
+	}
+	if lines < 1 {
+		return failed(result, "條碼高度為 0, 無法掃描")
+	}
+	result.Scans = lines
+
+	var (
+		decodeSum, quietSum, decodabilitySum, ratioSum float64
+		scoreSum, gainSum, ratioTotal                  float64
+		gainCount, ratioCount, decodedCount            int
+		minLeft, minRight                              = math.MaxFloat64, math.MaxFloat64
+		minDecodability                                = 1.0
+		lastError                                      string
+	)
+	for i := 0; i < lines; i++ {
+		v := height * (2*i + 1) / (2 * lines)
+		s, found := readScan(sample, v, -margin, total+margin, -2*narrow, gapLimit)
+		if !found {
+			lastError = "掃描線上找不到條碼"
+			continue
+		}
+
+		left := float64(s.left) / float64(narrow)
+		right := float64(s.right) / float64(narrow)
+		minLeft = math.Min(minLeft, left)
+		minRight = math.Min(minRight, right)
+		quiet := passGrade(left >= float64(required[0]) && right >= float64(required[1]))
+		quietSum += quiet
+
+		decoded, err := barcode.DecodeRuns(codeType, s.runs)
+		if err != nil {
+			lastError = err.Error()
+			continue
+		}
+		if result.Decoded == "" {
+			result.Decoded = decoded.Text
+		}
+		if !matches(codeType, code, pattern, decoded) {
+			lastError = fmt.Sprintf("解碼結果 %q 與條碼內容不符", decoded.Text)
+			continue
+		}
+		decodedCount++
+		decodeSum += 4
+
+		decodability, ratio := measure(s.runs, decoded)
+		minDecodability = math.Min(minDecodability, decodability)
+		decodabilitySum += marginGrade(decodability)
+		scan := math.Min(quiet, marginGrade(decodability))
+		if decoded.TwoWidth {
+			ratioTotal += ratio
+			ratioCount++
+			ratioOK := ratio >= 2.0 && ratio <= 3.0
+			ratioSum += passGrade(ratioOK)
+			scan = math.Min(scan, passGrade(ratioOK))
+		}
+		scoreSum += scan
+
+		if len(s.runs) == len(nominal) {
+			for j := 0; j < len(nominal); j += 2 {
+				gainSum += float64(s.runs[j] - nominal[j])
+				gainCount++
+			}
+		}
+	}
+
+	result.ScansPassed = decodedCount
+	result.Match = decodedCount > 0
+	if minLeft != math.MaxFloat64 {
+		result.QuietZoneLeft = round(minLeft, 1)
+		result.QuietZoneRight = round(minRight, 1)
+	}
+	if decodedCount == 0 {
+		result.Grade = "F"
+		result.Grades = map[string]string{"decode": "F"}
+		if lastError != "" {
+			result.Messages = append(result.Messages, lastError)
+		}
+		return result
+	}
+
+	n := float64(lines)
+	result.Decodability = round(minDecodability, 2)
+	if gainCount > 0 {
+		result.BarGain = dotsToMM(gainSum/float64(gainCount), data.DPI)
+	}
+	result.Score = round(scoreSum/n, 2)
+	result.Grade = gradeLetter(result.Score)
+	result.Grades = map[string]string{
+		"decode":       gradeLetter(decodeSum / n),
+		"quietZone":    gradeLetter(quietSum / n),
+		"decodability": gradeLetter(decodabilitySum / n),
+	}
+	if ratioCount > 0 {
+		result.WideNarrowRatio = round(ratioTotal/float64(ratioCount), 2)
+		result.Grades["wideNarrowRatio"] = gradeLetter(ratioSum / n)
+		if result.WideNarrowRatio < 2.0 || result.WideNarrowRatio > 3.0 {
+			result.Messages = append(result.Messages,
+				fmt.Sprintf("寬窄比 %.2f 超出建議範圍 2.0-3.0", result.WideNarrowRatio))
+		}
+	}
+	if result.Grades["quietZone"] != "A" {
+		result.Messages = append(result.Messages, fmt.Sprintf("靜區不足: 左 %.1f / 右 %.1f 模組, 需要 %d / %d 模組",
+			result.QuietZoneLeft, result.QuietZoneRight, required[0], required[1]))
+	}
+	if decodedCount < lines && lastError != "" {
+		result.Messages = append(result.Messages,
+			fmt.Sprintf("%d/%d 條掃描線解碼失敗: %s", lines-decodedCount, lines, lastError))
+	}
+	return result
+}
+
+// matches 比對解碼結果與元素的 code 屬性 (GS1-128 比對移除括號並插入 FNC1 分隔符後的資料)
+func matches(codeType, code string, pattern *barcode.Pattern, decoded *barcode.Decoded) bool {
+	switch codeType {
+	case "EAN128":
+		return decoded.GS1 && decoded.Text == gs1.Content(code)
+	case "CODA":
+		want := strings.ToUpper(code)
+		text := decoded.Text
+		return text == want || (len(text) > 2 && text[1:len(text)-1] == want)
+	default:
+		return decoded.Text == pattern.Text
+	}
+}
+
+// measure 計算可解碼度與寬窄比
+//   - 單一模組寬度的條碼: 以相鄰條空和 (edge-to-similar-edge) 與名義值的最大偏差換算, 偏差半個模組為 0
+//   - 兩種寬度的條碼: 以各元素與寬窄分界的最小距離相對於寬窄差的一半換算
+func measure(runs []int, decoded *barcode.Decoded) (float64, float64) {
+	if decoded.TwoWidth {
+		var narrowSum, wideSum float64
+		var narrowN, wideN int
+		for i, m := range decoded.Modules {
+			if m == 2 {
+				wideSum += float64(runs[i])
+				wideN++
+			} else {
+				narrowSum += float64(runs[i])
+				narrowN++
+			}
+		}
+		if narrowN == 0 || wideN == 0 || narrowSum == 0 {
+			return 0, 0
+		}
+		narrowMean, wideMean := narrowSum/float64(narrowN), wideSum/float64(wideN)
+		threshold := (narrowMean + wideMean) / 2
+		half := (wideMean - narrowMean) / 2
+		if half <= 0 {
+			return 0, wideMean / narrowMean
+		}
+		v := 1.0
+		for i, m := range decoded.Modules {
+			dist := float64(runs[i]) - threshold
+			if m == 1 {
+				dist = -dist
+			}
+			v = math.Min(v, dist/half)
+		}
+		return math.Max(0, v), wideMean / narrowMean
+	}
+
+	totalDots, totalModules := 0, 0
+	for i, m := range decoded.Modules {
+		totalDots += runs[i]
+		totalModules += m
+	}
+	if totalModules == 0 {
+		return 0, 0
+	}
+	x := float64(totalDots) / float64(totalModules)
+	worst := 0.0
+	for i := 0; i+1 < len(runs); i++ {
+		measured := float64(runs[i] + runs[i+1])
+		expected := float64(decoded.Modules[i]+decoded.Modules[i+1]) * x
+		worst = math.Max(worst, math.Abs(measured-expected)/x)
+	}
+	return math.Max(0, 1-worst/0.5), 0
+}
@@ -0,0 +1,228 @@
+package verifier
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"tspl-simulator/models"
+	"tspl-simulator/qrcode"
+	"tspl-simulator/renderer"
+)
+
+// qrQuietZone ISO/IEC 18004 要求的四周靜區 (模組數)
+const qrQuietZone = 4
+
+// verifyQRCode 在元素所在區域尋找 QR Code, 判斷方向與版本後取樣解碼並估計等級
+func verifyQRCode(data *models.RenderData, el models.Element, canvas *renderer.Canvas) models.ScanResult {
+	props := el.Properties
+	text := renderer.StringProp(props, "data")
+	result := models.ScanResult{Type: "qrcode", Symbology: "QR", Expected: text}
+
+	expected, err := qrcode.Encode(text, qrcode.ParseECC(renderer.StringProp(props, "eccLevel")))
+	if err != nil {
+		return failed(result, err.Error())
+	}
+	cell := renderer.IntProp(props, "cellSize")
+	if cell < 1 {
+		cell = 1
+	}
+
+	// 搜尋範圍: 元素預期位置外擴一個模組 (容許列印暈開)
+	point := renderer.ElementPoint(data, el)
+	span := expected.Size*cell - 1
+	a, b := point(0, 0), point(span, span)
+	region := image.Rect(a.X, a.Y, b.X, b.Y).Canon()
+	region.Max = region.Max.Add(image.Pt(1, 1))
+	region = region.Inset(-cell).Intersect(image.Rect(0, 0, canvas.Width, canvas.Height))
+
+	bbox := darkBounds(canvas, region)
+	if bbox.Empty() {
+		return failed(result, "元素位置找不到 QR Code")
+	}
+
+	grid, fixed := locate(canvas, bbox)
+	if grid == nil {
+		return failed(result, "找不到 QR Code 定位圖形")
+	}
+	size := len(grid)
+	module := float64(bbox.Dx()) / float64(size)
+	result.XDots = round(module, 2)
+	result.XDimension = dotsToMM(module, data.DPI)
+	result.QuietZone = round(quietZone(canvas, bbox, module), 1)
+
+	decoded, err := qrcode.Decode(grid)
+	if err != nil {
+		result.Grades = map[string]string{"decode": "F"}
+		return failed(result, "QR Code 解碼失敗: "+err.Error())
+	}
+	result.Decoded = decoded.Data
+	result.Match = decoded.Data == text
+	result.Version = decoded.Version
+	result.ECC = decoded.ECC.String()
+	result.Corrected = decoded.Corrected
+	result.UnusedECC = round(decoded.UnusedECC, 2)
+
+	grades := map[string]float64{
+		"decode":                passGrade(result.Match),
+		"quietZone":             passGrade(result.QuietZone >= qrQuietZone),
+		"unusedErrorCorrection": marginGrade(decoded.UnusedECC),
+		"fixedPatternDamage":    fixedPatternGrade(fixed),
+	}
+	score := 4.0
+	result.Grades = map[string]string{}
+	for name, g := range grades {
+		result.Grades[name] = gradeLetter(g)
+		score = math.Min(score, g)
+	}
+	result.Score = score
+	result.Grade = gradeLetter(score)
+
+	if !result.Match {
+		result.Messages = append(result.Messages, fmt.Sprintf("解碼結果 %q 與 QR Code 內容不符", decoded.Data))
+	}
+	if result.QuietZone < qrQuietZone {
+		result.Messages = append(result.Messages,
+			fmt.Sprintf("靜區不足: %.1f 模組, 需要 %d 模組", result.QuietZone, qrQuietZone))
+	}
+	if decoded.Corrected > 0 {
+		result.Messages = append(result.Messages, fmt.Sprintf("以糾錯修正了 %d 個碼字", decoded.Corrected))
+	}
+	return result
+}
+
+// darkBounds 取得區域內黑點的外框
+func darkBounds(canvas *renderer.Canvas, region image.Rectangle) image.Rectangle {
+	bounds := image.Rectangle{}
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			if canvas.Get(x, y) {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
+
+// sampleGrid 在外框內以模組中心取樣 n×n 矩陣
+func sampleGrid(canvas *renderer.Canvas, bbox image.Rectangle, n int) [][]bool {
+	grid := make([][]bool, n)
+	for y := 0; y < n; y++ {
+		grid[y] = make([]bool, n)
+		py := bbox.Min.Y + int((float64(y)+0.5)*float64(bbox.Dy())/float64(n))
+		for x := 0; x < n; x++ {
+			px := bbox.Min.X + int((float64(x)+0.5)*float64(bbox.Dx())/float64(n))
+			grid[y][x] = canvas.Get(px, py)
+		}
+	}
+	return grid
+}
+
+// rotate 將矩陣順時針旋轉 90 度
+func rotate(grid [][]bool) [][]bool {
+	n := len(grid)
+	out := make([][]bool, n)
+	for y := 0; y < n; y++ {
+		out[y] = make([]bool, n)
+		for x := 0; x < n; x++ {
+			out[y][x] = grid[n-1-x][y]
+		}
+	}
+	return out
+}
+
+// patternScore 計算三個定位圖形 (含分隔區) 與時序圖形的吻合比例
+func patternScore(grid [][]bool) float64 {
+	n := len(grid)
+	match, total := 0, 0
+	check := func(x, y int, dark bool) {
+		if x < 0 || y < 0 || x >= n || y >= n {
+			return
+		}
+		total++
+		if grid[y][x] == dark {
+			match++
+		}
+	}
+	for _, c := range [][2]int{{3, 3}, {n - 4, 3}, {3, n - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				dist := int(math.Max(math.Abs(float64(dx)), math.Abs(float64(dy))))
+				check(c[0]+dx, c[1]+dy, dist != 2 && dist != 4)
+			}
+		}
+	}
+	for i := 8; i < n-8; i++ {
+		check(i, 6, i%2 == 0)
+		check(6, i, i%2 == 0)
+	}
+	return float64(match) / float64(total)
+}
+
+// locate 嘗試每個版本的模組數與四個方向, 取定位/時序圖形最吻合者並轉正
+func locate(canvas *renderer.Canvas, bbox image.Rectangle) ([][]bool, float64) {
+	var best [][]bool
+	bestScore := 0.0
+	for version := 1; version <= 40; version++ {
+		n := 17 + 4*version
+		module := float64(bbox.Dx()) / float64(n)
+		if module < 0.9 {
+			break
+		}
+		if math.Abs(float64(bbox.Dy())/float64(n)-module) > 0.2*module {
+			continue
+		}
+		grid := sampleGrid(canvas, bbox, n)
+		for r := 0; r < 4; r++ {
+			if score := patternScore(grid); score > bestScore {
+				best, bestScore = grid, score
+			}
+			grid = rotate(grid)
+		}
+	}
+	if bestScore < 0.8 {
+		return nil, 0
+	}
+	return best, bestScore
+}
+
+// fixedPatternGrade 依定位/時序圖形吻合比例評分
+func fixedPatternGrade(score float64) float64 {
+	switch {
+	case score >= 0.99:
+		return 4
+	case score >= 0.95:
+		return 3
+	case score >= 0.90:
+		return 2
+	case score >= 0.85:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// quietZone 由外框逐點向外擴張, 直到遇到黑點或標籤邊緣, 回傳以模組為單位的靜區寬度 (最多量到 2 倍要求值)
+func quietZone(canvas *renderer.Canvas, bbox image.Rectangle, module float64) float64 {
+	limit := int(math.Ceil(module * qrQuietZone * 2))
+	bounds := image.Rect(0, 0, canvas.Width, canvas.Height)
+	clear := 0
+	for t := 1; t <= limit; t++ {
+		ring := bbox.Inset(-t)
+		if !ring.In(bounds) {
+			break
+		}
+		dark := false
+		for x := ring.Min.X; x < ring.Max.X && !dark; x++ {
+			dark = canvas.Get(x, ring.Min.Y) || canvas.Get(x, ring.Max.Y-1)
+		}
+		for y := ring.Min.Y; y < ring.Max.Y && !dark; y++ {
+			dark = canvas.Get(ring.Min.X, y) || canvas.Get(ring.Max.X-1, y)
+		}
+		if dark {
+			break
+		}
+		clear = t
+	}
+	return float64(clear) / module
+}
@@ -0,0 +1,89 @@
+package verifier
+
+import (
+	"math"
+
+	"tspl-simulator/models"
+	"tspl-simulator/renderer"
+)
+
+// Verify 以純 Go 解碼器掃描已點陣化的標籤, 確認每個 barcode/qrcode 元素都能解出其 code/data 屬性
+// 並估計 ISO/IEC 15416 (一維) 與 15415 (QR) 風格的等級
+func Verify(data *models.RenderData, canvas *renderer.Canvas) []models.ScanResult {
+	var results []models.ScanResult
+	for i, el := range data.Elements {
+		var result models.ScanResult
+		switch el.Type {
+		case "barcode":
+			result = verifyBarcode(data, el, canvas)
+		case "qrcode":
+			result = verifyQRCode(data, el, canvas)
+		default:
+			continue
+		}
+		result.Element = i + 1
+		results = append(results, result)
+	}
+	return results
+}
+
+// dotsToMM 以設定的 DPI 將點數換算為毫米
+func dotsToMM(dots float64, dpi int) float64 {
+	if dpi <= 0 {
+		dpi = 203
+	}
+	return round(dots*25.4/float64(dpi), 3)
+}
+
+// round 四捨五入到指定小數位數
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+// gradeLetter 將 0-4 分換算為 A-F 等級
+func gradeLetter(score float64) string {
+	switch {
+	case score >= 3.5:
+		return "A"
+	case score >= 2.5:
+		return "B"
+	case score >= 1.5:
+		return "C"
+	case score >= 0.5:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// marginGrade 依 ISO 可解碼度/未使用糾錯能力的門檻 (0.62, 0.50, 0.37, 0.25) 評分
+func marginGrade(v float64) float64 {
+	switch {
+	case v >= 0.62:
+		return 4
+	case v >= 0.50:
+		return 3
+	case v >= 0.37:
+		return 2
+	case v >= 0.25:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// passGrade 通過為 A (4), 否則為 F (0)
+func passGrade(ok bool) float64 {
+	if ok {
+		return 4
+	}
+	return 0
+}
+
+// failed 建立無法驗證的結果
+func failed(result models.ScanResult, message string) models.ScanResult {
+	result.Grade = "F"
+	result.Messages = append(result.Messages, message)
+	return result
+}